@@ -0,0 +1,79 @@
+package api
+
+import (
+	"time"
+
+	"web-chess/backend/clock"
+)
+
+// Ends the game if the clock of the side to move has run out. Must be called
+// with the session locked, returns whether the game ended
+func (sess *session) checkFlag() bool {
+	if sess.clock == nil || !sess.clock.Expired() {
+		return false
+	}
+	sess.stopClock()
+	return sess.game.Timeout() == nil
+}
+
+// Punches the clock for the move just made. Must be called with the session
+// locked
+func (sess *session) punchClock() {
+	if sess.clock == nil {
+		return
+	}
+	sess.clock.Punch(!sess.game.ColorToMove)
+	if sess.game.Status().IsOver() {
+		sess.stopClock()
+		return
+	}
+	sess.scheduleFlagCheck()
+}
+
+// Stops the clock at the end of the game. Must be called with the session
+// locked
+func (sess *session) stopClock() {
+	if sess.clock == nil {
+		return
+	}
+	sess.clock.Stop()
+	if sess.flagTimer != nil {
+		sess.flagTimer.Stop()
+	}
+}
+
+// Checks for the flag fall of the side to move when its time runs out, so
+// live subscribers learn about it without anyone making a request. Must be
+// called with the session locked
+func (sess *session) scheduleFlagCheck() {
+	if sess.flagTimer != nil {
+		sess.flagTimer.Stop()
+	}
+	untilExpiry, ok := sess.clock.UntilExpiry()
+	if !ok {
+		return
+	}
+	sess.flagTimer = time.AfterFunc(untilExpiry, func() {
+		sess.Lock()
+		defer sess.Unlock()
+		switch {
+		case sess.checkFlag():
+			sess.changed()
+		case !sess.game.Status().IsOver():
+			// The timer fired before the clock ran out, as it does with a
+			// time source other than the system's
+			sess.scheduleFlagCheck()
+		}
+	})
+}
+
+func newClock(timeControl string, source clock.Source) (*clock.Clock, error) {
+	if timeControl == "" {
+		return nil, nil
+	}
+	control, err := clock.ParseTimeControl(timeControl)
+	if err != nil {
+		return nil, err
+	}
+	return clock.New(control, source), nil
+}
@@ -3,27 +3,192 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
+	"web-chess/backend/clock"
+	"web-chess/backend/engine"
+	"web-chess/backend/pgn"
 	game "web-chess/backend/src"
+	"web-chess/backend/storage"
 
 	"github.com/gorilla/mux"
 )
 
 type GameHandler struct {
-	game *game.Game
+	store      *gameStore
+	clock      clock.Source
+	repository storage.GameRepository
 }
 
-func (h *GameHandler) NewGame(w http.ResponseWriter, req *http.Request) {
-	h.game = game.NewGame()
+// NewGameHandler creates a handler with the games of the configured
+// repository
+func NewGameHandler(config Config) (*GameHandler, error) {
+	h := &GameHandler{store: newGameStore(), clock: config.Clock, repository: config.Repository}
+	if h.clock == nil {
+		h.clock = clock.System
+	}
+	if h.repository != nil {
+		if err := h.store.restore(h.repository, h.clock); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+type gameState struct {
+	ID string `json:"id"`
+	*game.Game
+	Variant string `json:"variant"`
+	// Checks given by each side in Three-check
+	Checks *checks `json:"checks,omitempty"`
+	// Pieces in hand in Crazyhouse, counted by piece type
+	Pockets      *pockets    `json:"pockets,omitempty"`
+	Status       game.Status `json:"status"`
+	Winner       string      `json:"winner,omitempty"`
+	Result       string      `json:"result"`
+	CanClaimDraw bool        `json:"canClaimDraw"`
+	// Settings of the engine when playing against it
+	Opponent *opponentOptions `json:"opponent,omitempty"`
+	Clock    *clock.State     `json:"clock,omitempty"`
+}
+
+type checks struct {
+	White int `json:"white"`
+	Black int `json:"black"`
+}
+
+type pockets struct {
+	White [game.Queen + 1]int `json:"white"`
+	Black [game.Queen + 1]int `json:"black"`
+}
+
+// Must be called with the session locked
+func newGameState(sess *session) gameState {
+	g := sess.game
+	winner := ""
+	switch g.Winner() {
+	case game.White:
+		winner = "white"
+	case game.Black:
+		winner = "black"
+	}
+	state := gameState{
+		ID:           sess.id,
+		Game:         g,
+		Variant:      variantName(g),
+		Status:       g.Status(),
+		Winner:       winner,
+		Result:       g.Result(),
+		CanClaimDraw: g.CanClaimDraw(),
+	}
+	if g.Variant() == game.ThreeCheck {
+		state.Checks = &checks{White: g.ChecksGiven(true), Black: g.ChecksGiven(false)}
+	}
+	if g.Variant() == game.Crazyhouse {
+		state.Pockets = &pockets{White: g.Pocket(true), Black: g.Pocket(false)}
+	}
+	if sess.opponent != nil {
+		options := sess.opponent.options()
+		state.Opponent = &options
+	}
+	if sess.clock != nil {
+		clockState := sess.clock.State()
+		state.Clock = &clockState
+	}
+	return state
+}
+
+type gameSummary struct {
+	ID        string      `json:"id"`
+	Created   time.Time   `json:"created"`
+	Fen       string      `json:"fen"`
+	MoveCount int         `json:"moveCount"`
+	Status    game.Status `json:"status"`
+	Result    string      `json:"result"`
+}
+
+// Looks up the game in the route and locks it, the caller has to unlock it.
+// Writes a 404 and returns nil if there is no such game. A game whose side to
+// move has run out of time ends here
+func (h *GameHandler) lockSession(w http.ResponseWriter, r *http.Request) *session {
+	id := mux.Vars(r)["id"]
+	sess, ok := h.store.get(id)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Game %s not found", id), http.StatusNotFound)
+		return nil
+	}
+	sess.Lock()
+	if sess.checkFlag() {
+		sess.changed()
+	}
+	return sess
+}
+
+// Options accepted when creating a game
+type gameOptions struct {
+	opponentOptions
+	variantOptions
+	// Such as "5+3", see clock.ParseTimeControl. Games without one are
+	// untimed
+	TimeControl string `json:"timeControl,omitempty"`
+}
+
+// Adds the game and responds with its state. When the engine is to move it
+// plays first
+func (h *GameHandler) addGame(w http.ResponseWriter, g *game.Game, tags pgn.Tags, options gameOptions) {
+	opponent, err := newEngineOpponent(options.opponentOptions)
+	if err != nil {
+		fmt.Printf("Error creating opponent: %v\n", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	gameClock, err := newClock(options.TimeControl, h.clock)
+	if err != nil {
+		fmt.Printf("Error creating clock: %v\n", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sess := &session{game: g, tags: tags, opponent: opponent, clock: gameClock, repository: h.repository}
+	h.store.add(sess)
+	sess.Lock()
+	defer sess.Unlock()
+
+	sess.save()
+	sess.playEngineMove()
+
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(h.game)
+	json.NewEncoder(w).Encode(newGameState(sess))
+}
+
+// NewGame starts a game from the initial position. The body is optional and
+// chooses the variant, opponent and time control
+func (h *GameHandler) NewGame(w http.ResponseWriter, req *http.Request) {
+	var options gameOptions
+	err := json.NewDecoder(req.Body).Decode(&options)
+	if err != nil && err != io.EOF {
+		fmt.Printf("Error decoding options: %v\n", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	g, err := newVariantGame(options.variantOptions)
+	if err != nil {
+		fmt.Printf("Error creating game: %v\n", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.addGame(w, g, nil, options)
 }
 
 func (h *GameHandler) NewGameFromFen(w http.ResponseWriter, req *http.Request) {
 	var fen struct {
 		Fen string `json:"fen"`
+		gameOptions
 	}
 
 	err := json.NewDecoder(req.Body).Decode(&fen)
@@ -33,54 +198,237 @@ func (h *GameHandler) NewGameFromFen(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	h.game = game.NewGameFromFen(fen.Fen)
+	g, err := gameFromFen(fen.Fen, fen.variantOptions)
+	if err != nil {
+		fmt.Printf("Error loading fen: %v\n", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.addGame(w, g, nil, fen.gameOptions)
+}
+
+func (h *GameHandler) ListGames(w http.ResponseWriter, req *http.Request) {
+	summaries := []gameSummary{}
+	for _, sess := range h.store.list() {
+		sess.Lock()
+		summaries = append(summaries, gameSummary{
+			ID:        sess.id,
+			Created:   sess.created,
+			Fen:       sess.game.CurrentFen(),
+			MoveCount: len(sess.game.Moves()),
+			Status:    sess.game.Status(),
+			Result:    sess.game.Result(),
+		})
+		sess.Unlock()
+	}
+
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(h.game)
+	json.NewEncoder(w).Encode(summaries)
+}
+
+func (h *GameHandler) DeleteGame(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+	sess, ok := h.store.delete(id)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Game %s not found", id), http.StatusNotFound)
+		return
+	}
+	sess.Lock()
+	sess.deleted = true
+	sess.stopClock()
+	sess.unsubscribeAll()
+	sess.Unlock()
+
+	if h.repository != nil {
+		if err := h.repository.Delete(id); err != nil && err != storage.ErrNotFound {
+			fmt.Printf("Error deleting game %s: %v\n", id, err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *GameHandler) Move(w http.ResponseWriter, req *http.Request) {
-	var move game.Move
+	sess := h.lockSession(w, req)
+	if sess == nil {
+		return
+	}
+	defer sess.Unlock()
+
+	var moveRequest struct {
+		game.Move
+		San string `json:"san"`
+	}
 
-	err := json.NewDecoder(req.Body).Decode(&move)
+	err := json.NewDecoder(req.Body).Decode(&moveRequest)
 	if err != nil {
 		fmt.Printf("Error decoding move: %v\n", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	err = h.game.Move(move)
+	if sess.opponent.toMove(sess.game) {
+		http.Error(w, "It is the engine's turn", http.StatusBadRequest)
+		return
+	}
+
+	move := moveRequest.Move
+	if moveRequest.San != "" {
+		move, err = game.ParseSAN(sess.game, moveRequest.San)
+		if err != nil {
+			fmt.Printf("Error parsing move: %v\n", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = sess.game.Move(move)
 	if err != nil {
 		fmt.Printf("Error moving piece: %v\n", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	sess.punchClock()
+	sess.changed()
+	sess.playEngineMove()
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(h.game)
+	json.NewEncoder(w).Encode(newGameState(sess))
 }
 
 func (h *GameHandler) Undo(w http.ResponseWriter, r *http.Request) {
-	var move game.Move
+	sess := h.lockSession(w, r)
+	if sess == nil {
+		return
+	}
+	defer sess.Unlock()
+
+	if sess.clock != nil {
+		http.Error(w, "Moves cannot be taken back in timed games", http.StatusBadRequest)
+		return
+	}
 
-	err := json.NewDecoder(r.Body).Decode(&move)
+	err := sess.game.UndoMove()
 	if err != nil {
-		fmt.Printf("Error decoding move: %v\n", err)
+		fmt.Printf("Error undoing move: %v\n", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Against the engine, the move it replied to is taken back with the reply
+	for sess.opponent.toMove(sess.game) && len(sess.game.Moves()) > 0 {
+		sess.game.UndoMove()
+	}
+
+	sess.changed()
+	// Only when the engine made the first move of the game
+	sess.playEngineMove()
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newGameState(sess))
+}
+
+func (h *GameHandler) ImportPGN(w http.ResponseWriter, r *http.Request) {
+	index := 1
+	if gameParam := r.URL.Query().Get("game"); gameParam != "" {
+		i, err := strconv.Atoi(gameParam)
+		if err != nil || i < 1 {
+			http.Error(w, "Invalid game number", http.StatusBadRequest)
+			return
+		}
+		index = i
+	}
+
+	games, err := pgn.Parse(r.Body)
+	if err != nil {
+		fmt.Printf("Error parsing pgn: %v\n", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if index > len(games) {
+		http.Error(w, fmt.Sprintf("PGN contains %d games", len(games)), http.StatusBadRequest)
+		return
+	}
+
+	g, err := games[index-1].Replay()
+	if err != nil {
+		fmt.Printf("Error replaying pgn: %v\n", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	h.game.UnmakeMove(move)
+	h.addGame(w, g, games[index-1].Tags, gameOptions{})
+}
+
+func (h *GameHandler) ClaimDraw(w http.ResponseWriter, r *http.Request) {
+	sess := h.lockSession(w, r)
+	if sess == nil {
+		return
+	}
+	defer sess.Unlock()
 
+	err := sess.game.ClaimDraw()
+	if err != nil {
+		fmt.Printf("Error claiming draw: %v\n", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sess.stopClock()
+
+	sess.changed()
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(h.game)
+	json.NewEncoder(w).Encode(newGameState(sess))
 }
 
 func (h *GameHandler) CurrentState(w http.ResponseWriter, r *http.Request) {
+	sess := h.lockSession(w, r)
+	if sess == nil {
+		return
+	}
+	defer sess.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newGameState(sess))
+}
+
+func (h *GameHandler) PGN(w http.ResponseWriter, r *http.Request) {
+	sess := h.lockSession(w, r)
+	if sess == nil {
+		return
+	}
+	defer sess.Unlock()
+
+	tags := pgn.Tags{
+		"Event": "Casual game",
+		"Site":  "web-chess",
+		"Date":  sess.created.Format("2006.01.02"),
+		"Round": "-",
+	}
+	if status := sess.game.Status(); status == game.Timeout || status == game.TimeoutVsInsufficientMaterial {
+		tags["Termination"] = "time forfeit"
+	}
+	for name, value := range sess.tags {
+		tags[name] = value
+	}
+
+	text, err := pgn.Export(sess.game, tags)
+	if err != nil {
+		fmt.Printf("Error exporting pgn: %v\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-chess-pgn")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(h.game)
+	fmt.Fprint(w, text)
 }
 
 func (h *GameHandler) LegalMoves(w http.ResponseWriter, r *http.Request) {
+	sess := h.lockSession(w, r)
+	if sess == nil {
+		return
+	}
+	defer sess.Unlock()
+
 	vars := mux.Vars(r)
 	index := vars["index"]
 
@@ -90,7 +438,37 @@ func (h *GameHandler) LegalMoves(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	moves := h.game.LegalMovesAtIndex(i)
+	moves := sess.game.LegalMovesAtIndex(i)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(moves)
 }
+
+func (h *GameHandler) Captures(w http.ResponseWriter, r *http.Request) {
+	sess := h.lockSession(w, r)
+	if sess == nil {
+		return
+	}
+	defer sess.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sess.game.GenerateCaptures())
+}
+
+// The static evaluation of the position with each term, from white's point of
+// view
+type evaluationResponse struct {
+	engine.Terms
+	Total int `json:"total"`
+}
+
+func (h *GameHandler) Evaluation(w http.ResponseWriter, r *http.Request) {
+	sess := h.lockSession(w, r)
+	if sess == nil {
+		return
+	}
+	defer sess.Unlock()
+
+	terms := engine.EvaluateTerms(sess.game)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(evaluationResponse{Terms: terms, Total: terms.Total()})
+}
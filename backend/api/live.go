@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	game "web-chess/backend/src"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	liveWriteTimeout = 10 * time.Second
+	livePingInterval = 30 * time.Second
+	// Subscribers that fall this many updates behind are disconnected
+	liveBufferSize = 16
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// Pushed to subscribers after every change to the game
+type liveUpdate struct {
+	gameState
+	Fen      string     `json:"fen"`
+	LastMove *game.Move `json:"lastMove"`
+}
+
+type subscriber struct {
+	updates chan []byte
+}
+
+// Must be called with the session locked
+func newLiveUpdate(sess *session) ([]byte, error) {
+	update := liveUpdate{
+		gameState: newGameState(sess),
+		Fen:       sess.game.CurrentFen(),
+	}
+	if moves := sess.game.Moves(); len(moves) > 0 {
+		update.LastMove = &moves[len(moves)-1]
+	}
+	return json.Marshal(update)
+}
+
+// Sends the current state to every subscriber. Must be called with the
+// session locked
+func (sess *session) broadcast() {
+	if len(sess.subscribers) == 0 {
+		return
+	}
+
+	data, err := newLiveUpdate(sess)
+	if err != nil {
+		fmt.Printf("Error encoding live update: %v\n", err)
+		return
+	}
+	for sub := range sess.subscribers {
+		select {
+		case sub.updates <- data:
+		default:
+			sess.unsubscribe(sub)
+		}
+	}
+}
+
+// Must be called with the session locked
+func (sess *session) subscribe() *subscriber {
+	if sess.subscribers == nil {
+		sess.subscribers = map[*subscriber]struct{}{}
+	}
+	sub := &subscriber{updates: make(chan []byte, liveBufferSize)}
+	sess.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Must be called with the session locked
+func (sess *session) unsubscribe(sub *subscriber) {
+	if _, ok := sess.subscribers[sub]; !ok {
+		return
+	}
+	delete(sess.subscribers, sub)
+	close(sub.updates)
+}
+
+// Must be called with the session locked
+func (sess *session) unsubscribeAll() {
+	for sub := range sess.subscribers {
+		sess.unsubscribe(sub)
+	}
+}
+
+// Live upgrades the request to a WebSocket that receives the game state
+// whenever a move is made or taken back, starting with the current state
+func (h *GameHandler) Live(w http.ResponseWriter, r *http.Request) {
+	sess := h.lockSession(w, r)
+	if sess == nil {
+		return
+	}
+	sub := sess.subscribe()
+	current, err := newLiveUpdate(sess)
+	sess.Unlock()
+	if err != nil {
+		fmt.Printf("Error encoding live update: %v\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		fmt.Printf("Error upgrading connection: %v\n", err)
+		sess.Lock()
+		sess.unsubscribe(sub)
+		sess.Unlock()
+		return
+	}
+	defer conn.Close()
+
+	// Clients only listen, reading is needed to notice when they go away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	defer func() {
+		sess.Lock()
+		sess.unsubscribe(sub)
+		sess.Unlock()
+	}()
+
+	ping := time.NewTicker(livePingInterval)
+	defer ping.Stop()
+
+	if !writeLive(conn, websocket.TextMessage, current) {
+		return
+	}
+	for {
+		select {
+		case data, ok := <-sub.updates:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(liveWriteTimeout))
+				return
+			}
+			if !writeLive(conn, websocket.TextMessage, data) {
+				return
+			}
+		case <-ping.C:
+			if !writeLive(conn, websocket.PingMessage, nil) {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+func writeLive(conn *websocket.Conn, messageType int, data []byte) bool {
+	conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+	return conn.WriteMessage(messageType, data) == nil
+}
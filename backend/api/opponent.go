@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"web-chess/backend/engine"
+	game "web-chess/backend/src"
+)
+
+const (
+	defaultLevel    = 4
+	defaultMoveTime = time.Second
+	maxMoveTime     = 10 * time.Second
+	// How long past its move time the engine may take before a random move is
+	// played instead
+	engineGrace = 2 * time.Second
+	// Every game against the engine has its own table
+	opponentHashMB = 4
+	// In timed games the engine spends at most this share of its remaining
+	// time on a move
+	engineMovesToGo = 30
+)
+
+// Difficulty levels, starting at level 1
+var levels = [...]struct {
+	depth int
+	// The move is picked at random from the moves scoring at most this many
+	// centipawns below the best one. Zero always plays the best move
+	margin int
+}{
+	{depth: 1, margin: 300},
+	{depth: 2, margin: 150},
+	{depth: 3, margin: 60},
+	{depth: 4, margin: 25},
+	{depth: 6},
+	{depth: 8},
+	{depth: 12},
+	{depth: engine.MaxPly},
+}
+
+// Body of /new-game and part of the body of /new-game-from-fen. Without an
+// opponent both sides are played through the API
+type opponentOptions struct {
+	// "human" or "engine"
+	Opponent string `json:"opponent,omitempty"`
+	// "white" or "black", the engine plays black by default
+	EngineColor string `json:"engineColor,omitempty"`
+	Level       int    `json:"level,omitempty"`
+	MoveTimeMs  int    `json:"moveTimeMs,omitempty"`
+}
+
+// The engine playing one side of a game
+type engineOpponent struct {
+	color    bool
+	level    int
+	moveTime time.Duration
+	tt       *engine.TranspositionTable
+}
+
+// Returns nil when the options do not ask for the engine
+func newEngineOpponent(options opponentOptions) (*engineOpponent, error) {
+	switch options.Opponent {
+	case "", "human":
+		return nil, nil
+	case "engine":
+	default:
+		return nil, fmt.Errorf("unknown opponent %q", options.Opponent)
+	}
+
+	o := &engineOpponent{level: defaultLevel, moveTime: defaultMoveTime}
+	switch options.EngineColor {
+	case "", "black":
+	case "white":
+		o.color = true
+	default:
+		return nil, fmt.Errorf("unknown engine color %q", options.EngineColor)
+	}
+
+	if options.Level != 0 {
+		if options.Level < 1 || options.Level > len(levels) {
+			return nil, fmt.Errorf("level must be between 1 and %d", len(levels))
+		}
+		o.level = options.Level
+	}
+	if options.MoveTimeMs < 0 {
+		return nil, errors.New("move time must not be negative")
+	}
+	if options.MoveTimeMs > 0 {
+		o.moveTime = min(time.Duration(options.MoveTimeMs)*time.Millisecond, maxMoveTime)
+	}
+
+	o.tt = engine.NewTranspositionTable(opponentHashMB)
+	return o, nil
+}
+
+func (o *engineOpponent) options() opponentOptions {
+	color := "black"
+	if o.color {
+		color = "white"
+	}
+	return opponentOptions{
+		Opponent:    "engine",
+		EngineColor: color,
+		Level:       o.level,
+		MoveTimeMs:  int(o.moveTime.Milliseconds()),
+	}
+}
+
+func (o *engineOpponent) toMove(g *game.Game) bool {
+	return o != nil && g.ColorToMove == o.color
+}
+
+// Plays the engine's move if it is the engine's turn in an ongoing game. Must
+// be called with the session locked. The lock is released while the engine
+// searches, so the game can be read and its clock can fall in the meantime.
+// The move is dropped if the game changed before it could be played
+func (sess *session) playEngineMove() {
+	if !sess.opponent.toMove(sess.game) || sess.game.Status() != game.Ongoing {
+		return
+	}
+
+	moveTime := sess.opponent.moveTime
+	if sess.clock != nil {
+		remaining := sess.clock.Remaining(sess.opponent.color)
+		moveTime = max(min(moveTime, remaining/engineMovesToGo), time.Millisecond)
+	}
+
+	clone := sess.game.Clone()
+	hash, ply := sess.game.Hash(), len(sess.game.Moves())
+	sess.Unlock()
+	move := sess.opponent.chooseMove(clone, moveTime)
+	sess.Lock()
+
+	if sess.deleted || sess.game.Hash() != hash || len(sess.game.Moves()) != ply {
+		return
+	}
+	if sess.checkFlag() {
+		sess.changed()
+		return
+	}
+	if sess.game.Status() != game.Ongoing {
+		return
+	}
+	if err := sess.game.Move(move); err != nil {
+		fmt.Printf("Error playing engine move: %v\n", err)
+		return
+	}
+	sess.punchClock()
+	sess.changed()
+}
+
+// Searches a copy of the game on another goroutine. The search stops at the
+// move time, if it has not returned shortly after that a random legal move is
+// played so the request does not hang
+func (o *engineOpponent) chooseMove(g *game.Game, moveTime time.Duration) game.Move {
+	ctx, cancel := context.WithTimeout(context.Background(), moveTime)
+	defer cancel()
+
+	clone := g.Clone()
+	moves := make(chan game.Move, 1)
+	go func() {
+		moves <- o.search(ctx, clone, moveTime)
+	}()
+
+	select {
+	case move := <-moves:
+		return move
+	case <-time.After(moveTime + engineGrace):
+		fmt.Printf("Engine did not move within %v, playing a random move\n", moveTime+engineGrace)
+		legalMoves := g.GenerateLegalMoves()
+		return legalMoves[rand.IntN(len(legalMoves))]
+	}
+}
+
+func (o *engineOpponent) search(ctx context.Context, g *game.Game, moveTime time.Duration) game.Move {
+	e := engine.NewWithTable(o.tt)
+	level := levels[o.level-1]
+	limits := engine.Limits{Depth: level.depth, Time: moveTime}
+	if level.margin == 0 {
+		result, err := e.SearchContext(ctx, g, limits)
+		if err != nil {
+			return game.Move{}
+		}
+		return result.Move
+	}
+
+	// Score every move with a search one ply shallower and pick one of those
+	// close to the best
+	limits.Depth = max(level.depth-1, 1)
+	type scoredMove struct {
+		move  game.Move
+		score engine.Score
+	}
+	var scored []scoredMove
+	best := -engine.Infinity
+	for _, move := range g.GenerateLegalMoves() {
+		if ctx.Err() != nil && len(scored) > 0 {
+			break
+		}
+
+		g.Move(move)
+		var score engine.Score
+		result, err := e.SearchContext(ctx, g, limits)
+		switch {
+		case err == nil:
+			score = -result.Score
+		// The move mated or won by the rules of the variant
+		case g.InCheck() || g.IsVariantLoss():
+			score = engine.MateScore
+		}
+		g.UndoMove()
+
+		scored = append(scored, scoredMove{move, score})
+		best = max(best, score)
+	}
+
+	var candidates []game.Move
+	for _, s := range scored {
+		if s.score >= best-engine.Score(level.margin) {
+			candidates = append(candidates, s.move)
+		}
+	}
+	if len(candidates) == 0 {
+		return game.Move{}
+	}
+	return candidates[rand.IntN(len(candidates))]
+}
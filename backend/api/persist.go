@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"web-chess/backend/clock"
+	"web-chess/backend/pgn"
+	"web-chess/backend/storage"
+)
+
+// Saves the game and sends it to the subscribers after every change. Must be
+// called with the session locked
+func (sess *session) changed() {
+	sess.save()
+	sess.broadcast()
+}
+
+// Must be called with the session locked
+func (sess *session) save() {
+	if sess.repository == nil {
+		return
+	}
+	if err := sess.repository.Save(sess.record()); err != nil {
+		fmt.Printf("Error saving game %s: %v\n", sess.id, err)
+	}
+}
+
+// Must be called with the session locked
+func (sess *session) record() storage.Record {
+	record := storage.NewRecord(sess.game)
+	record.ID = sess.id
+	record.Created = sess.created
+	record.Tags = sess.tags
+	if sess.opponent != nil {
+		options := sess.opponent.options()
+		record.Opponent = &storage.Opponent{
+			EngineColor: options.EngineColor,
+			Level:       options.Level,
+			MoveTimeMs:  options.MoveTimeMs,
+		}
+	}
+	if sess.clock != nil {
+		snapshot := sess.clock.Snapshot()
+		record.Clock = &snapshot
+	}
+	return record
+}
+
+// Rebuilds a session from its record. The clock has kept running while the
+// game was stored
+func restoreSession(record storage.Record, repository storage.GameRepository, source clock.Source) (*session, error) {
+	g, err := record.Replay()
+	if err != nil {
+		return nil, err
+	}
+	sess := &session{
+		id:         record.ID,
+		created:    record.Created,
+		game:       g,
+		tags:       pgn.Tags(record.Tags),
+		repository: repository,
+	}
+
+	if record.Opponent != nil {
+		sess.opponent, err = newEngineOpponent(opponentOptions{
+			Opponent:    "engine",
+			EngineColor: record.Opponent.EngineColor,
+			Level:       record.Opponent.Level,
+			MoveTimeMs:  record.Opponent.MoveTimeMs,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if record.Clock != nil {
+		if sess.clock, err = clock.Restore(*record.Clock, source); err != nil {
+			return nil, err
+		}
+		if !g.Status().IsOver() {
+			sess.scheduleFlagCheck()
+		}
+	}
+	return sess, nil
+}
+
+// Loads every stored game into the store. A game that cannot be read or
+// replayed is left in the repository and skipped, so the others still load
+func (s *gameStore) restore(repository storage.GameRepository, source clock.Source) error {
+	records, err := repository.List()
+	if err != nil && !errors.Is(err, storage.ErrUnreadable) {
+		return err
+	}
+	if err != nil {
+		fmt.Printf("Error listing games: %v\n", err)
+	}
+	for _, record := range records {
+		sess, err := restoreSession(record, repository, source)
+		if err != nil {
+			fmt.Printf("Error restoring game %s: %v\n", record.ID, err)
+			continue
+		}
+		s.put(sess)
+	}
+	return nil
+}
@@ -3,6 +3,9 @@ package api
 import (
 	"net/http"
 
+	"web-chess/backend/clock"
+	"web-chess/backend/storage"
+
 	"github.com/gorilla/mux"
 )
 
@@ -10,34 +13,68 @@ type Server struct {
 	*mux.Router
 }
 
+// Config changes how the server runs. The zero value is what NewServer uses
+type Config struct {
+	// Time source of the game clocks, the system time when nil
+	Clock clock.Source
+	// Where games are saved after every change and loaded from at start.
+	// Games are only kept in memory when nil
+	Repository storage.GameRepository
+}
+
+// NewServer creates a server that keeps its games in memory
 func NewServer() *Server {
+	s, _ := NewServerWithConfig(Config{})
+	return s
+}
+
+// NewServerWithConfig creates a server, failing when the stored games cannot
+// be loaded
+func NewServerWithConfig(config Config) (*Server, error) {
 	s := &Server{
 		Router: mux.NewRouter(),
 	}
 
-	s.routes()
+	if err := s.routes(config); err != nil {
+		return nil, err
+	}
 
 	s.PathPrefix("/static/styles/").Handler(http.StripPrefix("/static/styles/", http.FileServer(http.Dir("./static/styles/"))))
 	s.PathPrefix("/static/dist/").Handler(http.StripPrefix("/static/dist/", http.FileServer(http.Dir("./static/dist/"))))
 	s.PathPrefix("/static/images/").Handler(http.StripPrefix("/static/images/", http.FileServer(http.Dir("./static/images/"))))
 
-	return s
+	return s, nil
 }
 
-func (s *Server) routes() {
+func (s *Server) routes(config Config) error {
 	s.HandleFunc("/", s.appHandler())
 
-	gameHandler := &GameHandler{}
+	gameHandler, err := NewGameHandler(config)
+	if err != nil {
+		return err
+	}
 	s.HandleFunc("/new-game", gameHandler.NewGame)
 	s.HandleFunc("/new-game-from-fen", gameHandler.NewGameFromFen)
-	s.HandleFunc("/move", gameHandler.Move)
-	s.HandleFunc("/undo-move", gameHandler.Undo)
-	s.HandleFunc("/current-state", gameHandler.CurrentState)
-	s.HandleFunc("/legal-moves/{index}", gameHandler.LegalMoves)
+	s.HandleFunc("/import-pgn", gameHandler.ImportPGN)
+	s.HandleFunc("/games", gameHandler.ListGames).Methods(http.MethodGet)
+	s.HandleFunc("/games/{id}", gameHandler.CurrentState).Methods(http.MethodGet)
+	s.HandleFunc("/games/{id}", gameHandler.DeleteGame).Methods(http.MethodDelete)
+
+	games := s.PathPrefix("/games/{id}").Subrouter()
+	games.HandleFunc("/move", gameHandler.Move)
+	games.HandleFunc("/undo-move", gameHandler.Undo)
+	games.HandleFunc("/claim-draw", gameHandler.ClaimDraw)
+	games.HandleFunc("/current-state", gameHandler.CurrentState)
+	games.HandleFunc("/pgn", gameHandler.PGN)
+	games.HandleFunc("/legal-moves/{index}", gameHandler.LegalMoves)
+	games.HandleFunc("/captures", gameHandler.Captures)
+	games.HandleFunc("/evaluation", gameHandler.Evaluation)
+	games.HandleFunc("/live", gameHandler.Live)
 
 	s.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "404 page not found", http.StatusNotFound)
 	})
+	return nil
 }
 
 func (s *Server) appHandler() http.HandlerFunc {
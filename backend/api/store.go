@@ -0,0 +1,100 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"web-chess/backend/clock"
+	"web-chess/backend/pgn"
+	game "web-chess/backend/src"
+	"web-chess/backend/storage"
+)
+
+// A game played through the server. The game mutates itself even when only
+// reading its status, so every access has to hold the lock
+type session struct {
+	sync.Mutex
+	id      string
+	created time.Time
+	game    *game.Game
+	// PGN tags of an imported game, kept for export
+	tags pgn.Tags
+	// Nil when both sides are played through the API
+	opponent *engineOpponent
+	// Nil in untimed games
+	clock       *clock.Clock
+	flagTimer   *time.Timer
+	subscribers map[*subscriber]struct{}
+	// Nil when games are only kept in memory
+	repository storage.GameRepository
+	// Set when the game is deleted while the engine is searching
+	deleted bool
+}
+
+type gameStore struct {
+	mu    sync.RWMutex
+	games map[string]*session
+}
+
+func newGameStore() *gameStore {
+	return &gameStore{games: map[string]*session{}}
+}
+
+// Adds the session under a new id
+func (s *gameStore) add(sess *session) {
+	sess.id = newGameID()
+	sess.created = time.Now()
+	s.put(sess)
+}
+
+func (s *gameStore) put(sess *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.games[sess.id] = sess
+}
+
+func (s *gameStore) get(id string) (*session, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sess, ok := s.games[id]
+	return sess, ok
+}
+
+func (s *gameStore) delete(id string) (*session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.games[id]
+	if ok {
+		delete(s.games, id)
+	}
+	return sess, ok
+}
+
+// Returns the sessions oldest first
+func (s *gameStore) list() []*session {
+	s.mu.RLock()
+	sessions := make([]*session, 0, len(s.games))
+	for _, sess := range s.games {
+		sessions = append(sessions, sess)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].created.Equal(sessions[j].created) {
+			return sessions[i].id < sessions[j].id
+		}
+		return sessions[i].created.Before(sessions[j].created)
+	})
+	return sessions
+}
+
+func newGameID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"errors"
+	"math/rand/v2"
+
+	game "web-chess/backend/src"
+)
+
+// Body of /new-game and part of the body of /new-game-from-fen
+type variantOptions struct {
+	// "standard", "chess960" or a variant of the game package such as
+	// "kingofthehill", "threecheck", "atomic", "horde" or "crazyhouse"
+	Variant string `json:"variant,omitempty"`
+	// Number of the Chess960 start position from 0 to 959, a random one is
+	// picked when it is not given
+	StartPosition *int `json:"startPosition,omitempty"`
+}
+
+// Starts a game from the start position of the variant
+func newVariantGame(options variantOptions) (*game.Game, error) {
+	if options.Variant == "chess960" {
+		position := rand.IntN(game.Chess960Positions)
+		if options.StartPosition != nil {
+			position = *options.StartPosition
+		}
+		return game.NewChess960Game(position)
+	}
+	if options.StartPosition != nil {
+		return nil, errors.New("only chess960 has numbered start positions")
+	}
+	variant, err := game.ParseVariant(options.Variant)
+	if err != nil {
+		return nil, err
+	}
+	return game.NewVariantGame(variant), nil
+}
+
+// Loads a game of the variant from a FEN. A FEN with Chess960 castling rights
+// is played as Chess960 whatever the variant
+func gameFromFen(fen string, options variantOptions) (*game.Game, error) {
+	if options.StartPosition != nil {
+		return nil, errors.New("a game from a fen has no numbered start position")
+	}
+	if options.Variant == "chess960" {
+		g, err := game.ParseFen(fen)
+		if err != nil {
+			return nil, err
+		}
+		return g, g.SetChess960(true)
+	}
+	variant, err := game.ParseVariant(options.Variant)
+	if err != nil {
+		return nil, err
+	}
+	return game.ParseVariantFen(variant, fen)
+}
+
+func variantName(g *game.Game) string {
+	if variant := g.Variant(); variant != game.Standard {
+		return game.VariantKey(variant.Name())
+	}
+	if g.Chess960() {
+		return "chess960"
+	}
+	return "standard"
+}
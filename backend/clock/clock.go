@@ -0,0 +1,318 @@
+// Package clock implements chess clocks for time controls with increments,
+// delays and several periods
+package clock
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source tells the current time. Tests pass their own to drive the clocks
+type Source interface {
+	Now() time.Time
+}
+
+type systemSource struct{}
+
+func (systemSource) Now() time.Time {
+	return time.Now()
+}
+
+// System reads the time from the operating system
+var System Source = systemSource{}
+
+// Bonus is the kind of extra time a period gives for each move
+type Bonus int
+
+const (
+	// Fischer increment, added after every move
+	Increment Bonus = iota
+	// Simple delay, the clock only starts counting down after the delay
+	Delay
+	// Bronstein delay, the time used for the move is given back, up to the
+	// delay
+	Bronstein
+)
+
+var bonusSymbols = [...]string{Increment: "+", Delay: "d", Bronstein: "b"}
+
+// Period is one stage of a time control
+type Period struct {
+	// Moves to make within the period, 0 for the rest of the game
+	Moves     int
+	Time      time.Duration
+	Bonus     Bonus
+	BonusTime time.Duration
+}
+
+// TimeControl is a sequence of periods. Once a side has made the moves of a
+// period, the time of the next period is added to its clock. A last period
+// with a move count repeats
+type TimeControl []Period
+
+// ParseTimeControl parses periods separated by colons. A period is written
+// as [moves/]minutes[bonus seconds], where the bonus is + for an increment, d
+// for a simple delay and b for a Bronstein delay. For example "5+3", "15d5"
+// or "40/90+30:30+30"
+func ParseTimeControl(s string) (TimeControl, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty time control")
+	}
+
+	var control TimeControl
+	parts := strings.Split(s, ":")
+	for i, part := range parts {
+		period, err := parsePeriod(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time control %q: %v", s, err)
+		}
+		if period.Moves == 0 && i < len(parts)-1 {
+			return nil, fmt.Errorf("invalid time control %q: only the last period may be without a move count", s)
+		}
+		control = append(control, period)
+	}
+	return control, nil
+}
+
+func parsePeriod(s string) (Period, error) {
+	var period Period
+	if moves, rest, ok := strings.Cut(s, "/"); ok {
+		n, err := strconv.Atoi(moves)
+		if err != nil || n < 1 {
+			return period, fmt.Errorf("invalid move count %q", moves)
+		}
+		period.Moves = n
+		s = rest
+	}
+
+	minutes := s
+	if i := strings.IndexAny(s, "+db"); i >= 0 {
+		minutes = s[:i]
+		switch s[i] {
+		case 'd':
+			period.Bonus = Delay
+		case 'b':
+			period.Bonus = Bronstein
+		}
+		seconds, err := strconv.ParseFloat(s[i+1:], 64)
+		if err != nil || seconds < 0 {
+			return period, fmt.Errorf("invalid bonus %q", s[i+1:])
+		}
+		period.BonusTime = time.Duration(seconds * float64(time.Second))
+	}
+
+	m, err := strconv.ParseFloat(minutes, 64)
+	if err != nil || m <= 0 {
+		return period, fmt.Errorf("invalid minutes %q", minutes)
+	}
+	period.Time = time.Duration(m * float64(time.Minute))
+	return period, nil
+}
+
+func (tc TimeControl) String() string {
+	parts := make([]string, len(tc))
+	for i, period := range tc {
+		var b strings.Builder
+		if period.Moves > 0 {
+			fmt.Fprintf(&b, "%d/", period.Moves)
+		}
+		b.WriteString(strconv.FormatFloat(period.Time.Minutes(), 'f', -1, 64))
+		if period.BonusTime > 0 {
+			b.WriteString(bonusSymbols[period.Bonus])
+			b.WriteString(strconv.FormatFloat(period.BonusTime.Seconds(), 'f', -1, 64))
+		}
+		parts[i] = b.String()
+	}
+	return strings.Join(parts, ":")
+}
+
+// Returns the period at the index, repeating the last one
+func (tc TimeControl) period(index int) Period {
+	return tc[min(index, len(tc)-1)]
+}
+
+// Clock keeps the time of both sides. Nothing runs before the first move, from
+// then on the clock of the side to move runs until Stop. Sides are indexed 0
+// for white and 1 for black
+type Clock struct {
+	control TimeControl
+	source  Source
+
+	// Time left at the start of the current turn
+	remaining [2]time.Duration
+	// Index of the period each side is in and the moves made in it
+	period      [2]int
+	periodMoves [2]int
+
+	running bool
+	turn    int
+	since   time.Time
+}
+
+func New(control TimeControl, source Source) *Clock {
+	return &Clock{
+		control:   control,
+		source:    source,
+		remaining: [2]time.Duration{control[0].Time, control[0].Time},
+	}
+}
+
+func (c *Clock) TimeControl() TimeControl {
+	return c.control
+}
+
+func side(white bool) int {
+	if white {
+		return 0
+	}
+	return 1
+}
+
+// Remaining returns the time left on the clock of the color, with the running
+// clock counted down to now
+func (c *Clock) Remaining(white bool) time.Duration {
+	s := side(white)
+	if !c.running || c.turn != s {
+		return c.remaining[s]
+	}
+	return c.remaining[s] - c.charge(s, c.source.Now().Sub(c.since))
+}
+
+// Returns how much of the time used on a move is taken off the clock
+func (c *Clock) charge(s int, elapsed time.Duration) time.Duration {
+	if period := c.control.period(c.period[s]); period.Bonus == Delay {
+		return max(elapsed-period.BonusTime, 0)
+	}
+	return elapsed
+}
+
+// Running reports whether a clock runs and whose it is
+func (c *Clock) Running() (white bool, running bool) {
+	return c.turn == 0, c.running
+}
+
+// Expired reports whether the side whose clock runs is out of time
+func (c *Clock) Expired() bool {
+	return c.running && c.Remaining(c.turn == 0) <= 0
+}
+
+// UntilExpiry returns how long the running clock has left until the flag
+// falls
+func (c *Clock) UntilExpiry() (time.Duration, bool) {
+	if !c.running {
+		return 0, false
+	}
+	left := c.remaining[c.turn] - c.source.Now().Sub(c.since)
+	if period := c.control.period(c.period[c.turn]); period.Bonus == Delay {
+		left += period.BonusTime
+	}
+	return max(left, 0), true
+}
+
+// Punch ends the turn of the color after its move, adds the bonus and the
+// time of its next period, and starts the opponent's clock
+func (c *Clock) Punch(white bool) {
+	s := side(white)
+	now := c.source.Now()
+	if c.running && c.turn == s {
+		elapsed := now.Sub(c.since)
+		c.remaining[s] -= c.charge(s, elapsed)
+		switch period := c.control.period(c.period[s]); period.Bonus {
+		case Increment:
+			c.remaining[s] += period.BonusTime
+		case Bronstein:
+			c.remaining[s] += min(elapsed, period.BonusTime)
+		}
+	}
+
+	c.periodMoves[s]++
+	if period := c.control.period(c.period[s]); period.Moves > 0 && c.periodMoves[s] == period.Moves {
+		c.period[s]++
+		c.periodMoves[s] = 0
+		c.remaining[s] += c.control.period(c.period[s]).Time
+	}
+
+	c.running = true
+	c.turn = 1 - s
+	c.since = now
+}
+
+// Stop stops the running clock for good, at the end of the game
+func (c *Clock) Stop() {
+	if !c.running {
+		return
+	}
+	c.remaining[c.turn] = c.Remaining(c.turn == 0)
+	c.running = false
+}
+
+// State is the clock as shown to players
+type State struct {
+	TimeControl string `json:"timeControl"`
+	WhiteMs     int64  `json:"whiteMs"`
+	BlackMs     int64  `json:"blackMs"`
+	// "white" or "black", empty while no clock runs
+	Running string `json:"running,omitempty"`
+}
+
+func (c *Clock) State() State {
+	state := State{
+		TimeControl: c.control.String(),
+		WhiteMs:     max(c.Remaining(true), 0).Milliseconds(),
+		BlackMs:     max(c.Remaining(false), 0).Milliseconds(),
+	}
+	if white, running := c.Running(); running {
+		state.Running = "black"
+		if white {
+			state.Running = "white"
+		}
+	}
+	return state
+}
+
+// Snapshot is everything a clock keeps, to store it and restore it later. A
+// clock that was running keeps running while it is stored
+type Snapshot struct {
+	TimeControl string           `json:"timeControl"`
+	Remaining   [2]time.Duration `json:"remaining"`
+	Period      [2]int           `json:"period"`
+	PeriodMoves [2]int           `json:"periodMoves"`
+	Running     bool             `json:"running"`
+	Turn        int              `json:"turn"`
+	Since       time.Time        `json:"since"`
+}
+
+func (c *Clock) Snapshot() Snapshot {
+	return Snapshot{
+		TimeControl: c.control.String(),
+		Remaining:   c.remaining,
+		Period:      c.period,
+		PeriodMoves: c.periodMoves,
+		Running:     c.running,
+		Turn:        c.turn,
+		Since:       c.since,
+	}
+}
+
+// Restore creates a clock in the state of the snapshot
+func Restore(snapshot Snapshot, source Source) (*Clock, error) {
+	control, err := ParseTimeControl(snapshot.TimeControl)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.Turn != 0 && snapshot.Turn != 1 {
+		return nil, fmt.Errorf("invalid turn %d", snapshot.Turn)
+	}
+	return &Clock{
+		control:     control,
+		source:      source,
+		remaining:   snapshot.Remaining,
+		period:      snapshot.Period,
+		periodMoves: snapshot.PeriodMoves,
+		running:     snapshot.Running,
+		turn:        snapshot.Turn,
+		since:       snapshot.Since,
+	}, nil
+}
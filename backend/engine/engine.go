@@ -0,0 +1,219 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	game "web-chess/backend/src"
+)
+
+const (
+	// Depth searched when no limit is given
+	DefaultDepth = 5
+	// Longest line the search follows from the root
+	MaxPly = 64
+)
+
+var ErrNoLegalMoves = errors.New("side to move has no legal moves")
+
+// Limits bound a search. Zero fields are not limited, with no limits at all
+// the search stops after DefaultDepth
+type Limits struct {
+	Depth int
+	Nodes uint64
+	Time  time.Duration
+}
+
+// Result is the outcome of the deepest iteration that was completed
+type Result struct {
+	Move  game.Move
+	Score Score
+	Depth int
+	Nodes uint64
+	Time  time.Duration
+	// Principal variation, the line both sides are expected to play starting
+	// with Move
+	PV []game.Move
+}
+
+// Options switch parts of the search on and off, mainly to measure what each
+// of them is worth. The zero value is a plain alpha-beta search
+type Options struct {
+	// Search captures at the horizon until the position is quiet
+	Quiescence bool
+	// Order captures by MVV-LVA and exchange value, then killer and history
+	// moves
+	MoveOrdering bool
+	// Skip positions that are good enough even after passing the move
+	NullMove bool
+	// Search late quiet moves less deep
+	Reductions bool
+	// Search a move deeper when the opponent is in check after it
+	CheckExtensions bool
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Quiescence:      true,
+		MoveOrdering:    true,
+		NullMove:        true,
+		Reductions:      true,
+		CheckExtensions: true,
+	}
+}
+
+// Engine searches positions for the best move. The buffers used by the search
+// are kept between searches, so an engine runs one search at a time
+type Engine struct {
+	Options Options
+	// Called with the result of every completed iteration while searching
+	OnIteration func(Result)
+	stop        atomic.Bool
+	tt          *TranspositionTable
+	threads     int
+	// Engines that search the same position on other goroutines and share
+	// the table, so the main search finds more of the tree already searched
+	helpers []*Engine
+	s       searcher
+}
+
+// New creates an engine with a transposition table of DefaultHashMB
+func New() *Engine {
+	return NewWithTable(NewTranspositionTable(DefaultHashMB))
+}
+
+// NewWithTable creates an engine that uses the given table, which may be
+// shared with engines searching at the same time
+func NewWithTable(tt *TranspositionTable) *Engine {
+	return &Engine{Options: DefaultOptions(), tt: tt, threads: 1}
+}
+
+// SetHashSize replaces the transposition table with an empty one of sizeMB
+func (e *Engine) SetHashSize(sizeMB int) {
+	e.tt = NewTranspositionTable(sizeMB)
+	e.helpers = nil
+}
+
+// SetThreads sets the number of goroutines that search at the same time
+func (e *Engine) SetThreads(threads int) {
+	e.threads = max(threads, 1)
+	e.helpers = nil
+}
+
+// Clear forgets everything learned in earlier searches, for a new game
+func (e *Engine) Clear() {
+	e.tt.Clear()
+	e.s.history = [2][64][64]int{}
+	e.helpers = nil
+}
+
+// Search is a convenience for searching with a new engine
+func Search(g *game.Game, limits Limits) (Result, error) {
+	return New().Search(g, limits)
+}
+
+// Search runs an iterative deepening alpha-beta search on the game. The game
+// is used to make and unmake moves during the search and is left in the
+// position it was given in
+func (e *Engine) Search(g *game.Game, limits Limits) (Result, error) {
+	return e.SearchContext(context.Background(), g, limits)
+}
+
+// SearchContext searches like Search and also stops when the context is done,
+// returning the result of the last completed iteration
+func (e *Engine) SearchContext(ctx context.Context, g *game.Game, limits Limits) (Result, error) {
+	e.stop.Store(false)
+	if len(e.helpers) != e.threads-1 {
+		e.helpers = make([]*Engine, e.threads-1)
+		for i := range e.helpers {
+			e.helpers[i] = NewWithTable(e.tt)
+		}
+	}
+
+	// Helpers search until the main search is done
+	helperCtx, cancel := context.WithCancel(ctx)
+	helperNodes := make([]uint64, len(e.helpers))
+	var wg sync.WaitGroup
+	for i, helper := range e.helpers {
+		helper.Options = e.Options
+		clone := g.Clone()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, _ := helper.search(helperCtx, clone, Limits{Depth: MaxPly}, nil)
+			helperNodes[i] = result.Nodes
+		}()
+	}
+
+	result, err := e.search(ctx, g, limits, e.OnIteration)
+	cancel()
+	wg.Wait()
+	for _, nodes := range helperNodes {
+		result.Nodes += nodes
+	}
+	return result, err
+}
+
+func (e *Engine) search(ctx context.Context, g *game.Game, limits Limits, onIteration func(Result)) (Result, error) {
+	start := time.Now()
+
+	maxDepth := limits.Depth
+	if maxDepth <= 0 || maxDepth > MaxPly {
+		maxDepth = MaxPly
+		if limits.Nodes == 0 && limits.Time == 0 {
+			maxDepth = DefaultDepth
+		}
+	}
+
+	s := &e.s
+	s.reset(ctx, g, e.tt, e.Options, limits, start, &e.stop)
+	e.tt.NewSearch()
+	if g.GenerateLegalMovesInto(&s.moves[0]); s.moves[0].Len() == 0 {
+		return Result{}, ErrNoLegalMoves
+	}
+
+	var result Result
+	for depth := 1; depth <= maxDepth; depth++ {
+		s.rootDepth = depth
+		score := s.negamax(depth, 0, -Infinity, Infinity, false)
+		if s.stopped {
+			break
+		}
+
+		result = Result{
+			Move:  s.pv[0][0],
+			Score: score,
+			Depth: depth,
+			Nodes: s.nodes,
+			Time:  time.Since(start),
+			PV:    s.principalVariation(),
+		}
+		s.rootBest = result.Move
+		if onIteration != nil {
+			onIteration(result)
+		}
+
+		// A found mate cannot get shorter by searching deeper
+		if score.IsMate() && depth >= 2*score.MateIn()-1 {
+			break
+		}
+		// The next iteration takes longer than all previous ones together
+		if limits.Time > 0 && time.Since(start) > limits.Time/2 {
+			break
+		}
+	}
+
+	result.Nodes = s.nodes
+	result.Time = time.Since(start)
+	return result, nil
+}
+
+// Stop ends a running search from another goroutine. The search returns the
+// result of the last completed iteration. A search that has not started yet
+// is not affected, SearchContext can stop those too
+func (e *Engine) Stop() {
+	e.stop.Store(true)
+}
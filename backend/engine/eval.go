@@ -0,0 +1,311 @@
+package engine
+
+import (
+	"math/bits"
+
+	game "web-chess/backend/src"
+)
+
+// Middlegame and endgame values of each piece type
+var (
+	pieceValuesMg = [...]int{game.Pawn: 82, game.Knight: 337, game.Bishop: 365, game.Rook: 477, game.Queen: 1025}
+	pieceValuesEg = [...]int{game.Pawn: 94, game.Knight: 281, game.Bishop: 297, game.Rook: 512, game.Queen: 936}
+)
+
+// Contribution of each piece type to the game phase
+var phaseWeights = [...]int{game.Knight: 1, game.Bishop: 1, game.Rook: 2, game.Queen: 4}
+
+// Phase of the starting position, everything below it blends towards the
+// endgame
+const maxPhase = 24
+
+const (
+	doubledPawnMg, doubledPawnEg   = -10, -20
+	isolatedPawnMg, isolatedPawnEg = -10, -15
+	// Own pawns in front of the king
+	pawnShieldMg = 8
+	// Files next to the king without an own pawn
+	openKingFileMg = -12
+)
+
+// Passed pawn bonus by rank, counted from the pawn's own side
+var (
+	passedPawnMg = [8]int{0, 5, 10, 15, 25, 40, 60, 0}
+	passedPawnEg = [8]int{0, 10, 20, 35, 55, 85, 120, 0}
+)
+
+// Mobility is counted relative to a typical number of safe squares for the
+// piece, indexed by piece type
+var (
+	mobilityBaseline = [...]int{game.Knight: 4, game.Bishop: 6, game.Rook: 7, game.Queen: 13}
+	mobilityMg       = [...]int{game.Knight: 4, game.Bishop: 5, game.Rook: 2, game.Queen: 1}
+	mobilityEg       = [...]int{game.Knight: 4, game.Bishop: 5, game.Rook: 4, game.Queen: 2}
+	// Weight of a piece that attacks the squares around the enemy king
+	kingAttackWeight = [...]int{game.Knight: 2, game.Bishop: 2, game.Rook: 3, game.Queen: 5}
+)
+
+const maxKingDanger = 400
+
+const (
+	fileA    uint64 = 0x0101010101010101
+	fileH           = fileA << 7
+	notFileA        = ^fileA
+	notFileH        = ^fileH
+)
+
+var (
+	fileMasks     [8]uint64
+	adjacentFiles [8]uint64
+	// Squares in front of a pawn on its own and the adjacent files, indexed
+	// by color. Without enemy pawns there the pawn is passed
+	passedPawnMasks [game.Black + 1][64]uint64
+	// Squares one and two ranks in front of the king on its own and the
+	// adjacent files
+	kingShieldMasks [game.Black + 1][64]uint64
+)
+
+func init() {
+	for file := 0; file < 8; file++ {
+		fileMasks[file] = fileA << file
+	}
+	for file := 0; file < 8; file++ {
+		if file > 0 {
+			adjacentFiles[file] |= fileMasks[file-1]
+		}
+		if file < 7 {
+			adjacentFiles[file] |= fileMasks[file+1]
+		}
+	}
+
+	for square := 0; square < 64; square++ {
+		rank, file := square/8, square%8
+		files := fileMasks[file] | adjacentFiles[file]
+		for r := 0; r < 8; r++ {
+			rankMask := uint64(0xff) << (8 * r)
+			if r > rank {
+				passedPawnMasks[game.White][square] |= files & rankMask
+			}
+			if r < rank {
+				passedPawnMasks[game.Black][square] |= files & rankMask
+			}
+			if r == rank+1 || r == rank+2 {
+				kingShieldMasks[game.White][square] |= files & rankMask
+			}
+			if r == rank-1 || r == rank-2 {
+				kingShieldMasks[game.Black][square] |= files & rankMask
+			}
+		}
+	}
+}
+
+// Terms breaks an evaluation down into its parts. Each term is blended by the
+// game phase and scored in centipawns from white's point of view
+type Terms struct {
+	Material      int `json:"material"`
+	PieceSquare   int `json:"pieceSquare"`
+	PawnStructure int `json:"pawnStructure"`
+	KingSafety    int `json:"kingSafety"`
+	Mobility      int `json:"mobility"`
+	// From maxPhase with all pieces on the board down to 0 with only kings
+	// and pawns left
+	Phase int `json:"phase"`
+}
+
+// Total returns the sum of the terms from white's point of view
+func (t Terms) Total() int {
+	return t.Material + t.PieceSquare + t.PawnStructure + t.KingSafety + t.Mobility
+}
+
+// Evaluate scores the position in centipawns from the point of view of the
+// side to move
+func Evaluate(g *game.Game) int {
+	score := EvaluateTerms(g).Total()
+	if !g.ColorToMove {
+		return -score
+	}
+	return score
+}
+
+// Middlegame and endgame values of a term, blended by the game phase
+type tapered struct {
+	mg, eg int
+}
+
+func (t *tapered) add(sign, mg, eg int) {
+	t.mg += sign * mg
+	t.eg += sign * eg
+}
+
+func (t tapered) blend(phase int) int {
+	return (t.mg*phase + t.eg*(maxPhase-phase)) / maxPhase
+}
+
+type evaluation struct {
+	bitboards [23]uint64
+	occupancy uint64
+	// Squares attacked by the pawns of each color
+	pawnAttacks [game.Black + 1]uint64
+	// Summed weights of the pieces attacking the squares around each
+	// color's king, and how many pieces do
+	kingAttackUnits [game.Black + 1]int
+	kingAttackers   [game.Black + 1]int
+
+	material, pieceSquare, pawnStructure, kingSafety, mobility tapered
+}
+
+// EvaluateTerms evaluates the position and returns every term on its own
+func EvaluateTerms(g *game.Game) Terms {
+	e := evaluation{bitboards: g.BitBoards()}
+	e.occupancy = e.bitboards[game.White] | e.bitboards[game.Black]
+	e.pawnAttacks[game.White] = pawnAttacksOf(e.bitboards[game.Pawn|game.White], true)
+	e.pawnAttacks[game.Black] = pawnAttacksOf(e.bitboards[game.Pawn|game.Black], false)
+
+	phase := 0
+	for pieceType := game.Knight; pieceType <= game.Queen; pieceType++ {
+		count := bits.OnesCount64(e.bitboards[pieceType|game.White] | e.bitboards[pieceType|game.Black])
+		phase += count * phaseWeights[pieceType]
+	}
+	phase = min(phase, maxPhase)
+
+	for _, white := range []bool{true, false} {
+		e.evaluatePieces(white)
+		e.evaluatePawns(white)
+		e.evaluatePocket(g.Pocket(white), white)
+	}
+	// The king attacks are collected with the pieces
+	for _, white := range []bool{true, false} {
+		e.evaluateKing(white)
+	}
+
+	return Terms{
+		Material:      e.material.blend(phase),
+		PieceSquare:   e.pieceSquare.blend(phase),
+		PawnStructure: e.pawnStructure.blend(phase),
+		KingSafety:    e.kingSafety.blend(phase),
+		Mobility:      e.mobility.blend(phase),
+		Phase:         phase,
+	}
+}
+
+// Material, piece-square values and mobility of every piece of the color
+func (e *evaluation) evaluatePieces(white bool) {
+	us, them, sign := colorIndex(white)
+	enemyKing := e.bitboards[game.King|them]
+	var enemyKingZone uint64
+	if enemyKing != 0 {
+		square := bits.TrailingZeros64(enemyKing)
+		enemyKingZone = game.KingAttacks(square) | 1<<square
+	}
+	safe := ^e.bitboards[us] &^ e.pawnAttacks[them]
+
+	for pieceType := game.King; pieceType <= game.Queen; pieceType++ {
+		for pieces := e.bitboards[pieceType|us]; pieces != 0; pieces &= pieces - 1 {
+			square := bits.TrailingZeros64(pieces)
+			index := tableIndex(square, white)
+			e.pieceSquare.add(sign, pieceSquareMg[pieceType][index], pieceSquareEg[pieceType][index])
+			if pieceType == game.King {
+				continue
+			}
+			e.material.add(sign, pieceValuesMg[pieceType], pieceValuesEg[pieceType])
+			if pieceType == game.Pawn {
+				continue
+			}
+
+			attacks := e.attacks(pieceType, square)
+			moves := bits.OnesCount64(attacks&safe) - mobilityBaseline[pieceType]
+			e.mobility.add(sign, moves*mobilityMg[pieceType], moves*mobilityEg[pieceType])
+			if attacks&enemyKingZone != 0 {
+				e.kingAttackUnits[them] += kingAttackWeight[pieceType]
+				e.kingAttackers[them]++
+			}
+		}
+	}
+}
+
+// Pieces in hand in Crazyhouse count as material
+func (e *evaluation) evaluatePocket(pocket [game.Queen + 1]int, white bool) {
+	_, _, sign := colorIndex(white)
+	for pieceType, count := range pocket {
+		e.material.add(sign, count*pieceValuesMg[pieceType], count*pieceValuesEg[pieceType])
+	}
+}
+
+func (e *evaluation) attacks(pieceType, square int) uint64 {
+	switch pieceType {
+	case game.Knight:
+		return game.KnightAttacks(square)
+	case game.Bishop:
+		return game.BishopAttacks(square, e.occupancy)
+	case game.Rook:
+		return game.RookAttacks(square, e.occupancy)
+	}
+	return game.QueenAttacks(square, e.occupancy)
+}
+
+// Doubled, isolated and passed pawns of the color
+func (e *evaluation) evaluatePawns(white bool) {
+	us, them, sign := colorIndex(white)
+	pawns := e.bitboards[game.Pawn|us]
+	enemyPawns := e.bitboards[game.Pawn|them]
+
+	for file := 0; file < 8; file++ {
+		if count := bits.OnesCount64(pawns & fileMasks[file]); count > 1 {
+			e.pawnStructure.add(sign, (count-1)*doubledPawnMg, (count-1)*doubledPawnEg)
+		}
+	}
+
+	for rest := pawns; rest != 0; rest &= rest - 1 {
+		square := bits.TrailingZeros64(rest)
+		if pawns&adjacentFiles[square%8] == 0 {
+			e.pawnStructure.add(sign, isolatedPawnMg, isolatedPawnEg)
+		}
+		if enemyPawns&passedPawnMasks[us][square] == 0 {
+			rank := tableIndex(square, !white) / 8
+			e.pawnStructure.add(sign, passedPawnMg[rank], passedPawnEg[rank])
+		}
+	}
+}
+
+// Pawn shield, open files and attacking pieces around the king of the color.
+// Only matters while there are pieces left to attack with
+func (e *evaluation) evaluateKing(white bool) {
+	us, _, sign := colorIndex(white)
+	king := e.bitboards[game.King|us]
+	if king == 0 {
+		return
+	}
+	square := bits.TrailingZeros64(king)
+	pawns := e.bitboards[game.Pawn|us]
+
+	shield := bits.OnesCount64(pawns & kingShieldMasks[us][square])
+	e.kingSafety.add(sign, shield*pawnShieldMg, 0)
+
+	file := square % 8
+	for f := max(file-1, 0); f <= min(file+1, 7); f++ {
+		if pawns&fileMasks[f] == 0 {
+			e.kingSafety.add(sign, openKingFileMg, 0)
+		}
+	}
+
+	// A single attacker is rarely dangerous
+	if e.kingAttackers[us] >= 2 {
+		units := e.kingAttackUnits[us]
+		e.kingSafety.add(sign, -min(units*units, maxKingDanger), 0)
+	}
+}
+
+// Returns the bitboard index of the color, of its opponent, and the sign of
+// its terms from white's point of view
+func colorIndex(white bool) (int, int, int) {
+	if white {
+		return game.White, game.Black, 1
+	}
+	return game.Black, game.White, -1
+}
+
+func pawnAttacksOf(pawns uint64, white bool) uint64 {
+	if white {
+		return (pawns&notFileA)<<7 | (pawns&notFileH)<<9
+	}
+	return (pawns&notFileA)>>9 | (pawns&notFileH)>>7
+}
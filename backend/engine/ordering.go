@@ -0,0 +1,118 @@
+package engine
+
+import game "web-chess/backend/src"
+
+// Ordering scores, the hash move comes first, then winning and equal captures
+// and queen promotions, killers, quiet moves by history and losing captures
+// last
+const (
+	hashMoveScore    = 1 << 30
+	goodCaptureScore = 1 << 24
+	killerScore      = 1 << 22
+	badCaptureScore  = -1 << 24
+	// History scores are halved when one of them gets this large, so they
+	// stay below the killers
+	maxHistory = 1 << 20
+)
+
+// Piece types ranked by value for MVV-LVA, the king only ever attacks
+var mvvLvaRank = [...]int{game.King: 6, game.Pawn: 1, game.Knight: 2, game.Bishop: 3, game.Rook: 4, game.Queen: 5}
+
+// Chess960 castling moves target the king's own rook, they are not captures
+func (s *searcher) isCapture(move game.Move) bool {
+	return move.Flag == game.EnPassantCapture || move.Flag != game.Castling && s.g.Board[move.TargetSquare].Type != game.None
+}
+
+func isPromotion(move game.Move) bool {
+	return move.Flag >= game.PromoteToQueen && move.Flag <= game.PromoteToBishop
+}
+
+// Scores the moves of the ply for pickMove
+func (s *searcher) scoreMoves(list *game.MoveList, ply int, hashMove game.PackedMove) {
+	scores := &s.scores[ply]
+	side := sideIndex(s.g.ColorToMove)
+
+	for i, move := range list.Moves() {
+		switch {
+		case hashMove != 0 && move.Pack() == hashMove:
+			scores[i] = hashMoveScore
+		case !s.options.MoveOrdering:
+			scores[i] = 0
+		case s.isCapture(move):
+			scores[i] = s.captureScore(move)
+		case move.Flag == game.PromoteToQueen:
+			scores[i] = goodCaptureScore
+		case move == s.killers[ply][0]:
+			scores[i] = killerScore
+		case move == s.killers[ply][1]:
+			scores[i] = killerScore - 1
+		default:
+			scores[i] = s.history[side][move.StartSquare][move.TargetSquare]
+		}
+	}
+}
+
+// Most valuable victim first, least valuable attacker among equal victims.
+// Captures that lose material by static exchange go last
+func (s *searcher) captureScore(move game.Move) int {
+	victim := game.Pawn
+	if move.Flag != game.EnPassantCapture {
+		victim = s.g.Board[move.TargetSquare].PieceType()
+	}
+	attacker := s.g.Board[move.StartSquare].PieceType()
+	mvvLva := mvvLvaRank[victim]*8 - mvvLvaRank[attacker]
+
+	// Taking a piece at least as valuable cannot lose material
+	if mvvLvaRank[attacker] > mvvLvaRank[victim] && s.g.SEE(move) < 0 {
+		return badCaptureScore + mvvLva
+	}
+	return goodCaptureScore + mvvLva
+}
+
+// Swaps the best scored of the remaining moves to index i and returns it.
+// Picking one move at a time is cheaper than sorting, since a cutoff often
+// comes after the first few moves
+func (s *searcher) pickMove(list *game.MoveList, ply, i int) (game.Move, int) {
+	moves, scores := list.Moves(), &s.scores[ply]
+	best := i
+	for j := i + 1; j < len(moves); j++ {
+		if scores[j] > scores[best] {
+			best = j
+		}
+	}
+	moves[i], moves[best] = moves[best], moves[i]
+	scores[i], scores[best] = scores[best], scores[i]
+	return moves[i], scores[i]
+}
+
+// Remembers a quiet move that caused a cutoff, for its siblings and for the
+// next search
+func (s *searcher) updateQuietCutoff(move game.Move, depth, ply int) {
+	if s.killers[ply][0] != move {
+		s.killers[ply][1] = s.killers[ply][0]
+		s.killers[ply][0] = move
+	}
+
+	side := sideIndex(s.g.ColorToMove)
+	s.history[side][move.StartSquare][move.TargetSquare] += depth * depth
+	if s.history[side][move.StartSquare][move.TargetSquare] >= maxHistory {
+		s.ageHistory()
+	}
+}
+
+func (s *searcher) ageHistory() {
+	for side := range s.history {
+		for from := range s.history[side] {
+			for to := range s.history[side][from] {
+				s.history[side][from][to] /= 2
+			}
+		}
+	}
+}
+
+func sideIndex(white bool) int {
+	if white {
+		return 0
+	}
+	return 1
+}
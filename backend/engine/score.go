@@ -0,0 +1,43 @@
+package engine
+
+import "fmt"
+
+// Score is the value of a position in centipawns from the point of view of
+// the side to move. Scores close to MateScore stand for a forced mate
+type Score int
+
+const (
+	Infinity  Score = 32000
+	MateScore Score = 31000
+	// Mates found within the search horizon score above this
+	mateThreshold = MateScore - MaxPly
+)
+
+// Returned for the side to move when it is mated ply half moves from the root
+func matedIn(ply int) Score {
+	return -MateScore + Score(ply)
+}
+
+func (s Score) IsMate() bool {
+	return s >= mateThreshold || s <= -mateThreshold
+}
+
+// MateIn returns the number of moves until mate. It is negative when the side
+// to move gets mated and 0 when the score is not a mate
+func (s Score) MateIn() int {
+	switch {
+	case s >= mateThreshold:
+		return int(MateScore-s+1) / 2
+	case s <= -mateThreshold:
+		return -int(MateScore+s) / 2
+	}
+	return 0
+}
+
+// String formats the score the way UCI reports it, e.g. "cp 35" or "mate -2"
+func (s Score) String() string {
+	if s.IsMate() {
+		return fmt.Sprintf("mate %d", s.MateIn())
+	}
+	return fmt.Sprintf("cp %d", int(s))
+}
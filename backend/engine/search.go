@@ -0,0 +1,342 @@
+package engine
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	game "web-chess/backend/src"
+)
+
+// The clock and node limit are checked when the node count is a multiple of
+// this plus one
+const checkInterval = 2047
+
+type searcher struct {
+	ctx      context.Context
+	g        *game.Game
+	tt       *TranspositionTable
+	options  Options
+	limits   Limits
+	deadline time.Time
+	stop     *atomic.Bool
+	stopped  bool
+	nodes    uint64
+
+	rootDepth int
+	// Best move of the previous iteration, searched first at the root
+	rootBest game.Move
+	// Move lists for every ply and the ordering scores of their moves,
+	// filled in place so the search does not allocate
+	moves  [MaxPly + 1]game.MoveList
+	scores [MaxPly + 1][game.MaxMoves]int
+	// Triangular principal variation table, pv[ply] holds the best line
+	// found from ply onwards
+	pv       [MaxPly + 1][MaxPly + 1]game.Move
+	pvLength [MaxPly + 1]int
+	// Two quiet moves per ply that recently caused a cutoff
+	killers [MaxPly + 1][2]game.Move
+	// How often and how deep quiet moves caused cutoffs, by side, start and
+	// target square. Kept between searches
+	history [2][64][64]int
+	// Used to check moves taken from the table outside the search
+	scratch game.MoveList
+}
+
+func (s *searcher) reset(ctx context.Context, g *game.Game, tt *TranspositionTable, options Options, limits Limits, start time.Time, stop *atomic.Bool) {
+	s.ctx = ctx
+	s.g = g
+	s.tt = tt
+	s.options = options
+	s.limits = limits
+	s.deadline = start.Add(limits.Time)
+	s.stop = stop
+	s.stopped = false
+	s.nodes = 0
+	s.rootBest = game.Move{}
+	s.killers = [MaxPly + 1][2]game.Move{}
+	s.ageHistory()
+}
+
+// Null move pruning searches this much less deep, plus one ply for every
+// nullMoveDepthStep plies of depth
+const (
+	nullMoveReduction = 2
+	nullMoveDepthStep = 6
+)
+
+// Late move reductions start with the quiet move at this index, and reduce
+// one more ply from lateMoveDoubleReduction on
+const (
+	lateMoveIndex           = 3
+	lateMoveDoubleReduction = 8
+)
+
+func (s *searcher) negamax(depth, ply int, alpha, beta Score, allowNull bool) Score {
+	s.pvLength[ply] = ply
+	if s.g.IsVariantLoss() {
+		return matedIn(ply)
+	}
+	if ply > 0 && s.isDraw() {
+		return 0
+	}
+
+	inCheck := s.g.InCheck()
+	if inCheck && s.options.CheckExtensions {
+		depth++
+	}
+	if depth <= 0 || ply == MaxPly {
+		if s.options.Quiescence && ply < MaxPly {
+			return s.quiescence(ply, alpha, beta)
+		}
+		s.nodes++
+		return Score(Evaluate(s.g))
+	}
+
+	s.nodes++
+	if s.nodes&checkInterval == 0 {
+		s.checkLimits()
+	}
+	if s.stopped {
+		return 0
+	}
+	// Nodes searched with a null window only need to tell whether the value
+	// is above or below it
+	pvNode := beta-alpha > 1
+
+	hash := s.g.Hash()
+	var hashMove game.PackedMove
+	if entry, ok := s.tt.Probe(hash, ply); ok {
+		hashMove = entry.Move
+		if !pvNode && entry.Depth >= depth {
+			switch {
+			case entry.Bound == BoundExact:
+				return min(max(entry.Score, alpha), beta)
+			case entry.Bound == BoundLower && entry.Score >= beta:
+				return beta
+			case entry.Bound == BoundUpper && entry.Score <= alpha:
+				return alpha
+			}
+		}
+	}
+	if ply == 0 && s.rootBest != (game.Move{}) {
+		hashMove = s.rootBest.Pack()
+	}
+
+	// If passing the move still fails high, a real move will too. Not used
+	// with only pawns left, where passing can be the best move
+	if s.options.NullMove && allowNull && !pvNode && !inCheck && depth >= nullMoveReduction+1 &&
+		s.hasPieces() && Score(Evaluate(s.g)) >= beta {
+		s.g.MakeNullMove()
+		score := -s.negamax(depth-1-nullMoveReduction-depth/nullMoveDepthStep, ply+1, -beta, -beta+1, false)
+		s.g.UnmakeNullMove()
+		if s.stopped {
+			return 0
+		}
+		if score >= beta {
+			return beta
+		}
+	}
+
+	list := &s.moves[ply]
+	s.g.GenerateLegalMovesInto(list)
+	if list.Len() == 0 {
+		if inCheck {
+			return matedIn(ply)
+		}
+		return 0
+	}
+	s.scoreMoves(list, ply, hashMove)
+
+	originalAlpha := alpha
+	var bestMove game.PackedMove
+	for i := 0; i < list.Len(); i++ {
+		move, orderScore := s.pickMove(list, ply, i)
+		quiet := !s.isCapture(move) && !isPromotion(move)
+
+		s.g.MakeMove(move)
+		var score Score
+		if i == 0 {
+			score = -s.negamax(depth-1, ply+1, -beta, -alpha, true)
+		} else {
+			// Late quiet moves rarely turn out best, they are searched less
+			// deep first and again at full depth if they beat alpha
+			reduction := 0
+			if s.options.Reductions && depth >= 3 && i >= lateMoveIndex && quiet && !inCheck &&
+				orderScore < killerScore-1 && !s.g.InCheck() {
+				reduction = 1
+				if i >= lateMoveDoubleReduction && depth >= 4 {
+					reduction = 2
+				}
+			}
+
+			// The first move is expected to be best, the others only have
+			// to be proven worse with a null window
+			score = -s.negamax(depth-1-reduction, ply+1, -alpha-1, -alpha, true)
+			if score > alpha && reduction > 0 {
+				score = -s.negamax(depth-1, ply+1, -alpha-1, -alpha, true)
+			}
+			if score > alpha && score < beta {
+				score = -s.negamax(depth-1, ply+1, -beta, -alpha, true)
+			}
+		}
+		s.g.UnmakeMove(move)
+		if s.stopped {
+			return 0
+		}
+
+		if score > alpha {
+			alpha = score
+			bestMove = move.Pack()
+			s.updatePV(ply, move)
+			if alpha >= beta {
+				if quiet && s.options.MoveOrdering {
+					s.updateQuietCutoff(move, depth, ply)
+				}
+				break
+			}
+		}
+	}
+
+	bound := BoundUpper
+	if alpha >= beta {
+		bound = BoundLower
+	} else if alpha > originalAlpha {
+		bound = BoundExact
+	}
+	s.tt.Store(hash, ply, TTEntry{Move: bestMove, Score: alpha, Depth: depth, Bound: bound})
+	return alpha
+}
+
+// Searches captures until the position is quiet, so the evaluation is not
+// taken in the middle of an exchange. The side to move can stand pat on the
+// evaluation instead of capturing, except when in check, where every evasion
+// is searched
+func (s *searcher) quiescence(ply int, alpha, beta Score) Score {
+	s.pvLength[ply] = ply
+	if s.g.IsVariantLoss() {
+		return matedIn(ply)
+	}
+	s.nodes++
+	if s.nodes&checkInterval == 0 {
+		s.checkLimits()
+	}
+	if s.stopped {
+		return 0
+	}
+
+	inCheck := s.g.InCheck()
+	list := &s.moves[ply]
+	if inCheck {
+		s.g.GenerateLegalMovesInto(list)
+		if list.Len() == 0 {
+			return matedIn(ply)
+		}
+	} else {
+		standPat := Score(Evaluate(s.g))
+		if ply == MaxPly || standPat >= beta {
+			return min(standPat, beta)
+		}
+		alpha = max(alpha, standPat)
+		s.g.GenerateCapturesInto(list)
+	}
+	if ply == MaxPly {
+		return alpha
+	}
+	s.scoreMoves(list, ply, 0)
+
+	for i := 0; i < list.Len(); i++ {
+		move, orderScore := s.pickMove(list, ply, i)
+		// The remaining captures all lose material
+		if !inCheck && s.options.MoveOrdering && orderScore < 0 {
+			break
+		}
+
+		s.g.MakeMove(move)
+		score := -s.quiescence(ply+1, -beta, -alpha)
+		s.g.UnmakeMove(move)
+		if s.stopped {
+			return 0
+		}
+
+		if score > alpha {
+			alpha = score
+			if alpha >= beta {
+				return beta
+			}
+		}
+	}
+	return alpha
+}
+
+// Reports whether the side to move has a piece other than pawns and the king
+func (s *searcher) hasPieces() bool {
+	bitboards := s.g.BitBoards()
+	us := game.Black
+	if s.g.ColorToMove {
+		us = game.White
+	}
+	return bitboards[us]&^(bitboards[game.Pawn|us]|bitboards[game.King|us]) != 0
+}
+
+// The first iteration always completes, so there is a move to return
+func (s *searcher) checkLimits() {
+	if s.rootDepth == 1 {
+		return
+	}
+	if s.stop.Load() || s.ctx.Err() != nil ||
+		s.limits.Nodes > 0 && s.nodes >= s.limits.Nodes ||
+		s.limits.Time > 0 && time.Now().After(s.deadline) {
+		s.stopped = true
+	}
+}
+
+// Repetitions inside the search are scored as draws right away, since the side
+// that can repeat once can usually repeat again
+func (s *searcher) isDraw() bool {
+	return s.g.HalfmoveClock() >= 100 || s.g.RepetitionCount() >= 2 || s.g.IsInsufficientMaterial()
+}
+
+func (s *searcher) updatePV(ply int, move game.Move) {
+	s.pv[ply][ply] = move
+	copy(s.pv[ply][ply+1:], s.pv[ply+1][ply+1:s.pvLength[ply+1]])
+	s.pvLength[ply] = s.pvLength[ply+1]
+}
+
+// Returns the principal variation of the last iteration. Where the search
+// used a stored score the line is cut short, so it is continued with the moves
+// of exact entries in the table
+func (s *searcher) principalVariation() []game.Move {
+	pv := append([]game.Move{}, s.pv[0][:s.pvLength[0]]...)
+	for _, move := range pv {
+		s.g.MakeMove(move)
+	}
+	for len(pv) < MaxPly && s.g.RepetitionCount() < 2 {
+		entry, ok := s.tt.Probe(s.g.Hash(), 0)
+		if !ok || entry.Bound != BoundExact || entry.Move == 0 {
+			break
+		}
+		move, ok := s.legalMove(entry.Move)
+		if !ok {
+			break
+		}
+		s.g.MakeMove(move)
+		pv = append(pv, move)
+	}
+	for i := len(pv) - 1; i >= 0; i-- {
+		s.g.UnmakeMove(pv[i])
+	}
+	return pv
+}
+
+// Stored moves can come from another position with the same table slot, so
+// they are only used when legal
+func (s *searcher) legalMove(packed game.PackedMove) (game.Move, bool) {
+	s.g.GenerateLegalMovesInto(&s.scratch)
+	for _, move := range s.scratch.Moves() {
+		if move.Pack() == packed {
+			return move, true
+		}
+	}
+	return game.Move{}, false
+}
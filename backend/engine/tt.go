@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"math/bits"
+	"sync/atomic"
+
+	game "web-chess/backend/src"
+)
+
+// Size of the table of a new engine
+const DefaultHashMB = 16
+
+// Bound tells how a stored score relates to the real value of the position
+type Bound uint8
+
+const (
+	BoundNone Bound = iota
+	// The score is the value of the position
+	BoundExact
+	// The search failed high, the value is at least the score
+	BoundLower
+	// The search failed low, the value is at most the score
+	BoundUpper
+)
+
+// TTEntry is what the table remembers about a position
+type TTEntry struct {
+	// Zero when no move is known
+	Move  game.PackedMove
+	Score Score
+	Depth int
+	Bound Bound
+}
+
+// Entries are written and read with two atomic words. The key is stored
+// xored with the data, so a slot that is torn by a concurrent write does not
+// match the hash and is treated as a miss
+type ttSlot struct {
+	check atomic.Uint64
+	data  atomic.Uint64
+}
+
+const ttSlotBytes = 16
+
+// TranspositionTable is a fixed size hash table of search results. Entries
+// are replaced by deeper searches of any position and by every search of a
+// later generation. It can be shared by goroutines searching at the same time
+type TranspositionTable struct {
+	slots      []ttSlot
+	mask       uint64
+	generation atomic.Uint32
+}
+
+// NewTranspositionTable creates a table that uses at most sizeMB megabytes,
+// rounded down to a power of two number of entries
+func NewTranspositionTable(sizeMB int) *TranspositionTable {
+	count := uint64(max(sizeMB, 1)) << 20 / ttSlotBytes
+	count = 1 << (bits.Len64(count) - 1)
+	return &TranspositionTable{
+		slots: make([]ttSlot, count),
+		mask:  count - 1,
+	}
+}
+
+// Clear forgets every entry
+func (t *TranspositionTable) Clear() {
+	for i := range t.slots {
+		t.slots[i].check.Store(0)
+		t.slots[i].data.Store(0)
+	}
+}
+
+// NewSearch starts a new generation, so the entries of earlier searches are
+// replaced first
+func (t *TranspositionTable) NewSearch() {
+	t.generation.Add(1)
+}
+
+// Probe looks up the position. Mate scores are returned relative to ply, the
+// distance of the position from the root
+func (t *TranspositionTable) Probe(hash uint64, ply int) (TTEntry, bool) {
+	slot := &t.slots[hash&t.mask]
+	data := slot.data.Load()
+	if data == 0 || slot.check.Load()^data != hash {
+		return TTEntry{}, false
+	}
+
+	entry := unpackEntry(data)
+	entry.Score = scoreFromTT(entry.Score, ply)
+	return entry, true
+}
+
+// Store saves the result of searching the position to depth. Mate scores are
+// stored relative to the position, so they stay correct when the position is
+// reached at another ply
+func (t *TranspositionTable) Store(hash uint64, ply int, entry TTEntry) {
+	slot := &t.slots[hash&t.mask]
+	generation := uint8(t.generation.Load() & generationMask)
+
+	if old := slot.data.Load(); old != 0 && slot.check.Load()^old != hash {
+		oldEntry := unpackEntry(old)
+		if entryGeneration(old) == generation && oldEntry.Depth > entry.Depth {
+			return
+		}
+	} else if old != 0 && entry.Move == 0 {
+		// Keep the move of an earlier search of the same position
+		entry.Move = unpackEntry(old).Move
+	}
+
+	entry.Score = scoreToTT(entry.Score, ply)
+	data := packEntry(entry, generation)
+	slot.check.Store(hash ^ data)
+	slot.data.Store(data)
+}
+
+// Bits 0-15: move, 16-31: score, 32-39: depth, 40-41: bound, 42-47:
+// generation. The bound is never BoundNone, so stored data is never zero
+const generationMask = 0b111111
+
+func packEntry(entry TTEntry, generation uint8) uint64 {
+	return uint64(entry.Move) |
+		uint64(uint16(int16(entry.Score)))<<16 |
+		uint64(uint8(entry.Depth))<<32 |
+		uint64(entry.Bound)<<40 |
+		uint64(generation&generationMask)<<42
+}
+
+func unpackEntry(data uint64) TTEntry {
+	return TTEntry{
+		Move:  game.PackedMove(data),
+		Score: Score(int16(data >> 16)),
+		Depth: int(uint8(data >> 32)),
+		Bound: Bound(data >> 40 & 0b11),
+	}
+}
+
+func entryGeneration(data uint64) uint8 {
+	return uint8(data >> 42 & generationMask)
+}
+
+func scoreToTT(score Score, ply int) Score {
+	switch {
+	case score >= mateThreshold:
+		return score + Score(ply)
+	case score <= -mateThreshold:
+		return score - Score(ply)
+	}
+	return score
+}
+
+func scoreFromTT(score Score, ply int) Score {
+	switch {
+	case score >= mateThreshold:
+		return score - Score(ply)
+	case score <= -mateThreshold:
+		return score + Score(ply)
+	}
+	return score
+}
@@ -0,0 +1,133 @@
+package pgn
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	game "web-chess/backend/src"
+)
+
+const maxLineLength = 80
+
+var sevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+type Tags map[string]string
+
+// Export returns the game as PGN text. Tags missing from the Seven Tag Roster
+// are filled with "?" placeholders, and FEN and SetUp tags are added when the
+// game did not start from the start position of its variant. Chess960 games
+// and games of a variant get a Variant tag
+func Export(g *game.Game, tags Tags) (string, error) {
+	allTags := Tags{}
+	for name, value := range tags {
+		allTags[name] = value
+	}
+	// A decisive result without checkmate, e.g. by resignation, can only come
+	// from the tags
+	result := g.Result()
+	if result == "*" && results[tags["Result"]] {
+		result = tags["Result"]
+	}
+	allTags["Result"] = result
+	if allTags["Date"] == "" {
+		allTags["Date"] = "????.??.??"
+	}
+	variant := g.Variant()
+	switch {
+	case allTags["Variant"] != "":
+	case variant != game.Standard:
+		allTags["Variant"] = variant.Name()
+	case g.Chess960():
+		allTags["Variant"] = "Chess960"
+	}
+	if g.InitialFen() != variant.StartFen() {
+		allTags["SetUp"] = "1"
+		allTags["FEN"] = g.InitialFen()
+	}
+
+	var sb strings.Builder
+	for _, name := range sevenTagRoster {
+		value := allTags[name]
+		if value == "" {
+			value = "?"
+		}
+		writeTag(&sb, name, value)
+		delete(allTags, name)
+	}
+	otherNames := make([]string, 0, len(allTags))
+	for name := range allTags {
+		otherNames = append(otherNames, name)
+	}
+	sort.Strings(otherNames)
+	for _, name := range otherNames {
+		writeTag(&sb, name, allTags[name])
+	}
+	sb.WriteString("\n")
+
+	movetext, err := movetext(g)
+	if err != nil {
+		return "", err
+	}
+	sb.WriteString(wrap(append(movetext, result)))
+	sb.WriteString("\n")
+
+	return sb.String(), nil
+}
+
+func writeTag(sb *strings.Builder, name, value string) {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	fmt.Fprintf(sb, "[%s \"%s\"]\n", name, value)
+}
+
+// Replays the moves from the initial position, by the rules of the variant,
+// to get SAN and move numbers
+func movetext(g *game.Game) ([]string, error) {
+	replay := game.NewVariantGameFromFen(g.Variant(), g.InitialFen())
+
+	// The fullmove number ends the FEN as written by the game, whatever
+	// fields a variant adds
+	moveNumber := 1
+	fields := strings.Fields(replay.CurrentFen())
+	if n, err := strconv.Atoi(fields[len(fields)-1]); err == nil && n > 0 {
+		moveNumber = n
+	}
+
+	tokens := []string{}
+	for i, move := range g.Moves() {
+		san, err := game.MoveToSAN(replay, move)
+		if err != nil {
+			return nil, fmt.Errorf("move %d: %w", i+1, err)
+		}
+		if replay.ColorToMove {
+			tokens = append(tokens, strconv.Itoa(moveNumber)+".")
+		} else if i == 0 {
+			tokens = append(tokens, strconv.Itoa(moveNumber)+"...")
+		}
+		if !replay.ColorToMove {
+			moveNumber++
+		}
+		tokens = append(tokens, san)
+		replay.MakeMove(move)
+	}
+	return tokens, nil
+}
+
+func wrap(tokens []string) string {
+	var sb strings.Builder
+	lineLength := 0
+	for _, token := range tokens {
+		if lineLength > 0 && lineLength+1+len(token) > maxLineLength {
+			sb.WriteString("\n")
+			lineLength = 0
+		}
+		if lineLength > 0 {
+			sb.WriteString(" ")
+			lineLength++
+		}
+		sb.WriteString(token)
+		lineLength += len(token)
+	}
+	return sb.String()
+}
@@ -0,0 +1,395 @@
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	game "web-chess/backend/src"
+)
+
+type Game struct {
+	Tags Tags
+	// Comment before the first move of the mainline
+	Comment string
+	Moves   []Move
+	Result  string
+}
+
+type Move struct {
+	SAN string
+	// Numeric Annotation Glyphs, with suffixes such as "!?" converted to their
+	// NAG equivalent
+	NAGs     []int
+	Comments []string
+	// Alternatives to this move, each starting from the position before it
+	Variations [][]Move
+	// Comment before the first move of a variation
+	CommentBefore string
+}
+
+// MoveError reports the first mainline move that could not be played
+type MoveError struct {
+	Ply int
+	SAN string
+	Err error
+}
+
+func (e *MoveError) Error() string {
+	return fmt.Sprintf("ply %d (%s): %v", e.Ply, e.SAN, e.Err)
+}
+
+func (e *MoveError) Unwrap() error {
+	return e.Err
+}
+
+var suffixAnnotations = map[string]int{
+	"!":  1,
+	"?":  2,
+	"!!": 3,
+	"??": 4,
+	"!?": 5,
+	"?!": 6,
+}
+
+var results = map[string]bool{
+	"1-0":     true,
+	"0-1":     true,
+	"1/2-1/2": true,
+	"*":       true,
+}
+
+// Parse reads every game from PGN text
+func Parse(r io.Reader) ([]*Game, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseString(string(data))
+}
+
+func ParseString(text string) ([]*Game, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+
+	games := []*Game{}
+	p := &parser{tokens: tokens}
+	for p.pos < len(p.tokens) {
+		g, err := p.parseGame()
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, g)
+	}
+	return games, nil
+}
+
+// Replay plays the mainline from the starting position given by the FEN tag,
+// or the start position of the variant if there is none. Chess960 and the
+// variants of the game package are recognized by their Variant tag, others
+// are played as standard chess
+func (pg *Game) Replay() (*game.Game, error) {
+	variant, err := game.ParseVariant(pg.Tags["Variant"])
+	if err != nil {
+		variant = game.Standard
+	}
+	g := game.NewVariantGame(variant)
+	if fen, ok := pg.Tags["FEN"]; ok {
+		if g, err = game.ParseVariantFen(variant, fen); err != nil {
+			return nil, err
+		}
+	}
+	// An X-FEN of a position with the king and rooks on their standard
+	// squares reads as a standard game
+	if isChess960(pg.Tags["Variant"]) {
+		if err := g.SetChess960(true); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, m := range pg.Moves {
+		move, err := game.ParseSAN(g, m.SAN)
+		if err == nil {
+			err = g.Move(move)
+		}
+		if err != nil {
+			return nil, &MoveError{Ply: i + 1, SAN: m.SAN, Err: err}
+		}
+	}
+	return g, nil
+}
+
+func isChess960(variant string) bool {
+	switch strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(variant)) {
+	case "chess960", "fischerandom", "fischerrandom":
+		return true
+	}
+	return false
+}
+
+type tokenKind int
+
+const (
+	tagToken tokenKind = iota
+	commentToken
+	openVariationToken
+	closeVariationToken
+	nagToken
+	resultToken
+	moveToken
+)
+
+type token struct {
+	kind tokenKind
+	// For tags the name, otherwise the token text
+	text string
+	// For tags the value
+	value string
+	line  int
+}
+
+func tokenize(text string) ([]token, error) {
+	runes := []rune(text)
+	tokens := []token{}
+	line := 1
+
+	for i := 0; i < len(runes); i++ {
+		char := runes[i]
+		switch {
+		case char == '\n':
+			line++
+		case unicode.IsSpace(char):
+		case char == '%' && (i == 0 || runes[i-1] == '\n'):
+			// Escape mechanism, the rest of the line is ignored
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			i--
+		case char == ';':
+			start := i + 1
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			tokens = append(tokens, token{kind: commentToken, text: strings.TrimSpace(string(runes[start:i])), line: line})
+			i--
+		case char == '{':
+			start := i + 1
+			startLine := line
+			for i < len(runes) && runes[i] != '}' {
+				if runes[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated comment", startLine)
+			}
+			tokens = append(tokens, token{kind: commentToken, text: strings.TrimSpace(string(runes[start:i])), line: startLine})
+		case char == '[':
+			tag, end, err := readTag(runes, i, line)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tag)
+			i = end
+		case char == '(':
+			tokens = append(tokens, token{kind: openVariationToken, text: "(", line: line})
+		case char == ')':
+			tokens = append(tokens, token{kind: closeVariationToken, text: ")", line: line})
+		case char == '$':
+			start := i + 1
+			for i+1 < len(runes) && unicode.IsDigit(runes[i+1]) {
+				i++
+			}
+			if start > i {
+				return nil, fmt.Errorf("line %d: missing number after $", line)
+			}
+			tokens = append(tokens, token{kind: nagToken, text: string(runes[start : i+1]), line: line})
+		default:
+			start := i
+			for i+1 < len(runes) && !isDelimiter(runes[i+1]) {
+				i++
+			}
+			tokens = append(tokens, symbolTokens(string(runes[start:i+1]), line)...)
+		}
+	}
+	return tokens, nil
+}
+
+func isDelimiter(char rune) bool {
+	return unicode.IsSpace(char) || strings.ContainsRune("{};()[]$", char)
+}
+
+func readTag(runes []rune, start, line int) (token, int, error) {
+	i := start + 1
+	skipSpace := func() {
+		for i < len(runes) && runes[i] != '\n' && unicode.IsSpace(runes[i]) {
+			i++
+		}
+	}
+
+	skipSpace()
+	nameStart := i
+	for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+		i++
+	}
+	name := string(runes[nameStart:i])
+	if name == "" {
+		return token{}, 0, fmt.Errorf("line %d: missing tag name", line)
+	}
+
+	skipSpace()
+	if i >= len(runes) || runes[i] != '"' {
+		return token{}, 0, fmt.Errorf("line %d: missing value for tag %s", line, name)
+	}
+	i++
+	var value strings.Builder
+	for ; i < len(runes) && runes[i] != '"'; i++ {
+		if runes[i] == '\\' && i+1 < len(runes) {
+			i++
+		}
+		if runes[i] == '\n' {
+			return token{}, 0, fmt.Errorf("line %d: unterminated value for tag %s", line, name)
+		}
+		value.WriteRune(runes[i])
+	}
+	i++
+
+	skipSpace()
+	if i >= len(runes) || runes[i] != ']' {
+		return token{}, 0, fmt.Errorf("line %d: unterminated tag %s", line, name)
+	}
+	return token{kind: tagToken, text: name, value: value.String(), line: line}, i, nil
+}
+
+// Splits a run of symbol characters into move numbers, moves, suffix
+// annotations and results
+func symbolTokens(symbol string, line int) []token {
+	if results[symbol] {
+		return []token{{kind: resultToken, text: symbol, line: line}}
+	}
+
+	// Move numbers such as "12." or "12..." can be glued to the move
+	trimmed := strings.TrimLeft(symbol, "0123456789")
+	if trimmed != symbol && (trimmed == "" || trimmed[0] == '.') {
+		symbol = strings.TrimLeft(trimmed, ".")
+	}
+	symbol = strings.TrimLeft(symbol, ".")
+	if symbol == "" {
+		return nil
+	}
+
+	tokens := []token{}
+	san := strings.TrimRight(symbol, "!?")
+	if san != "" {
+		tokens = append(tokens, token{kind: moveToken, text: san, line: line})
+	}
+	if suffix := symbol[len(san):]; suffix != "" {
+		if nag, ok := suffixAnnotations[suffix]; ok {
+			tokens = append(tokens, token{kind: nagToken, text: strconv.Itoa(nag), line: line})
+		}
+	}
+	return tokens
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) parseGame() (*Game, error) {
+	g := &Game{Tags: Tags{}, Result: "*"}
+
+	for p.pos < len(p.tokens) && p.tokens[p.pos].kind == tagToken {
+		g.Tags[p.tokens[p.pos].text] = p.tokens[p.pos].value
+		p.pos++
+	}
+
+	// Each open variation keeps the line being built, the outermost is the mainline
+	lines := [][]Move{{}}
+	pendingComments := []string{}
+
+	for p.pos < len(p.tokens) {
+		t := p.tokens[p.pos]
+		depth := len(lines) - 1
+		current := lines[depth]
+
+		if t.kind == tagToken {
+			// Next game without a result
+			break
+		}
+		p.pos++
+
+		switch t.kind {
+		case resultToken:
+			if depth != 0 {
+				return nil, fmt.Errorf("line %d: result inside variation", t.line)
+			}
+			g.Result = t.text
+			g.Moves = current
+			if len(current) == 0 {
+				g.Comment = strings.Join(pendingComments, " ")
+			}
+			return g, nil
+		case commentToken:
+			if len(current) == 0 {
+				pendingComments = append(pendingComments, t.text)
+				continue
+			}
+			last := &current[len(current)-1]
+			last.Comments = append(last.Comments, t.text)
+		case nagToken:
+			if len(current) == 0 {
+				return nil, fmt.Errorf("line %d: annotation before first move", t.line)
+			}
+			nag, err := strconv.Atoi(t.text)
+			if err != nil || nag > 255 {
+				return nil, fmt.Errorf("line %d: invalid annotation $%s", t.line, t.text)
+			}
+			last := &current[len(current)-1]
+			last.NAGs = append(last.NAGs, nag)
+		case moveToken:
+			move := Move{SAN: t.text}
+			if len(current) == 0 && len(pendingComments) > 0 {
+				if depth == 0 {
+					g.Comment = strings.Join(pendingComments, " ")
+				} else {
+					move.CommentBefore = strings.Join(pendingComments, " ")
+				}
+				pendingComments = pendingComments[:0]
+			}
+			lines[depth] = append(current, move)
+		case openVariationToken:
+			if len(current) == 0 {
+				return nil, fmt.Errorf("line %d: variation before first move", t.line)
+			}
+			lines = append(lines, []Move{})
+		case closeVariationToken:
+			if depth == 0 {
+				return nil, fmt.Errorf("line %d: unmatched )", t.line)
+			}
+			if len(current) == 0 {
+				return nil, fmt.Errorf("line %d: empty variation", t.line)
+			}
+			parent := lines[depth-1]
+			last := &parent[len(parent)-1]
+			last.Variations = append(last.Variations, current)
+			lines = lines[:depth]
+		}
+	}
+
+	if len(lines) != 1 {
+		return nil, fmt.Errorf("unterminated variation")
+	}
+	g.Moves = lines[0]
+	if len(g.Moves) == 0 && len(pendingComments) > 0 {
+		g.Comment = strings.Join(pendingComments, " ")
+	}
+	if result, ok := g.Tags["Result"]; ok && results[result] {
+		g.Result = result
+	}
+	return g, nil
+}
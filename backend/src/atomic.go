@@ -0,0 +1,116 @@
+package game
+
+import "math/bits"
+
+// Atomic is won by checkmate or by blowing up the enemy king. A capture blows
+// up the capturing and the captured piece along with every piece other than a
+// pawn next to them, so kings cannot capture and a king next to the enemy
+// king cannot be in check
+var Atomic Variant = atomic{}
+
+type atomic struct{ standardRules }
+
+// Pieces blown off the board by a capture, in the order of their squares
+type explosion struct {
+	squares uint64
+	pieces  [9]Piece
+}
+
+func (atomic) Name() string { return "Atomic" }
+
+func (atomic) outcome(g *Game) Status {
+	if g.bitboards[King|colorIndex(g.ColorToMove)] == 0 {
+		return KingExploded
+	}
+	return Ongoing
+}
+
+// Moves are generated without regard to checks and each is made to see
+// whether it blows up the own king or leaves it in check
+func (atomic) generateMoves(g *Game, list *MoveList, mode generationMode) bool {
+	g.generateMovesForColor(list, g.ColorToMove, mode, nil)
+	legal := 0
+	for _, move := range list.Moves() {
+		if isAtomicMoveLegal(g, move) {
+			list.moves[legal] = move
+			legal++
+		}
+	}
+	list.count = legal
+	return true
+}
+
+func isAtomicMoveLegal(g *Game, move Move) bool {
+	if move.Flag != Castling && g.Board[move.StartSquare].pieceType() == King && g.Board[move.TargetSquare].Type != None {
+		return false
+	}
+	white := g.ColorToMove
+	g.MakeMove(move)
+	ownKing := g.bitboards[King|colorIndex(white)] != 0
+	enemyKing := g.bitboards[King|colorIndex(!white)] != 0
+	legal := ownKing && (!enemyKing || !g.isKingInCheck(white))
+	g.UnmakeMove(move)
+	return legal
+}
+
+func (atomic) makeMove(g *Game, move Move, gameState uint32) uint32 {
+	if gameState>>8&0b111111 == None {
+		return gameState
+	}
+
+	pawns := g.bitboards[Pawn|White] | g.bitboards[Pawn|Black]
+	occupancy := g.bitboards[White] | g.bitboards[Black]
+	blast := kingAttacks[move.TargetSquare]&occupancy&^pawns | 1<<move.TargetSquare
+	exploded := explosion{squares: blast}
+	for i := 0; blast != 0; i++ {
+		square := popLSB(&blast)
+		exploded.pieces[i] = g.Board[square]
+		g.togglePiece(g.Board[square].Type, square)
+		g.Board[square] = Piece{None}
+	}
+	g.explosions = append(g.explosions, exploded)
+
+	for right, rookSquare := range g.castlingRooks {
+		if exploded.squares&(1<<rookSquare) != 0 || g.bitboards[King|colorIndex(right >= 2)] == 0 {
+			gameState &^= 1 << right
+		}
+	}
+	return gameState
+}
+
+func (atomic) unmakeMove(g *Game, move Move) {
+	if g.currentGameState>>8&0b111111 == None {
+		return
+	}
+
+	exploded := g.explosions[len(g.explosions)-1]
+	g.explosions = g.explosions[:len(g.explosions)-1]
+	for i, squares := 0, exploded.squares; squares != 0; i++ {
+		square := popLSB(&squares)
+		g.Board[square] = exploded.pieces[i]
+		g.togglePiece(exploded.pieces[i].Type, square)
+	}
+}
+
+func (atomic) safeSquares(g *Game, color bool) uint64 {
+	kings := g.bitboards[King|colorIndex(!color)]
+	if kings == 0 {
+		return 0
+	}
+	return kingAttacks[bits.TrailingZeros64(kings)]
+}
+
+// The opponent's pieces can always be blown up next to its king. Against a
+// bare king a single knight, bishop or rook, or two knights, cannot win
+func (atomic) canWin(g *Game, color bool) bool {
+	us, them := colorIndex(color), colorIndex(!color)
+	pieces := g.bitboards[us] &^ g.bitboards[King|us]
+	switch {
+	case pieces == 0:
+		return false
+	case g.bitboards[them]&^g.bitboards[King|them] != 0, g.bitboards[Queen|us]|g.bitboards[Pawn|us] != 0:
+		return true
+	}
+	count := bits.OnesCount64(pieces)
+	return count > 2 || count == 2 && pieces != g.bitboards[Knight|us]
+}
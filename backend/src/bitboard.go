@@ -0,0 +1,203 @@
+package game
+
+import "math/bits"
+
+var (
+	knightAttacks [BoardSize * BoardSize]uint64
+	kingAttacks   [BoardSize * BoardSize]uint64
+	// Squares attacked by a pawn of each color, indexed by White and Black
+	pawnAttacks [Black + 1][BoardSize * BoardSize]uint64
+)
+
+type magic struct {
+	mask    uint64
+	magic   uint64
+	shift   uint8
+	attacks []uint64
+}
+
+var (
+	// Squares strictly between two squares on a common line, or none
+	between [BoardSize * BoardSize][BoardSize * BoardSize]uint64
+	// The whole line through two squares, including both, or none
+	lines [BoardSize * BoardSize][BoardSize * BoardSize]uint64
+)
+
+var (
+	rookMagicTable   [BoardSize * BoardSize]magic
+	bishopMagicTable [BoardSize * BoardSize]magic
+)
+
+// Found offline by trying sparse random numbers until every blocker subset of
+// the mask maps to a slot without a conflicting attack set
+var rookMagics = [BoardSize * BoardSize]uint64{
+	0x0080068051e04000, 0x0040001000402000, 0x0080100020008008, 0x4e000a0010208440,
+	0x4200040802002010, 0x0100010008020400, 0x9080608019000600, 0x8100020080204100,
+	0x4103800480400020, 0x8015004004802100, 0x000200108a002040, 0x0801000821001000,
+	0x0015000500080070, 0x0120800400800200, 0x0109000432001100, 0x020080055b000080,
+	0x0080004000402002, 0x5260848020004008, 0x2402020014402080, 0x3000808010000802,
+	0x0304018004810800, 0x0000808004000200, 0x0002040001500248, 0x0012020000408401,
+	0x8440008080004020, 0x0804200840100040, 0x0820008080201000, 0x2080100100082100,
+	0x0001000500100800, 0x00a1000900028400, 0x0100100400c80102, 0x000001120000a044,
+	0x800080c004800620, 0x4040081000202000, 0x0d08802008801000, 0x1000800800801004,
+	0x1004000801010010, 0x0402800400800200, 0x0004080204008110, 0x0000404082000401,
+	0x00c0118861408000, 0x1100220081020048, 0x09a0430420050010, 0x0000082200420010,
+	0x2110080004008080, 0x2004201040680104, 0x1106001451820008, 0x0002224104820014,
+	0x00800c8044210500, 0x02a0200040100040, 0x040100a0001e4100, 0x00204023108a0200,
+	0x2400080080040080, 0x1289008400020900, 0x0002088250010400, 0x0001006084010200,
+	0x0001023480002141, 0x0006400021810015, 0x8400100840200101, 0x40003000a1000825,
+	0x1002011008200402, 0x100d000400080201, 0x0020048806102904, 0x8401000020804201,
+}
+
+var bishopMagics = [BoardSize * BoardSize]uint64{
+	0x4c40240122060016, 0x8048110404004a80, 0x8004440410414020, 0x021c410060405000,
+	0x80cd1040d0480812, 0x0002021104000082, 0x08440082a8200001, 0x00202a0800841002,
+	0x0200c40810842088, 0x60c0081000c08901, 0x00a3d0040042510c, 0x1c00110400808541,
+	0x0400820211084005, 0x0000008860080800, 0x002002020202c000, 0x0400344e08040a81,
+	0x812800102098a080, 0x00202010823a2040, 0x4086400800830201, 0x5008012a22004000,
+	0x0004801c00a00000, 0x0000400200505400, 0x0480408401080820, 0x8000400029082824,
+	0x0008880804501000, 0x0001600048084100, 0x0108220624040400, 0x0008080000820002,
+	0xc804040010410041, 0x01080a0040208400, 0x2018030480a88800, 0x4040410020410810,
+	0x1108044010100210, 0x084a100400029800, 0x0801080100820c00, 0x8010400808108200,
+	0x0084008400020500, 0x0002004200290481, 0x0010150200032090, 0x8404042220404102,
+	0x0302080308004008, 0x1200420820000408, 0x0802002024200800, 0x4020824208000084,
+	0x000002020c008200, 0x2c40208081000882, 0x2082223441000401, 0x8804080081101020,
+	0x4401011002220808, 0x81020c4202100000, 0x4005004404040308, 0x0820400c42020001,
+	0x0020206421820010, 0x0150401001424008, 0x02a20242020c0608, 0x5020110109011200,
+	0x2050840108410401, 0x0100090880842108, 0x220008960142187a, 0x1111028880208820,
+	0x4400200042028200, 0x4400010802084206, 0x0000400242040100, 0x0002201104010944,
+}
+
+var (
+	rookDirections   = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	bishopDirections = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+)
+
+var (
+	knightSteps = [8][2]int{{2, 1}, {2, -1}, {1, 2}, {1, -2}, {-1, 2}, {-1, -2}, {-2, 1}, {-2, -1}}
+	kingSteps   = [8][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+)
+
+func precomputedAttackTables() {
+	for square := 0; square < BoardSize*BoardSize; square++ {
+		knightAttacks[square] = stepAttacks(square, knightSteps[:])
+		kingAttacks[square] = stepAttacks(square, kingSteps[:])
+		pawnAttacks[White][square] = stepAttacks(square, [][2]int{{1, 1}, {1, -1}})
+		pawnAttacks[Black][square] = stepAttacks(square, [][2]int{{-1, 1}, {-1, -1}})
+
+		fillMagicTable(&rookMagicTable[square], square, rookMagics[square], rookDirections)
+		fillMagicTable(&bishopMagicTable[square], square, bishopMagics[square], bishopDirections)
+	}
+
+	for from := 0; from < BoardSize*BoardSize; from++ {
+		for to := 0; to < BoardSize*BoardSize; to++ {
+			ends := uint64(1)<<from | uint64(1)<<to
+			if rookAttacks(from, 0)&(1<<to) != 0 {
+				between[from][to] = rookAttacks(from, 1<<to) & rookAttacks(to, 1<<from)
+				lines[from][to] = rookAttacks(from, 0)&rookAttacks(to, 0) | ends
+			} else if bishopAttacks(from, 0)&(1<<to) != 0 {
+				between[from][to] = bishopAttacks(from, 1<<to) & bishopAttacks(to, 1<<from)
+				lines[from][to] = bishopAttacks(from, 0)&bishopAttacks(to, 0) | ends
+			}
+		}
+	}
+}
+
+func stepAttacks(square int, steps [][2]int) uint64 {
+	attacks := uint64(0)
+	rank, file := square/BoardSize, square%BoardSize
+	for _, step := range steps {
+		r, f := rank+step[0], file+step[1]
+		if r >= 0 && r < BoardSize && f >= 0 && f < BoardSize {
+			attacks |= 1 << (r*BoardSize + f)
+		}
+	}
+	return attacks
+}
+
+// Fills the attack table for every subset of the blockers that matter for the
+// square. The magic maps each subset to an index without harmful collisions
+func fillMagicTable(m *magic, square int, magicNumber uint64, directions [4][2]int) {
+	m.mask = slidingAttacks(square, 0, directions, true)
+	m.magic = magicNumber
+	m.shift = uint8(64 - bits.OnesCount64(m.mask))
+	m.attacks = make([]uint64, 1<<bits.OnesCount64(m.mask))
+
+	// Carry-rippler trick to walk every subset of the mask
+	occupancy := uint64(0)
+	for {
+		m.attacks[(occupancy*m.magic)>>m.shift] = slidingAttacks(square, occupancy, directions, false)
+		occupancy = (occupancy - m.mask) & m.mask
+		if occupancy == 0 {
+			break
+		}
+	}
+}
+
+// Walks the rays from the square until they hit a blocker. Used to build the
+// magic tables, and with edgesOnly to leave out the last square of each ray,
+// which never blocks anything
+func slidingAttacks(square int, occupancy uint64, directions [4][2]int, edgesOnly bool) uint64 {
+	attacks := uint64(0)
+	rank, file := square/BoardSize, square%BoardSize
+	for _, direction := range directions {
+		r, f := rank+direction[0], file+direction[1]
+		for r >= 0 && r < BoardSize && f >= 0 && f < BoardSize {
+			nextR, nextF := r+direction[0], f+direction[1]
+			if edgesOnly && (nextR < 0 || nextR >= BoardSize || nextF < 0 || nextF >= BoardSize) {
+				break
+			}
+			bit := uint64(1) << (r*BoardSize + f)
+			attacks |= bit
+			if occupancy&bit != 0 {
+				break
+			}
+			r, f = nextR, nextF
+		}
+	}
+	return attacks
+}
+
+func rookAttacks(square int, occupancy uint64) uint64 {
+	m := &rookMagicTable[square]
+	return m.attacks[((occupancy&m.mask)*m.magic)>>m.shift]
+}
+
+func bishopAttacks(square int, occupancy uint64) uint64 {
+	m := &bishopMagicTable[square]
+	return m.attacks[((occupancy&m.mask)*m.magic)>>m.shift]
+}
+
+func queenAttacks(square int, occupancy uint64) uint64 {
+	return rookAttacks(square, occupancy) | bishopAttacks(square, occupancy)
+}
+
+// Removes the lowest set bit and returns its square
+func popLSB(bitboard *uint64) int {
+	square := bits.TrailingZeros64(*bitboard)
+	*bitboard &= *bitboard - 1
+	return square
+}
+
+// Attack lookups for code outside the package, such as evaluation. The tables
+// are filled when the first game is created
+
+func KnightAttacks(square int) uint64 {
+	return knightAttacks[square]
+}
+
+func KingAttacks(square int) uint64 {
+	return kingAttacks[square]
+}
+
+func BishopAttacks(square int, occupancy uint64) uint64 {
+	return bishopAttacks(square, occupancy)
+}
+
+func RookAttacks(square int, occupancy uint64) uint64 {
+	return rookAttacks(square, occupancy)
+}
+
+func QueenAttacks(square int, occupancy uint64) uint64 {
+	return queenAttacks(square, occupancy)
+}
@@ -0,0 +1,151 @@
+package game
+
+import "math/bits"
+
+// Everything needed to generate only legal moves for one side, computed once
+// per position. A nil checkInfo places no restrictions, which gives
+// pseudo-legal moves
+type checkInfo struct {
+	kingSquare int
+	checkers   uint64
+	pinned     uint64
+	// Squares that capture the checking piece or block the check, or every
+	// square when not in check
+	evasions uint64
+	// Squares attacked by the opponent, with the king taken off the board so
+	// it cannot step back along the line of a checking slider
+	attacked uint64
+}
+
+// Returns false if color has no king
+func (g *Game) newCheckInfo(color bool) (checkInfo, bool) {
+	kingSquare := g.findKing(color)
+	if kingSquare < 0 {
+		return checkInfo{}, false
+	}
+
+	us, them := colorIndex(color), colorIndex(!color)
+	occupancy := g.bitboards[White] | g.bitboards[Black]
+	info := checkInfo{
+		kingSquare: kingSquare,
+		checkers:   g.attackersTo(kingSquare, color, occupancy),
+		evasions:   ^uint64(0),
+		attacked:   g.attackedBy(them, occupancy&^(1<<kingSquare)),
+	}
+	if bits.OnesCount64(info.checkers) == 1 {
+		checker := bits.TrailingZeros64(info.checkers)
+		info.evasions = info.checkers | between[kingSquare][checker]
+	}
+
+	// Sliders that would attack the king if our own pieces were not in the way
+	queens := g.bitboards[Queen|them]
+	snipers := rookAttacks(kingSquare, g.bitboards[them])&(g.bitboards[Rook|them]|queens) |
+		bishopAttacks(kingSquare, g.bitboards[them])&(g.bitboards[Bishop|them]|queens)
+	for snipers != 0 {
+		blockers := between[kingSquare][popLSB(&snipers)] & occupancy
+		if bits.OnesCount64(blockers) == 1 && blockers&g.bitboards[us] != 0 {
+			info.pinned |= blockers
+		}
+	}
+	return info, true
+}
+
+// Squares the piece on the start square may move to without leaving the king
+// in check, not counting en passant and castling
+func (info *checkInfo) targets(startSquare int) uint64 {
+	if info == nil {
+		return ^uint64(0)
+	}
+	targets := info.evasions
+	if info.pinned&(1<<startSquare) != 0 {
+		targets &= lines[info.kingSquare][startSquare]
+	}
+	return targets
+}
+
+func (info *checkInfo) attackedSquares() uint64 {
+	if info == nil {
+		return 0
+	}
+	return info.attacked
+}
+
+func (info *checkInfo) inCheck() bool {
+	return info != nil && info.checkers != 0
+}
+
+func (info *checkInfo) inDoubleCheck() bool {
+	return info != nil && bits.OnesCount64(info.checkers) > 1
+}
+
+// En passant removes two pieces from the rank of the captured pawn, which can
+// expose the king even when neither pawn is pinned, so the position after the
+// capture is checked directly
+func (g *Game) isEnPassantLegal(move Move, info *checkInfo) bool {
+	if info == nil {
+		return true
+	}
+	color := g.Board[move.StartSquare].color() == White
+	capturedSquare := move.TargetSquare - BoardSize
+	if !color {
+		capturedSquare = move.TargetSquare + BoardSize
+	}
+
+	occupancy := g.bitboards[White] | g.bitboards[Black]
+	occupancy ^= 1<<move.StartSquare | 1<<capturedSquare | 1<<move.TargetSquare
+	return g.attackersTo(info.kingSquare, color, occupancy)&^(1<<capturedSquare) == 0
+}
+
+// Returns every square attacked by the pieces of the color index
+func (g *Game) attackedBy(color int, occupancy uint64) uint64 {
+	attacked := uint64(0)
+	for pawns := g.bitboards[Pawn|color]; pawns != 0; {
+		attacked |= pawnAttacks[color][popLSB(&pawns)]
+	}
+	for knights := g.bitboards[Knight|color]; knights != 0; {
+		attacked |= knightAttacks[popLSB(&knights)]
+	}
+	queens := g.bitboards[Queen|color]
+	for bishops := g.bitboards[Bishop|color] | queens; bishops != 0; {
+		attacked |= bishopAttacks(popLSB(&bishops), occupancy)
+	}
+	for rooks := g.bitboards[Rook|color] | queens; rooks != 0; {
+		attacked |= rookAttacks(popLSB(&rooks), occupancy)
+	}
+	for kings := g.bitboards[King|color]; kings != 0; {
+		attacked |= kingAttacks[popLSB(&kings)]
+	}
+	return attacked
+}
+
+// InCheck reports whether the side to move is in check
+func (g *Game) InCheck() bool {
+	return g.isKingInCheck(g.ColorToMove)
+}
+
+// Checkers returns the squares of the pieces giving check to the side to move
+func (g *Game) Checkers() []int {
+	info, ok := g.newCheckInfo(g.ColorToMove)
+	if !ok {
+		return []int{}
+	}
+	return squaresOf(info.checkers)
+}
+
+// Pinned returns the squares of the pieces of the side to move that cannot
+// leave the line between their king and an attacking slider
+func (g *Game) Pinned() []int {
+	info, ok := g.newCheckInfo(g.ColorToMove)
+	if !ok {
+		return []int{}
+	}
+	return squaresOf(info.pinned)
+}
+
+func squaresOf(bitboard uint64) []int {
+	squares := make([]int, 0, bits.OnesCount64(bitboard))
+	for bitboard != 0 {
+		squares = append(squares, popLSB(&bitboard))
+	}
+	return squares
+}
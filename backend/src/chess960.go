@@ -0,0 +1,106 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Chess960Positions is the number of Chess960 start positions
+const Chess960Positions = 960
+
+// Chess960StandardPosition is the number of the standard start position
+const Chess960StandardPosition = 518
+
+// Placements of the two knights on the five files left after the bishops and
+// the queen, as numbered by Scharnagl
+var knightPlacements = [10][2]int{{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4}}
+
+// Chess960StartFen returns the FEN of a Chess960 start position, numbered from
+// 0 to 959 in Scharnagl's scheme, with the castling rights in Shredder-FEN
+func Chess960StartFen(position int) (string, error) {
+	if position < 0 || position >= Chess960Positions {
+		return "", fmt.Errorf("chess960 position must be between 0 and %d, got %d", Chess960Positions-1, position)
+	}
+
+	var backRank [BoardSize]byte
+	n := position
+	// Light squared bishop on b, d, f or h, dark squared one on a, c, e or g
+	backRank[n%4*2+1] = 'b'
+	n /= 4
+	backRank[n%4*2] = 'b'
+	n /= 4
+	placeOnEmpty(&backRank, n%6, 'q')
+	n /= 6
+	// The second knight is placed first, so the first keeps its index
+	placeOnEmpty(&backRank, knightPlacements[n][1], 'n')
+	placeOnEmpty(&backRank, knightPlacements[n][0], 'n')
+	// The king goes between the rooks on the files left
+	for _, piece := range []byte{'r', 'k', 'r'} {
+		placeOnEmpty(&backRank, 0, piece)
+	}
+
+	var rookFiles []byte
+	for file, piece := range backRank {
+		if piece == 'r' {
+			rookFiles = append(rookFiles, byte('A'+file))
+		}
+	}
+	// Kingside first
+	castlingRights := string(rookFiles[1]) + string(rookFiles[0])
+	castlingRights += strings.ToLower(castlingRights)
+
+	black := string(backRank[:])
+	return fmt.Sprintf("%s/pppppppp/8/8/8/8/PPPPPPPP/%s w %s - 0 1", black, strings.ToUpper(black), castlingRights), nil
+}
+
+// Puts the piece on the empty square with the index, counting only the empty
+// ones
+func placeOnEmpty(backRank *[BoardSize]byte, index int, piece byte) {
+	for file := range backRank {
+		if backRank[file] != 0 {
+			continue
+		}
+		if index == 0 {
+			backRank[file] = piece
+			return
+		}
+		index--
+	}
+}
+
+// NewChess960Game starts a Chess960 game from one of its numbered start
+// positions
+func NewChess960Game(position int) (*Game, error) {
+	fen, err := Chess960StartFen(position)
+	if err != nil {
+		return nil, err
+	}
+	return NewGameFromFen(fen), nil
+}
+
+// Chess960 reports whether castling moves are encoded as the king taking its
+// own rook and the castling rights are written as rook files
+func (g *Game) Chess960() bool {
+	return g.chess960
+}
+
+// SetChess960 plays the game as Chess960 or not. A position with the king and
+// rooks on their standard squares is the same in both, only the encoding of
+// castling moves differs, so the mode cannot change once moves are played
+func (g *Game) SetChess960(chess960 bool) error {
+	if len(g.moveHistory) > 0 {
+		return fmt.Errorf("cannot change the castling mode after moves are played")
+	}
+	if !chess960 {
+		for right, rookSquare := range g.castlingRooks {
+			rookFile := rookSquare % BoardSize
+			kingFile := g.findKing(right >= 2) % BoardSize
+			if g.currentGameState>>right&1 != 0 && (kingFile != 4 || rookFile != 0 && rookFile != BoardSize-1) {
+				return fmt.Errorf("castling rights of the position need chess960")
+			}
+		}
+	}
+	g.chess960 = chess960
+	g.initialFen = g.CurrentFen()
+	return nil
+}
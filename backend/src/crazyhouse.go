@@ -0,0 +1,286 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Crazyhouse puts every captured piece into the pocket of the side that
+// captured it, from where it can be dropped back onto the board instead of
+// making a move. Promoted pieces go back to the pocket as pawns
+var Crazyhouse Variant = crazyhouse{}
+
+var (
+	ErrFenPocket   = errors.New("pocket must list pieces other than kings, like [QRbp]")
+	ErrFenPromoted = errors.New("only pieces other than pawns and kings can be marked as promoted")
+)
+
+// Bit of the game state set when the captured piece was promoted
+const capturedPromoted uint32 = 1 << 26
+
+// No more pieces of one type can be in a pocket than there are pawns
+const maxPocketCount = 16
+
+// Pocket pieces in the order FEN lists them
+var pocketOrder = [...]int{Queen, Rook, Bishop, Knight, Pawn}
+
+type crazyhouse struct{ standardRules }
+
+func (crazyhouse) Name() string { return "Crazyhouse" }
+
+func (crazyhouse) StartFen() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1"
+}
+
+// Moves the captured piece into the pocket of the side that moved, takes a
+// dropped one out of it and keeps track of which pieces were promoted
+func (crazyhouse) makeMove(g *Game, move Move, gameState uint32) uint32 {
+	us := colorIndex(g.ColorToMove)
+	from, to := move.StartSquare, move.TargetSquare
+	if isDropFlag(move.Flag) {
+		g.removeFromPocket(dropPieceType(move.Flag) | us)
+		return gameState
+	}
+
+	if captured := int(gameState>>8) & 0b111; captured != None {
+		if g.promoted&(1<<to) != 0 {
+			captured = Pawn
+			gameState |= capturedPromoted
+		}
+		g.addToPocket(captured | us)
+	}
+	if move.Flag == Castling {
+		// The king is never promoted, the rook may stay on its square
+		_, rookFrom, rookTo := g.castlingSquares(move, g.ColorToMove)
+		if g.promoted&(1<<rookFrom) != 0 {
+			g.promoted = g.promoted&^(1<<rookFrom) | 1<<rookTo
+		}
+		return gameState
+	}
+	promoted := isPromotionFlag(move.Flag) || g.promoted&(1<<from) != 0
+	g.promoted &^= 1<<from | 1<<to
+	if promoted {
+		g.promoted |= 1 << to
+	}
+	return gameState
+}
+
+// Called with the side that made the move not yet switched back
+func (crazyhouse) unmakeMove(g *Game, move Move) {
+	us := colorIndex(!g.ColorToMove)
+	from, to := move.StartSquare, move.TargetSquare
+	if isDropFlag(move.Flag) {
+		g.addToPocket(dropPieceType(move.Flag) | us)
+		return
+	}
+
+	if move.Flag == Castling {
+		_, rookFrom, rookTo := g.castlingSquares(move, !g.ColorToMove)
+		if g.promoted&(1<<rookTo) != 0 {
+			g.promoted = g.promoted&^(1<<rookTo) | 1<<rookFrom
+		}
+		return
+	}
+	promoted := !isPromotionFlag(move.Flag) && g.promoted&(1<<to) != 0
+	g.promoted &^= 1 << to
+	if promoted {
+		g.promoted |= 1 << from
+	}
+	if captured := int(g.currentGameState>>8) & 0b111; captured != None {
+		if g.currentGameState&capturedPromoted != 0 {
+			captured = Pawn
+			g.promoted |= 1 << to
+		}
+		g.removeFromPocket(captured | us)
+	}
+}
+
+// No material leaves the game, only bare kings with a minor piece between
+// them cannot mate
+func (crazyhouse) canWin(g *Game, color bool) bool {
+	pieces := 0
+	for _, pieceType := range pocketOrder {
+		for _, c := range [...]int{White, Black} {
+			count := g.pockets[pieceType|c] + bits.OnesCount64(g.bitboards[pieceType|c])
+			if count > 0 && pieceType != Knight && pieceType != Bishop {
+				return true
+			}
+			pieces += count
+		}
+	}
+	return pieces > 1
+}
+
+// The pocket follows the piece placement in brackets, "[QRbp]", or as a ninth
+// rank, "/QRbp", and promoted pieces are followed by a tilde, "Q~". A FEN
+// without a pocket has empty pockets
+func (crazyhouse) parseFenFields(g *Game, fen string) (string, uint32, error) {
+	fields := strings.Fields(fen)
+	if len(fields) == 0 {
+		return fen, 0, nil
+	}
+	placement, pocket := fields[0], ""
+	if i := strings.IndexByte(placement, '['); i >= 0 {
+		if !strings.HasSuffix(placement, "]") {
+			return fen, 0, ErrFenPocket
+		}
+		placement, pocket = placement[:i], placement[i+1:len(placement)-1]
+	} else if strings.Count(placement, "/") == BoardSize {
+		i := strings.LastIndexByte(placement, '/')
+		placement, pocket = placement[:i], placement[i+1:]
+	}
+
+	g.pockets = [23]int{}
+	for _, symbol := range pocket {
+		piece := createPiece(symbol)
+		if !strings.ContainsRune("PNBRQpnbrq", symbol) || g.pockets[piece.Type] == maxPocketCount {
+			return fen, 0, fmt.Errorf("%w, got %q", ErrFenPocket, pocket)
+		}
+		g.pockets[piece.Type]++
+	}
+
+	g.promoted = 0
+	rank, file := BoardSize-1, 0
+	previous := '/'
+	for _, char := range placement {
+		switch {
+		case char == '~':
+			if !strings.ContainsRune("NBRQnbrq", previous) || rank < 0 || file > BoardSize {
+				return fen, 0, ErrFenPromoted
+			}
+			g.promoted |= 1 << (rank*BoardSize + file - 1)
+		case char == '/':
+			rank, file = rank-1, 0
+		case char >= '1' && char <= '8':
+			file += int(char - '0')
+		default:
+			file++
+		}
+		previous = char
+	}
+	fields[0] = strings.ReplaceAll(placement, "~", "")
+	return strings.Join(fields, " "), 0, nil
+}
+
+func (crazyhouse) writeFenFields(g *Game, fen string) string {
+	fields := strings.Fields(fen)
+	var placement strings.Builder
+	square := (BoardSize - 1) * BoardSize
+	for _, char := range fields[0] {
+		placement.WriteRune(char)
+		switch {
+		case char == '/':
+			square -= 2 * BoardSize
+		case char >= '1' && char <= '8':
+			square += int(char - '0')
+		default:
+			if g.promoted&(1<<square) != 0 {
+				placement.WriteByte('~')
+			}
+			square++
+		}
+	}
+
+	placement.WriteByte('[')
+	for _, color := range [...]int{White, Black} {
+		for _, pieceType := range pocketOrder {
+			placement.WriteString(strings.Repeat(symbolForPiece(Piece{pieceType | color}), g.pockets[pieceType|color]))
+		}
+	}
+	placement.WriteByte(']')
+	fields[0] = placement.String()
+	return strings.Join(fields, " ")
+}
+
+// Pocket returns how many pieces of each type the color holds in Crazyhouse,
+// indexed by piece type
+func (g *Game) Pocket(white bool) [Queen + 1]int {
+	var pocket [Queen + 1]int
+	for _, pieceType := range pocketOrder {
+		pocket[pieceType] = g.pockets[pieceType|colorIndex(white)]
+	}
+	return pocket
+}
+
+func (g *Game) addToPocket(piece int) {
+	g.pockets[piece]++
+	g.hash ^= zobristPocketKeys[piece][g.pockets[piece]]
+}
+
+func (g *Game) removeFromPocket(piece int) {
+	g.hash ^= zobristPocketKeys[piece][g.pockets[piece]]
+	g.pockets[piece]--
+}
+
+// Adds the drops of the color, onto the squares that block a check when in
+// check. Dropping a piece never exposes the own king
+func (g *Game) generateDrops(list *MoveList, us int, info *checkInfo) {
+	targets := ^(g.bitboards[White] | g.bitboards[Black])
+	if info != nil {
+		targets &= info.evasions
+	}
+	for _, pieceType := range pocketOrder {
+		if g.pockets[pieceType|us] == 0 {
+			continue
+		}
+		squares := targets
+		if pieceType == Pawn {
+			squares &^= firstAndLastRank
+		}
+		for squares != 0 {
+			square := popLSB(&squares)
+			list.add(Move{square, square, dropFlag(pieceType)})
+		}
+	}
+}
+
+const firstAndLastRank uint64 = 0xff000000000000ff
+
+// IsDrop reports whether the move puts a piece from the pocket on the board
+func (m Move) IsDrop() bool {
+	return isDropFlag(m.Flag)
+}
+
+// DropPieceType returns the type of the piece a drop puts on the board, None
+// for other moves
+func (m Move) DropPieceType() int {
+	return dropPieceType(m.Flag)
+}
+
+func isDropFlag(flag int) bool {
+	return flag >= DropPawn && flag <= DropQueen
+}
+
+func dropPieceType(flag int) int {
+	switch flag {
+	case DropPawn:
+		return Pawn
+	case DropKnight:
+		return Knight
+	case DropBishop:
+		return Bishop
+	case DropRook:
+		return Rook
+	case DropQueen:
+		return Queen
+	}
+	return None
+}
+
+func dropFlag(pieceType int) int {
+	switch pieceType {
+	case Pawn:
+		return DropPawn
+	case Knight:
+		return DropKnight
+	case Bishop:
+		return DropBishop
+	case Rook:
+		return DropRook
+	case Queen:
+		return DropQueen
+	}
+	return -1
+}
@@ -8,7 +8,11 @@ import (
 )
 
 func (g *Game) loadPositionFromFen(fen string) error {
-	pieces, color, castlingRights, enPassantSquare, fiftyMoveCounter, plyCount, err := parseFen(fen)
+	standardFen, variantState, err := g.Variant().parseFenFields(g, fen)
+	if err != nil {
+		return err
+	}
+	pieces, color, castlingRights, enPassantSquare, fiftyMoveCounter, plyCount, err := parseFen(standardFen)
 	if err != nil {
 		return err
 	}
@@ -17,26 +21,17 @@ func (g *Game) loadPositionFromFen(fen string) error {
 
 	g.ColorToMove = color == "w"
 
-	var currentGameState uint32 = 0
-	var newCastleState uint32 = 0
-	if strings.Contains(castlingRights, "K") {
-		newCastleState |= 0b1000
-	}
-	if strings.Contains(castlingRights, "Q") {
-		newCastleState |= 0b0100
-	}
-	if strings.Contains(castlingRights, "k") {
-		newCastleState |= 0b0010
-	}
-	if strings.Contains(castlingRights, "q") {
-		newCastleState |= 0b0001
-	}
+	currentGameState := variantState
+	newCastleState := g.loadCastlingRights(castlingRights)
 	currentGameState |= newCastleState
 
 	if enPassantSquare != "-" {
 		enPassantIndex := util.FromChessNotation(enPassantSquare)
 		enPassantFile := enPassantIndex % BoardSize
 		currentGameState |= uint32(enPassantFile+1) << 4
+		if g.canCaptureEnPassant(enPassantFile, g.ColorToMove) {
+			currentGameState |= enPassantCapturable
+		}
 	}
 
 	g.currentGameState = currentGameState
@@ -45,9 +40,88 @@ func (g *Game) loadPositionFromFen(fen string) error {
 
 	g.gameStateHistory = []uint32{}
 	g.gameStateHistory = append(g.gameStateHistory, currentGameState)
+	g.moveHistory = []Move{}
+	g.explosions = nil
+	g.initialFen = fen
+	g.hash = g.computeHash()
+	g.hashHistory = []uint64{g.hash}
+	g.endedBy = Ongoing
 	return nil
 }
 
+// Reads the castling rights, see castlingRook. Rights without a king and rook
+// to castle with are ignored. The game is played as Chess960 when the rights
+// name files or the king and rooks are not on their standard squares
+func (g *Game) loadCastlingRights(castlingRights string) uint32 {
+	var castleState uint32 = 0
+	g.castlingRooks = [4]int{}
+	g.chess960 = false
+	for _, symbol := range castlingRights {
+		right, kingSquare, rookSquare, err := castlingRook(&g.Board, symbol)
+		if err != nil {
+			continue
+		}
+		castleState |= 1 << right
+		g.castlingRooks[right] = rookSquare
+		rookFile := rookSquare % BoardSize
+		if !strings.ContainsRune("KQkq", symbol) || kingSquare%BoardSize != 4 || rookFile != 0 && rookFile != BoardSize-1 {
+			g.chess960 = true
+		}
+	}
+	return castleState
+}
+
+// Finds the king and rook a castling symbol stands for and the bit of the
+// right. K and Q stand for the outermost rook on either side of the king, as
+// in standard FEN and X-FEN, a file letter for the rook on that file, as in
+// Shredder-FEN
+func castlingRook(board *[BoardSize * BoardSize]Piece, symbol rune) (right, kingSquare, rookSquare int, err error) {
+	white := symbol >= 'A' && symbol <= 'Z'
+	lower := symbol | 0x20
+	if lower != 'k' && lower != 'q' && (lower < 'a' || lower > 'h') {
+		return 0, 0, 0, ErrFenCastlingRights
+	}
+
+	rank := 0
+	if !white {
+		rank = (BoardSize - 1) * BoardSize
+	}
+	king, rook := Piece{King | colorIndex(white)}, Piece{Rook | colorIndex(white)}
+	kingFile := -1
+	for file := 0; file < BoardSize; file++ {
+		if board[rank+file] == king {
+			kingFile = file
+		}
+	}
+	if kingFile == -1 {
+		return 0, 0, 0, errFenCastlingNoKing
+	}
+
+	rookFile := -1
+	switch lower {
+	case 'k':
+		for file := BoardSize - 1; file > kingFile && rookFile == -1; file-- {
+			if board[rank+file] == rook {
+				rookFile = file
+			}
+		}
+	case 'q':
+		for file := 0; file < kingFile && rookFile == -1; file++ {
+			if board[rank+file] == rook {
+				rookFile = file
+			}
+		}
+	default:
+		if file := int(lower - 'a'); board[rank+file] == rook {
+			rookFile = file
+		}
+	}
+	if rookFile == -1 {
+		return 0, 0, 0, errFenCastlingNoRook
+	}
+	return castlingRight(white, rookFile > kingFile), rank + kingFile, rank + rookFile, nil
+}
+
 func (g *Game) LoadPiecesFromFen(fen string) {
 	rank := 7
 	file := 0
@@ -88,6 +162,7 @@ func (g *Game) LoadPiecesFromFen(fen string) {
 
 func (g *Game) updateBitboards(index int, piece Piece) {
 	g.bitboards[piece.Type] |= 1 << index
+	g.bitboards[piece.color()] |= 1 << index
 }
 
 func (g *Game) CurrentFen() string {
@@ -126,20 +201,7 @@ func (g *Game) CurrentFen() string {
 	}
 
 	fen += " "
-	currentCastlingRights := g.currentGameState & 0b1111
-	castlingRights := ""
-	if currentCastlingRights&0b1000 != 0 {
-		castlingRights += "K"
-	}
-	if currentCastlingRights&0b0100 != 0 {
-		castlingRights += "Q"
-	}
-	if currentCastlingRights&0b0010 != 0 {
-		castlingRights += "k"
-	}
-	if currentCastlingRights&0b0001 != 0 {
-		castlingRights += "q"
-	}
+	castlingRights := g.castlingRightsFen()
 	if castlingRights == "" {
 		castlingRights = "-"
 	}
@@ -163,24 +225,52 @@ func (g *Game) CurrentFen() string {
 	fen += " "
 	fen += strconv.Itoa(int(g.plyCount))
 
+	if g.variant != nil {
+		return g.variant.writeFenFields(g, fen)
+	}
 	return fen
 }
 
+// Writes the castling rights as KQkq, or as the files of the rooks in
+// Shredder-FEN for Chess960 games
+func (g *Game) castlingRightsFen() string {
+	castlingRights := ""
+	for _, right := range [...]int{castlingRight(true, true), castlingRight(true, false), castlingRight(false, true), castlingRight(false, false)} {
+		if g.currentGameState>>right&1 == 0 {
+			continue
+		}
+		symbol := "KQkq"[3-right]
+		if g.chess960 {
+			symbol = 'A' + byte(g.castlingRooks[right]%BoardSize)
+			if right < 2 {
+				symbol |= 0x20
+			}
+		}
+		castlingRights += string(symbol)
+	}
+	return castlingRights
+}
+
 func parseFen(fen string) (pieces, color, castlingRights, enPassantSquare string, fiftyMoveCounter, plyCount uint32, err error) {
-	splitFen := strings.Split(fen, " ")
+	splitFen := strings.Fields(fen)
+	if len(splitFen) != 6 {
+		return "", "", "", "", 0, 0, &FenError{Fen: fen, Err: ErrFenFieldCount, Detail: fmt.Sprintf("got %d fields", len(splitFen))}
+	}
 	pieces = splitFen[0]
 	color = splitFen[1]
 	castlingRights = splitFen[2]
 	enPassantSquare = splitFen[3]
 	fiftyMoveCounterInt, err := strconv.Atoi(splitFen[4])
 	if err != nil {
-		return "", "", "", "", 0, 0, err
+		return "", "", "", "", 0, 0, &FenError{Fen: fen, Err: ErrFenHalfmoveClock, Detail: fmt.Sprintf("got %q", splitFen[4])}
 	}
-	fiftyMoveCounter = uint32(fiftyMoveCounterInt)
+	// The counter has eight bits in the game state, FENs that were not
+	// validated are held to what it can store
+	fiftyMoveCounter = uint32(min(max(fiftyMoveCounterInt, 0), maxHalfmoveClock))
 
 	plyCountInt, err := strconv.Atoi(splitFen[5])
 	if err != nil {
-		return "", "", "", "", 0, 0, err
+		return "", "", "", "", 0, 0, &FenError{Fen: fen, Err: ErrFenFullmoveNumber, Detail: fmt.Sprintf("got %q", splitFen[5])}
 	}
 	plyCount = uint32(plyCountInt)
 	return pieces, color, castlingRights, enPassantSquare, fiftyMoveCounter, plyCount, nil
@@ -0,0 +1,251 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"web-chess/backend/util"
+)
+
+var (
+	ErrFenFieldCount       = errors.New("fen must have six space separated fields")
+	ErrFenRankCount        = errors.New("piece placement must have eight ranks")
+	ErrFenRankLength       = errors.New("rank must describe exactly eight squares")
+	ErrFenPiece            = errors.New("invalid piece character")
+	ErrFenKingCount        = errors.New("each side must have exactly one king")
+	ErrFenPawnOnBackRank   = errors.New("pawns cannot stand on the first or eighth rank")
+	ErrFenSideToMove       = errors.New("side to move must be w or b")
+	ErrFenOpponentInCheck  = errors.New("side not to move is in check")
+	ErrFenCastlingRights   = errors.New("invalid castling rights")
+	ErrFenEnPassant        = errors.New("invalid en passant square")
+	ErrFenHalfmoveClock    = errors.New("halfmove clock must be an integer from 0 to 150")
+	ErrFenFullmoveNumber   = errors.New("fullmove number must be a positive integer")
+	errFenCastlingNoKing   = fmt.Errorf("%w: king is not on its starting square", ErrFenCastlingRights)
+	errFenCastlingNoRook   = fmt.Errorf("%w: rook is not on its starting square", ErrFenCastlingRights)
+	errFenEnPassantNoPawn  = fmt.Errorf("%w: no pawn that just moved two squares", ErrFenEnPassant)
+	errFenEnPassantBlocked = fmt.Errorf("%w: squares the pawn passed are not empty", ErrFenEnPassant)
+)
+
+// The 75-move rule ends every game before the halfmove clock goes past this,
+// and the game state has room for no more
+const maxHalfmoveClock = 150
+
+// FenError describes why a FEN string was rejected. Err is one of the ErrFen
+// values and can be checked with errors.Is
+type FenError struct {
+	Fen    string
+	Err    error
+	Detail string
+}
+
+func (e *FenError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("invalid fen %q: %v", e.Fen, e.Err)
+	}
+	return fmt.Sprintf("invalid fen %q: %v (%s)", e.Fen, e.Err, e.Detail)
+}
+
+func (e *FenError) Unwrap() error {
+	return e.Err
+}
+
+// ParseFen creates a game from a FEN string after validating that it
+// describes a legal position. Use it instead of NewGameFromFen for input that
+// is not known to be valid
+func ParseFen(fen string) (*Game, error) {
+	return ParseVariantFen(Standard, fen)
+}
+
+// ParseVariantFen is ParseFen for a position of the variant, whose FEN may
+// have fields of its own
+func ParseVariantFen(variant Variant, fen string) (*Game, error) {
+	if err := validateFenFields(variant, fen); err != nil {
+		return nil, err
+	}
+
+	g := NewVariantGameFromFen(variant, fen)
+	if g.isKingInCheck(!g.ColorToMove) {
+		return nil, &FenError{Fen: fen, Err: ErrFenOpponentInCheck}
+	}
+	return g, nil
+}
+
+func validateFenFields(variant Variant, fen string) error {
+	// Fields outside the game state, such as pockets, are loaded into a game
+	// that is thrown away
+	standardFen, _, err := variant.parseFenFields(&Game{}, fen)
+	if err != nil {
+		return &FenError{Fen: fen, Err: err, Detail: detailOf(err)}
+	}
+	fields := strings.Fields(standardFen)
+	if len(fields) != 6 {
+		return &FenError{Fen: fen, Err: ErrFenFieldCount, Detail: fmt.Sprintf("got %d fields", len(fields))}
+	}
+
+	board, err := validatePiecePlacement(fields[0])
+	if err == nil {
+		err = variant.validatePieces(&board)
+	}
+	if err != nil {
+		return &FenError{Fen: fen, Err: err, Detail: detailOf(err)}
+	}
+
+	if fields[1] != "w" && fields[1] != "b" {
+		return &FenError{Fen: fen, Err: ErrFenSideToMove, Detail: fmt.Sprintf("got %q", fields[1])}
+	}
+	whiteToMove := fields[1] == "w"
+
+	if err := validateCastlingRights(fields[2], board); err != nil {
+		return &FenError{Fen: fen, Err: err, Detail: fmt.Sprintf("got %q", fields[2])}
+	}
+
+	if err := validateEnPassantSquare(fields[3], whiteToMove, board); err != nil {
+		return &FenError{Fen: fen, Err: err, Detail: fmt.Sprintf("got %q", fields[3])}
+	}
+
+	if n, err := strconv.Atoi(fields[4]); err != nil || n < 0 || n > maxHalfmoveClock {
+		return &FenError{Fen: fen, Err: ErrFenHalfmoveClock, Detail: fmt.Sprintf("got %q", fields[4])}
+	}
+	if n, err := strconv.Atoi(fields[5]); err != nil || n < 1 {
+		return &FenError{Fen: fen, Err: ErrFenFullmoveNumber, Detail: fmt.Sprintf("got %q", fields[5])}
+	}
+	return nil
+}
+
+// Wraps a sentinel error with details about where the placement is invalid
+type placementError struct {
+	err    error
+	detail string
+}
+
+func (e *placementError) Error() string { return e.err.Error() }
+func (e *placementError) Unwrap() error { return e.err }
+
+func detailOf(err error) string {
+	var placementErr *placementError
+	if errors.As(err, &placementErr) {
+		return placementErr.detail
+	}
+	return ""
+}
+
+func validatePiecePlacement(placement string) ([BoardSize * BoardSize]Piece, error) {
+	board := [BoardSize * BoardSize]Piece{}
+
+	ranks := strings.Split(placement, "/")
+	if len(ranks) != BoardSize {
+		return board, &placementError{ErrFenRankCount, fmt.Sprintf("got %d ranks", len(ranks))}
+	}
+
+	for i, rankString := range ranks {
+		rank := BoardSize - 1 - i
+		file := 0
+		previousWasDigit := false
+		for _, char := range rankString {
+			if char >= '1' && char <= '8' {
+				if previousWasDigit {
+					return board, &placementError{ErrFenRankLength, fmt.Sprintf("consecutive digits in rank %d", rank+1)}
+				}
+				previousWasDigit = true
+				file += int(char - '0')
+				if file > BoardSize {
+					return board, &placementError{ErrFenRankLength, fmt.Sprintf("rank %d has more than %d squares", rank+1, BoardSize)}
+				}
+				continue
+			}
+			previousWasDigit = false
+			if !strings.ContainsRune("pnbrqkPNBRQK", char) {
+				return board, &placementError{ErrFenPiece, fmt.Sprintf("%q in rank %d", char, rank+1)}
+			}
+			if file >= BoardSize {
+				return board, &placementError{ErrFenRankLength, fmt.Sprintf("rank %d has more than %d squares", rank+1, BoardSize)}
+			}
+			board[rank*BoardSize+file] = createPiece(char)
+			file++
+		}
+		if file != BoardSize {
+			return board, &placementError{ErrFenRankLength, fmt.Sprintf("rank %d has %d squares", rank+1, file)}
+		}
+	}
+
+	return board, nil
+}
+
+// Each color must have the given number of kings. Pawns cannot stand on the
+// rank they promote on, nor on the rank behind them unless firstRankPawns
+func validateKingsAndPawns(board *[BoardSize * BoardSize]Piece, whiteKings, blackKings int, firstRankPawns bool) error {
+	kings := map[int]int{White: 0, Black: 0}
+	for square, piece := range board {
+		rank := square / BoardSize
+		switch {
+		case piece.pieceType() == King:
+			kings[piece.color()]++
+		case piece.pieceType() != Pawn:
+		case piece.color() == White && (rank == BoardSize-1 || rank == 0 && !firstRankPawns),
+			piece.color() == Black && (rank == 0 || rank == BoardSize-1 && !firstRankPawns):
+			return &placementError{ErrFenPawnOnBackRank, fmt.Sprintf("pawn on rank %d", rank+1)}
+		}
+	}
+
+	if kings[White] != whiteKings || kings[Black] != blackKings {
+		return &placementError{ErrFenKingCount, fmt.Sprintf("%d white and %d black kings", kings[White], kings[Black])}
+	}
+	return nil
+}
+
+// Castling rights may be written as in standard FEN, X-FEN or Shredder-FEN,
+// with the rights of white first and the kingside before the queenside
+func validateCastlingRights(castlingRights string, board [BoardSize * BoardSize]Piece) error {
+	if castlingRights == "-" {
+		return nil
+	}
+	if castlingRights == "" {
+		return ErrFenCastlingRights
+	}
+
+	// Rights are numbered from black queenside up to white kingside, so in
+	// the right order they only go down
+	previous := 4
+	for _, symbol := range castlingRights {
+		right, _, _, err := castlingRook(&board, symbol)
+		if err != nil {
+			return err
+		}
+		if right >= previous {
+			return ErrFenCastlingRights
+		}
+		previous = right
+	}
+	return nil
+}
+
+func validateEnPassantSquare(enPassantSquare string, whiteToMove bool, board [BoardSize * BoardSize]Piece) error {
+	if enPassantSquare == "-" {
+		return nil
+	}
+	if !isSquareNotation(enPassantSquare) {
+		return ErrFenEnPassant
+	}
+
+	square := util.FromChessNotation(enPassantSquare)
+	rank := square / BoardSize
+
+	// The pawn that moved two squares belongs to the side not to move
+	pawnSquare, startSquare := square-BoardSize, square+BoardSize
+	pawn := Piece{Pawn | Black}
+	if !whiteToMove {
+		pawnSquare, startSquare = square+BoardSize, square-BoardSize
+		pawn = Piece{Pawn | White}
+	}
+	if whiteToMove && rank != 5 || !whiteToMove && rank != 2 {
+		return fmt.Errorf("%w: wrong rank for the side to move", ErrFenEnPassant)
+	}
+	if board[pawnSquare] != pawn {
+		return errFenEnPassantNoPawn
+	}
+	if board[square].pieceType() != None || board[startSquare].pieceType() != None {
+		return errFenEnPassantBlocked
+	}
+	return nil
+}
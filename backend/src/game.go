@@ -1,14 +1,36 @@
 package game
 
-const startFen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+const StartingFen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
 
 func NewGame() *Game {
-	return NewGameFromFen(startFen)
+	return NewGameFromFen(StartingFen)
 }
 
+// NewGameFromFen loads a FEN without validating it, see ParseFen for
+// untrusted input
 func NewGameFromFen(fen string) *Game {
-	precomputedMoveData()
-	g := &Game{}
-	g.loadPositionFromFen(fen)
-	return g
+	return NewVariantGameFromFen(Standard, fen)
+}
+
+// InitialFen returns the position the game was started from
+func (g *Game) InitialFen() string {
+	return g.initialFen
+}
+
+// Moves returns the moves played since the start position
+func (g *Game) Moves() []Move {
+	moves := make([]Move, len(g.moveHistory))
+	copy(moves, g.moveHistory)
+	return moves
+}
+
+// Clone returns a copy of the game that can be played on independently,
+// including the history used for repetitions and undoing moves
+func (g *Game) Clone() *Game {
+	clone := *g
+	clone.gameStateHistory = append([]uint32{}, g.gameStateHistory...)
+	clone.moveHistory = append([]Move{}, g.moveHistory...)
+	clone.hashHistory = append([]uint64{}, g.hashHistory...)
+	clone.explosions = append([]explosion{}, g.explosions...)
+	return &clone
 }
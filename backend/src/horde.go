@@ -0,0 +1,31 @@
+package game
+
+// Horde pits the black army against 36 white pawns without a king. White
+// wins by checkmate, black by capturing every white piece. Pawns on the first
+// rank may move two squares
+var Horde Variant = horde{}
+
+type horde struct{ standardRules }
+
+func (horde) Name() string { return "Horde" }
+
+func (horde) StartFen() string {
+	return "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1"
+}
+
+func (horde) outcome(g *Game) Status {
+	if g.bitboards[colorIndex(g.ColorToMove)] == 0 {
+		return NoPiecesLeft
+	}
+	return Ongoing
+}
+
+// The side with the king can win by capturing the horde, the horde only by
+// checkmate
+func (horde) canWin(g *Game, color bool) bool {
+	return g.bitboards[King|colorIndex(!color)] == 0 || g.hasMatingMaterial(color)
+}
+
+func (horde) validatePieces(board *[BoardSize * BoardSize]Piece) error {
+	return validateKingsAndPawns(board, 0, 1, true)
+}
@@ -0,0 +1,24 @@
+package game
+
+// KingOfTheHill is won by checkmate or by bringing the king to one of the
+// four centre squares
+var KingOfTheHill Variant = kingOfTheHill{}
+
+type kingOfTheHill struct{ standardRules }
+
+// d4, e4, d5 and e5
+const hill uint64 = 1<<27 | 1<<28 | 1<<35 | 1<<36
+
+func (kingOfTheHill) Name() string { return "King of the Hill" }
+
+func (kingOfTheHill) outcome(g *Game) Status {
+	if g.bitboards[King|colorIndex(!g.ColorToMove)]&hill != 0 {
+		return KingOnHill
+	}
+	return Ongoing
+}
+
+// Even a lone king can walk to the centre
+func (kingOfTheHill) canWin(g *Game, color bool) bool {
+	return true
+}
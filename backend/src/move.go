@@ -3,29 +3,38 @@ package game
 import "fmt"
 
 func (g *Game) Move(move Move) error {
-	if g.Board[move.StartSquare].pieceType() == None {
+	if status := g.Status(); status.IsOver() {
+		return fmt.Errorf("game is over: %s", status)
+	}
+	if !isOnBoard(move.StartSquare) || !isOnBoard(move.TargetSquare) {
+		return fmt.Errorf("no square %d or %d on the board", move.StartSquare, move.TargetSquare)
+	}
+	if isDropFlag(move.Flag) {
+		// A drop has no start square and is told apart by its piece
+		move.StartSquare = move.TargetSquare
+	} else if g.Board[move.StartSquare].pieceType() == None {
 		return fmt.Errorf("no piece at %d", move.StartSquare)
 	}
 
-	moves := g.GenerateLegalMoves()
-	validMove := false
-	for _, m := range moves {
-		if m.StartSquare == move.StartSquare && m.TargetSquare == move.TargetSquare {
-			validMove = true
-			if move.Flag == 0 {
-				move.Flag = m.Flag
-			}
-			break
-		}
-	}
-	if !validMove {
+	move, ok := findLegalMove(g.GenerateLegalMoves(), move)
+	if !ok {
 		return fmt.Errorf("no move from %d to %d", move.StartSquare, move.TargetSquare)
 	}
 
+	g.endedBy = Ongoing
 	g.MakeMove(move)
 	return nil
 }
 
+// UndoMove unmakes the last move played
+func (g *Game) UndoMove() error {
+	if len(g.moveHistory) == 0 {
+		return fmt.Errorf("no move to undo")
+	}
+	g.UnmakeMove(g.moveHistory[len(g.moveHistory)-1])
+	return nil
+}
+
 func (g *Game) MakeMove(move Move) {
 	g.makeMoveBitboard(move)
 	var currentGameState uint32 = 0
@@ -41,7 +50,14 @@ func (g *Game) MakeMove(move Move) {
 	}
 
 	capturedPiece := g.Board[moveTo]
+	if move.Flag == Castling {
+		// A Chess960 castling move targets the king's own rook
+		capturedPiece = Piece{None}
+	}
 	movePiece := g.Board[moveFrom]
+	if isDropFlag(move.Flag) {
+		movePiece = Piece{dropPieceType(move.Flag) | colorToMove}
+	}
 	originalPieceType := movePiece.pieceType()
 	movePieceType := movePiece.pieceType()
 
@@ -100,36 +116,18 @@ func (g *Game) MakeMove(move Move) {
 		// }
 		// fmt.Printf("Pawns Bitboard after:\n%s", bitboardString(g.pawnsBitBoard))
 	} else if move.Flag == Castling {
-		kingside := false
-		if g.ColorToMove {
-			kingside = moveTo == 6 // g1
-		} else {
-			kingside = moveTo == 62 // g8
-		}
-		castlingRookFromIndex := 0
-		castlingRookToIndex := 0
-		if kingside {
-			castlingRookFromIndex = moveTo + 1
-			castlingRookToIndex = moveTo - 1
-		} else {
-			castlingRookFromIndex = moveTo - 2
-			castlingRookToIndex = moveTo + 1
-		}
-		g.Board[castlingRookFromIndex] = Piece{None}
-		g.Board[castlingRookToIndex] = Piece{Rook | colorToMove}
-		// g.rooksBitBoard &= ^(1 << castlingRookFromIndex)
-		// g.rooksBitBoard |= 1 << castlingRookToIndex
-		// if g.ColorToMove {
-		// 	g.whitePiecesBitBoard &= ^(1 << castlingRookFromIndex)
-		// 	g.whitePiecesBitBoard |= 1 << castlingRookToIndex
-		// } else {
-		// 	g.blackPiecesBitBoard &= ^(1 << castlingRookFromIndex)
-		// 	g.blackPiecesBitBoard |= 1 << castlingRookToIndex
-		// }
+		// The king and rook may start on each other's target squares
+		kingTo, rookFrom, rookTo := g.castlingSquares(move, g.ColorToMove)
+		g.Board[moveFrom] = Piece{None}
+		g.Board[rookFrom] = Piece{None}
+		g.Board[kingTo] = movePiece
+		g.Board[rookTo] = Piece{Rook | colorToMove}
 	}
 
-	g.Board[moveTo] = movePiece
-	g.Board[moveFrom] = Piece{None}
+	if move.Flag != Castling {
+		g.Board[moveFrom] = Piece{None}
+		g.Board[moveTo] = movePiece
+	}
 
 	// switch movePieceType {
 	// case King:
@@ -166,26 +164,31 @@ func (g *Game) MakeMove(move Move) {
 	if move.Flag == PawnTwoForward {
 		enPassantFile := uint32(moveFrom) % 8
 		currentGameState |= (enPassantFile + 1) << 4
+		if g.canCaptureEnPassant(int(enPassantFile), !g.ColorToMove) {
+			currentGameState |= enPassantCapturable
+		}
 	}
 
-	// If a piece moves to/from rook square, remove castling rights for that side
+	// If a piece moves to/from rook square, remove castling rights for that side.
+	// A rook taking the other rook along the file touches two of the squares
 	if originalCastleRights != 0 {
-		if moveTo == 7 || moveFrom == 7 { // h1
-			newCastleState &= whiteCastleKingsideMask
-		} else if moveTo == 0 || moveFrom == 0 { // a1
-			newCastleState &= whiteCastleQueensideMask
-		} else if moveTo == 63 || moveFrom == 63 { // h8
-			newCastleState &= blackCastleKingsideMask
-		} else if moveTo == 56 || moveFrom == 56 { // a8
-			newCastleState &= blackCastleQueensideMask
+		for right, rookSquare := range g.castlingRooks {
+			if moveTo == rookSquare || moveFrom == rookSquare {
+				newCastleState &^= 1 << right
+			}
 		}
 	}
 
 	currentGameState |= newCastleState
 	currentGameState |= g.fiftyMoveCounter << 14
+	if g.variant != nil {
+		currentGameState = g.variant.makeMove(g, move, currentGameState)
+	}
+	g.hash ^= gameStateKey(g.currentGameState) ^ gameStateKey(currentGameState) ^ zobristSideToMoveKey
 	g.currentGameState = currentGameState
 
 	g.gameStateHistory = append(g.gameStateHistory, currentGameState)
+	g.moveHistory = append(g.moveHistory, move)
 
 	if !g.ColorToMove {
 		g.plyCount++
@@ -197,9 +200,18 @@ func (g *Game) MakeMove(move Move) {
 	if originalPieceType == Pawn || capturedPiece.Type != None {
 		g.fiftyMoveCounter = 0
 	}
+
+	g.hashHistory = append(g.hashHistory, g.hash)
+
+	if DebugHashCheck {
+		g.mustVerifyHash(move)
+	}
 }
 
 func (g *Game) UnmakeMove(move Move) {
+	if g.variant != nil {
+		g.variant.unmakeMove(g, move)
+	}
 	g.unmakeMoveBitboard(move)
 	g.ColorToMove = !g.ColorToMove // color is the color that made the move
 
@@ -241,8 +253,18 @@ func (g *Game) UnmakeMove(move Move) {
 	// 	g.Board[movedTo] = capturedPiece
 	// }
 
-	g.Board[movedFrom] = Piece{movedPieceType | colorToMove}
-	g.Board[movedTo] = capturedPiece
+	if move.Flag == Castling {
+		kingTo, rookFrom, rookTo := g.castlingSquares(move, g.ColorToMove)
+		g.Board[kingTo] = Piece{None}
+		g.Board[rookTo] = Piece{None}
+		g.Board[movedFrom] = Piece{King | colorToMove}
+		g.Board[rookFrom] = Piece{Rook | colorToMove}
+	} else if isDropFlag(move.Flag) {
+		g.Board[movedTo] = Piece{None}
+	} else {
+		g.Board[movedFrom] = Piece{movedPieceType | colorToMove}
+		g.Board[movedTo] = capturedPiece
+	}
 
 	if move.Flag == EnPassantCapture {
 		epPawnSquare := 0
@@ -269,33 +291,6 @@ func (g *Game) UnmakeMove(move Move) {
 		// 	g.blackPiecesBitBoard &= ^(1 << movedTo)
 		// }
 		// fmt.Printf("Pawns Bitboard after:\n%s", bitboardString(g.pawnsBitBoard))
-	} else if move.Flag == Castling {
-		kingside := false
-		if g.ColorToMove {
-			kingside = movedTo == 6 // g1
-		} else {
-			kingside = movedTo == 62 //
-		}
-		castlingRookFromIndex := 0
-		castlingRookToIndex := 0
-		if kingside {
-			castlingRookFromIndex = movedTo + 1
-			castlingRookToIndex = movedTo - 1
-		} else {
-			castlingRookFromIndex = movedTo - 2
-			castlingRookToIndex = movedTo + 1
-		}
-		g.Board[castlingRookToIndex] = Piece{None}
-		g.Board[castlingRookFromIndex] = Piece{Rook | colorToMove}
-		// g.rooksBitBoard |= 1 << castlingRookFromIndex
-		// g.rooksBitBoard &= ^(1 << castlingRookToIndex)
-		// if g.ColorToMove {
-		// 	g.whitePiecesBitBoard |= 1 << castlingRookFromIndex
-		// 	g.whitePiecesBitBoard &= ^(1 << castlingRookToIndex)
-		// } else {
-		// 	g.blackPiecesBitBoard |= 1 << castlingRookFromIndex
-		// 	g.blackPiecesBitBoard &= ^(1 << castlingRookToIndex)
-		// }
 	}
 
 	// switch movedPieceType {
@@ -351,16 +346,24 @@ func (g *Game) UnmakeMove(move Move) {
 	// 	}
 	// }
 
+	unmadeGameState := g.gameStateHistory[len(g.gameStateHistory)-1]
 	g.gameStateHistory = g.gameStateHistory[:len(g.gameStateHistory)-1]
+	g.hashHistory = g.hashHistory[:len(g.hashHistory)-1]
+	g.moveHistory = g.moveHistory[:len(g.moveHistory)-1]
 	currentGameState := g.gameStateHistory[len(g.gameStateHistory)-1]
 
-	g.fiftyMoveCounter = (currentGameState >> 14) & 0b111111
+	g.fiftyMoveCounter = (unmadeGameState >> 14) & 0b11111111
 
 	if !g.ColorToMove {
 		g.plyCount--
 	}
 
+	g.hash ^= gameStateKey(g.currentGameState) ^ gameStateKey(currentGameState) ^ zobristSideToMoveKey
 	g.currentGameState = currentGameState
+
+	if DebugHashCheck {
+		g.mustVerifyHash(move)
+	}
 }
 
 func (g *Game) makeMoveBitboard(move Move) {
@@ -378,32 +381,21 @@ func (g *Game) makeMoveBitboard(move Move) {
 		} else {
 			epPawnSquare = moveTo + 8
 		}
-		g.bitboards[pieceToMove] ^= 1<<moveFrom | 1<<moveTo
-		g.bitboards[pieceToCapture] ^= 1 << epPawnSquare
+		g.togglePiece(pieceToMove, moveFrom)
+		g.togglePiece(pieceToMove, moveTo)
+		g.togglePiece(g.Board[epPawnSquare].Type, epPawnSquare)
 	case Castling:
-		kingside := false
-		if g.ColorToMove {
-			kingside = moveTo == 6 // g1
-		} else {
-			kingside = moveTo == 62 // g8
-		}
-		rookMoveFrom := 0
-		rookMoveTo := 0
-		if kingside {
-			rookMoveFrom = moveTo + 1
-			rookMoveTo = moveTo - 1
-		} else {
-			rookMoveFrom = moveTo - 2
-			rookMoveTo = moveTo + 1
-		}
-		rook := None
+		kingTo, rookFrom, rookTo := g.castlingSquares(move, g.ColorToMove)
+		rook := Rook
 		if g.ColorToMove {
 			rook |= White
 		} else {
 			rook |= Black
 		}
-		g.bitboards[pieceToMove] ^= 1<<moveFrom | 1<<moveTo
-		g.bitboards[rook] ^= 1<<rookMoveFrom | 1<<rookMoveTo
+		g.togglePiece(pieceToMove, moveFrom)
+		g.togglePiece(pieceToMove, kingTo)
+		g.togglePiece(rook, rookFrom)
+		g.togglePiece(rook, rookTo)
 	case PromoteToQueen, PromoteToKnight, PromoteToRook, PromoteToBishop:
 		promoteType := 0
 		switch move.Flag {
@@ -421,18 +413,20 @@ func (g *Game) makeMoveBitboard(move Move) {
 		} else {
 			promoteType |= Black
 		}
-		g.bitboards[pieceToMove] ^= 1 << moveFrom
-		g.bitboards[promoteType] ^= 1 << moveTo
+		g.togglePiece(pieceToMove, moveFrom)
+		g.togglePiece(promoteType, moveTo)
 		if pieceToCapture != None {
-			g.bitboards[pieceToCapture] ^= 1 << moveTo
+			g.togglePiece(pieceToCapture, moveTo)
 		}
-	case NoFlag:
-		g.bitboards[pieceToMove] ^= 1<<move.StartSquare | 1<<move.TargetSquare
+	case NoFlag, PawnTwoForward:
+		g.togglePiece(pieceToMove, moveFrom)
+		g.togglePiece(pieceToMove, moveTo)
 		if pieceToCapture != None {
-			g.bitboards[pieceToCapture] ^= 1 << move.TargetSquare
+			g.togglePiece(pieceToCapture, moveTo)
 		}
+	case DropPawn, DropKnight, DropBishop, DropRook, DropQueen:
+		g.togglePiece(dropPieceType(move.Flag)|colorIndex(g.ColorToMove), moveTo)
 	}
-
 }
 
 func (g *Game) unmakeMoveBitboard(move Move) {
@@ -440,7 +434,7 @@ func (g *Game) unmakeMoveBitboard(move Move) {
 	movedTo := move.TargetSquare
 
 	pieceMoved := g.Board[move.TargetSquare].Type
-	pieceCaptured := (g.currentGameState >> 8) & 0b111111
+	pieceCaptured := int(g.currentGameState>>8) & 0b111111
 
 	switch move.Flag {
 	case EnPassantCapture:
@@ -450,61 +444,48 @@ func (g *Game) unmakeMoveBitboard(move Move) {
 		} else {
 			epPawnSquare = movedTo + 8
 		}
-		g.bitboards[pieceMoved] ^= 1<<movedTo | 1<<movedFrom
-		g.bitboards[pieceCaptured] ^= 1 << epPawnSquare
+		g.togglePiece(pieceMoved, movedTo)
+		g.togglePiece(pieceMoved, movedFrom)
+		g.togglePiece(pieceCaptured, epPawnSquare)
 	case Castling:
-		kingside := false
-		if !g.ColorToMove {
-			kingside = movedTo == 6 // g1
-		} else {
-			kingside = movedTo == 62 // g8
-		}
-		rookMovedTo := 0
-		rookMovedFrom := 0
-		if kingside {
-			rookMovedTo = movedTo - 1
-			rookMovedFrom = movedTo + 1
-		} else {
-			rookMovedTo = movedTo + 1
-			rookMovedFrom = movedTo - 2
-		}
-		rook := Rook
+		kingTo, rookFrom, rookTo := g.castlingSquares(move, !g.ColorToMove)
+		king, rook := King|Black, Rook|Black
 		if !g.ColorToMove {
-			rook |= White
-		} else {
-			rook |= Black
+			king, rook = King|White, Rook|White
 		}
-		g.bitboards[pieceMoved] ^= 1<<movedTo | 1<<movedFrom
-		g.bitboards[rook] ^= 1<<rookMovedTo | 1<<rookMovedFrom
+		g.togglePiece(king, kingTo)
+		g.togglePiece(king, movedFrom)
+		g.togglePiece(rook, rookTo)
+		g.togglePiece(rook, rookFrom)
 	case PromoteToQueen, PromoteToKnight, PromoteToRook, PromoteToBishop:
-		promoteType := 0
-		switch move.Flag {
-		case PromoteToQueen:
-			promoteType = Queen
-		case PromoteToKnight:
-			promoteType = Knight
-		case PromoteToRook:
-			promoteType = Rook
-		case PromoteToBishop:
-			promoteType = Bishop
-		}
 		pawn := Pawn
-		if g.ColorToMove {
-			promoteType |= White
+		if !g.ColorToMove {
 			pawn |= White
 		} else {
-			promoteType |= Black
 			pawn |= Black
 		}
-		g.bitboards[pawn] ^= 1 << movedFrom
-		g.bitboards[promoteType] ^= 1 << movedTo
+		g.togglePiece(pawn, movedFrom)
+		g.togglePiece(pieceMoved, movedTo)
 		if pieceCaptured != None {
-			g.bitboards[pieceCaptured] ^= 1 << movedTo
+			g.togglePiece(pieceCaptured, movedTo)
 		}
-	case NoFlag:
-		g.bitboards[pieceMoved] ^= 1<<move.TargetSquare | 1<<move.StartSquare
+	case NoFlag, PawnTwoForward:
+		g.togglePiece(pieceMoved, movedTo)
+		g.togglePiece(pieceMoved, movedFrom)
 		if pieceCaptured != None {
-			g.bitboards[pieceCaptured] ^= 1 << move.TargetSquare
+			g.togglePiece(pieceCaptured, movedTo)
 		}
+	case DropPawn, DropKnight, DropBishop, DropRook, DropQueen:
+		g.togglePiece(pieceMoved, movedTo)
 	}
 }
+
+func (g *Game) togglePiece(piece int, square int) {
+	g.bitboards[piece] ^= 1 << square
+	g.bitboards[piece&(White|Black)] ^= 1 << square
+	g.hash ^= zobristPieceKeys[piece][square]
+}
+
+func isOnBoard(square int) bool {
+	return square >= 0 && square < BoardSize*BoardSize
+}
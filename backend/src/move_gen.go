@@ -1,8 +1,8 @@
 package game
 
 import (
-	"fmt"
-	"web-chess/backend/util"
+	"math/bits"
+	"sync"
 )
 
 var DirectionOffsets = [BoardSize]int{8, -8, 1, -1, 7, -7, 9, -9}
@@ -10,6 +10,9 @@ var KnightOffsets = [8]int{15, 17, 10, 6, -15, -17, -10, -6}
 
 var NumSquaresToEdge [BoardSize * BoardSize][8]int
 
+// Games are created concurrently by the server, the tables are only filled once
+var precomputeOnce sync.Once
+
 // var opponentAttackMap uint64
 // var opponentAttackMapSliding uint64
 
@@ -34,6 +37,7 @@ func precomputedMoveData() {
 			}
 		}
 	}
+	precomputedAttackTables()
 }
 
 func (g *Game) LegalMovesAtIndex(index int) []Move {
@@ -41,327 +45,327 @@ func (g *Game) LegalMovesAtIndex(index int) []Move {
 
 	filteredMoves := []Move{}
 	for _, move := range moves {
-		if move.StartSquare == index {
+		// Drops have no start square and are not moves of the piece there
+		if move.StartSquare == index && !isDropFlag(move.Flag) {
 			filteredMoves = append(filteredMoves, move)
 		}
 	}
 	return filteredMoves
 }
 
-func (g *Game) GenerateLegalMoves() []Move {
-	moves := g.GeneratePseudoLegalMoves()
+// Which legal moves to generate. Captures and quiet moves together make up
+// all moves
+type generationMode int
+
+const (
+	generateAll generationMode = iota
+	// Moves that take a piece, including en passant and promotions with capture
+	generateCaptures
+	// Moves that do not take a piece, including castling and promotions
+	// without capture
+	generateQuiets
+)
 
-	filteredMoves := []Move{}
-	for _, move := range moves {
-		if g.isMoveLegal(move) {
-			filteredMoves = append(filteredMoves, move)
-		}
-	}
+// GenerateLegalMoves generates only moves that do not leave the king in
+// check, using the checkers, pins and opponent attacks of the position
+func (g *Game) GenerateLegalMoves() []Move {
+	var list MoveList
+	g.GenerateLegalMovesInto(&list)
+	return append([]Move{}, list.Moves()...)
+}
 
-	return filteredMoves
+// GenerateLegalMovesInto replaces the contents of the list with the legal
+// moves, without allocating
+func (g *Game) GenerateLegalMovesInto(list *MoveList) {
+	g.generateLegalMoves(list, generateAll)
 }
 
-func (g *Game) isMoveLegal(move Move) bool {
-	color := g.ColorToMove
+// GenerateCaptures generates the legal moves that take a piece
+func (g *Game) GenerateCaptures() []Move {
+	var list MoveList
+	g.GenerateCapturesInto(&list)
+	return append([]Move{}, list.Moves()...)
+}
 
-	g.MakeMove(move)
+func (g *Game) GenerateCapturesInto(list *MoveList) {
+	g.generateLegalMoves(list, generateCaptures)
+}
 
-	isKingInCheck := g.isKingInCheck(color)
+// GenerateQuiets generates the legal moves that do not take a piece
+func (g *Game) GenerateQuiets() []Move {
+	var list MoveList
+	g.GenerateQuietsInto(&list)
+	return append([]Move{}, list.Moves()...)
+}
 
-	g.UnmakeMove(move)
+func (g *Game) GenerateQuietsInto(list *MoveList) {
+	g.generateLegalMoves(list, generateQuiets)
+}
 
-	return !isKingInCheck
+func (g *Game) generateLegalMoves(list *MoveList, mode generationMode) {
+	list.Clear()
+	if g.variant != nil {
+		if g.variant.outcome(g) != Ongoing || g.variant.generateMoves(g, list, mode) {
+			return
+		}
+	}
+	info, ok := g.newCheckInfo(g.ColorToMove)
+	if !ok {
+		// Without a king every move is legal
+		g.generateMovesForColor(list, g.ColorToMove, mode, nil)
+		return
+	}
+	g.generateMovesForColor(list, g.ColorToMove, mode, &info)
 }
 
 func (g *Game) isKingInCheck(color bool) bool {
 	kingPosition := g.findKing(color)
+	if kingPosition >= 0 && g.variant != nil && g.variant.safeSquares(g, color)&(1<<kingPosition) != 0 {
+		return false
+	}
 	return g.isSquareAttacked(kingPosition, color)
 }
 
 func (g *Game) findKing(color bool) int {
-	for i, piece := range g.Board {
-		if piece.pieceType() != King {
-			continue
-		}
-		pieceColor := piece.color()
-		if pieceColor == White && color || pieceColor == Black && !color {
-			return i
-		}
+	kings := g.bitboards[King|colorIndex(color)]
+	if kings == 0 {
+		return -1 // Should never happen
 	}
-	return -1 // Should never happen
+	return bits.TrailingZeros64(kings)
 }
 
 func (g *Game) isSquareAttacked(square int, color bool) bool {
-	opponentColor := !color
-
-	for _, move := range g.generateMovesForColor(opponentColor, true) {
-		if move.TargetSquare == square {
-			return true
-		}
+	if square < 0 {
+		return false
 	}
-	return false
+	return g.attackersTo(square, color, g.bitboards[White]|g.bitboards[Black]) != 0
+}
+
+// Returns the pieces of the opponent of color that attack the square, with
+// sliders blocked by the given occupancy. Each piece type is looked up from
+// the square outwards, since a piece attacks the square exactly when the same
+// piece on the square would attack it
+func (g *Game) attackersTo(square int, color bool, occupancy uint64) uint64 {
+	us, them := colorIndex(color), colorIndex(!color)
+	queens := g.bitboards[Queen|them]
+
+	return pawnAttacks[us][square]&g.bitboards[Pawn|them] |
+		knightAttacks[square]&g.bitboards[Knight|them] |
+		kingAttacks[square]&g.bitboards[King|them] |
+		bishopAttacks(square, occupancy)&(g.bitboards[Bishop|them]|queens) |
+		rookAttacks(square, occupancy)&(g.bitboards[Rook|them]|queens)
 }
 
 func (g *Game) GeneratePseudoLegalMoves() []Move {
-	return g.generateMovesForColor(g.ColorToMove, false)
+	var list MoveList
+	g.GeneratePseudoLegalMovesInto(&list)
+	return append([]Move{}, list.Moves()...)
 }
 
-func (g *Game) generateMovesForColor(color bool, inSearch bool) []Move {
-	moves := []Move{}
+func (g *Game) GeneratePseudoLegalMovesInto(list *MoveList) {
+	list.Clear()
+	g.generateMovesForColor(list, g.ColorToMove, generateAll, nil)
+}
 
-	for startSquare := 0; startSquare < BoardSize*BoardSize; startSquare++ {
-		piece := g.Board[startSquare]
-		if piece.pieceType() == None {
-			continue
-		}
-		if piece.color() == White && !color || piece.color() == Black && color {
-			continue
-		}
+// Adds the moves of color to the list. With check info only legal moves are
+// generated, without it the moves may leave the king in check
+func (g *Game) generateMovesForColor(list *MoveList, color bool, mode generationMode, info *checkInfo) {
+	us, them := colorIndex(color), colorIndex(!color)
+	occupancy := g.bitboards[White] | g.bitboards[Black]
+
+	// Squares pieces other than pawns may move to in this mode
+	targets := ^g.bitboards[us]
+	switch mode {
+	case generateCaptures:
+		targets = g.bitboards[them]
+	case generateQuiets:
+		targets = ^occupancy
+	}
 
-		switch piece.pieceType() {
-		case King:
-			moves = append(moves, g.generateKingMoves(startSquare, inSearch)...)
-		case Pawn:
-			moves = append(moves, g.generatePawnMoves(startSquare, inSearch)...)
-		case Knight:
-			moves = append(moves, g.generateKnightMoves(startSquare)...)
-		case Bishop, Rook, Queen:
-			moves = append(moves, g.generateSlidingMoves(startSquare)...)
+	for kings := g.bitboards[King|us]; kings != 0; {
+		startSquare := popLSB(&kings)
+		list.addTargets(startSquare, kingAttacks[startSquare]&targets&^info.attackedSquares())
+		if !info.inCheck() && mode != generateCaptures {
+			g.generateCastlingMoves(list, startSquare)
 		}
 	}
-	return moves
-}
-
-func (g *Game) generateSlidingMoves(startSquare int) []Move {
-	piece := g.Board[startSquare]
-
-	startDirIndex := 0
-	if piece.pieceType() == Bishop {
-		startDirIndex = 4
+	if info.inDoubleCheck() {
+		// Only the king can get out of a double check
+		return
 	}
-	endDirIndex := 8
-	if piece.pieceType() == Rook {
-		endDirIndex = 4
+	// Only Crazyhouse has pockets to drop from
+	if g.variant == Crazyhouse && mode != generateCaptures {
+		g.generateDrops(list, us, info)
 	}
 
-	moves := []Move{}
-	for directionIndex := startDirIndex; directionIndex < endDirIndex; directionIndex++ {
-		for numSquares := 0; numSquares < NumSquaresToEdge[startSquare][directionIndex]; numSquares++ {
-			targetSquare := startSquare + DirectionOffsets[directionIndex]*(numSquares+1)
-
-			pieceOnTargetSquare := g.Board[targetSquare]
-
-			if pieceOnTargetSquare.color() == piece.color() {
-				break
-			}
-
-			moves = append(moves, Move{startSquare, targetSquare, NoFlag})
-
-			if pieceOnTargetSquare.color() != None {
-				break
-			}
-		}
+	for pawns := g.bitboards[Pawn|us]; pawns != 0; {
+		g.generatePawnMoves(list, popLSB(&pawns), mode, info)
+	}
+	for knights := g.bitboards[Knight|us]; knights != 0; {
+		startSquare := popLSB(&knights)
+		list.addTargets(startSquare, knightAttacks[startSquare]&targets&info.targets(startSquare))
+	}
+	for bishops := g.bitboards[Bishop|us]; bishops != 0; {
+		startSquare := popLSB(&bishops)
+		list.addTargets(startSquare, bishopAttacks(startSquare, occupancy)&targets&info.targets(startSquare))
+	}
+	for rooks := g.bitboards[Rook|us]; rooks != 0; {
+		startSquare := popLSB(&rooks)
+		list.addTargets(startSquare, rookAttacks(startSquare, occupancy)&targets&info.targets(startSquare))
+	}
+	for queens := g.bitboards[Queen|us]; queens != 0; {
+		startSquare := popLSB(&queens)
+		list.addTargets(startSquare, queenAttacks(startSquare, occupancy)&targets&info.targets(startSquare))
 	}
-	return moves
 }
 
-func (g *Game) generateKnightMoves(startSquare int) []Move {
-	piece := g.Board[startSquare]
+func colorIndex(color bool) int {
+	if color {
+		return White
+	}
+	return Black
+}
 
-	moves := []Move{}
-	rank := startSquare / BoardSize
-	file := startSquare % BoardSize
-	for _, offset := range KnightOffsets {
-		targetSquare := startSquare + offset
+// Generates the castling moves of the side to move, which is not in check.
+// The king and rook may start anywhere on the back rank, as in Chess960, and
+// always end on the g and f files or the c and d files
+func (g *Game) generateCastlingMoves(list *MoveList, kingSquare int) {
+	castlingRights := g.currentGameState & 0b1111
+	if castlingRights == 0 {
+		return
+	}
 
-		if targetSquare < 0 || targetSquare >= BoardSize*BoardSize {
+	for _, kingside := range [...]bool{true, false} {
+		right := castlingRight(g.ColorToMove, kingside)
+		if castlingRights>>right&1 == 0 {
 			continue
 		}
+		rookSquare := g.castlingRooks[right]
+		kingTo, rookTo := castlingTargets(g.ColorToMove, kingside)
 
-		targetRank := targetSquare / BoardSize
-		targetFile := targetSquare % BoardSize
-
-		if util.Abs(rank-targetRank) > 2 || util.Abs(file-targetFile) > 2 {
+		// Only the castling king and rook may stand on the squares either of
+		// them passes or ends on
+		occupancy := (g.bitboards[White] | g.bitboards[Black]) &^ (1<<kingSquare | 1<<rookSquare)
+		if occupancy&(rankSpan(kingSquare, kingTo)|rankSpan(rookSquare, rookTo)) != 0 {
 			continue
 		}
-
-		pieceOnTargetSquare := g.Board[targetSquare]
-
-		if pieceOnTargetSquare.color() == piece.color() {
-			continue
+		// The king starts and passes its squares with the rook still in place,
+		// but ends on its square with the rook moved, which may have been
+		// shielding it
+		var safe uint64
+		if g.variant != nil {
+			safe = g.variant.safeSquares(g, g.ColorToMove)
 		}
-
-		moves = append(moves, Move{startSquare, targetSquare, NoFlag})
-
-	}
-	return moves
-}
-
-func (g *Game) generateKingMoves(startSquare int, inSearch bool) []Move {
-	piece := g.Board[startSquare]
-
-	moves := []Move{}
-	for i, offset := range DirectionOffsets {
-		if NumSquaresToEdge[startSquare][i] == 0 {
-			continue
+		attacked := false
+		for passed := (rankSpan(kingSquare, kingTo)&^(1<<kingTo) | 1<<kingSquare) &^ safe; passed != 0 && !attacked; {
+			attacked = g.attackersTo(popLSB(&passed), g.ColorToMove, occupancy|1<<rookSquare) != 0
 		}
-
-		targetSquare := startSquare + offset
-
-		pieceOnTargetSquare := g.Board[targetSquare]
-
-		if pieceOnTargetSquare.color() == piece.color() {
+		if attacked || safe&(1<<kingTo) == 0 && g.attackersTo(kingTo, g.ColorToMove, occupancy|1<<rookTo) != 0 {
 			continue
 		}
 
-		moves = append(moves, Move{startSquare, targetSquare, NoFlag})
+		targetSquare := kingTo
+		if g.chess960 {
+			targetSquare = rookSquare
+		}
+		list.add(Move{kingSquare, targetSquare, Castling})
 	}
-
-	moves = append(moves, g.generateCastlingMoves(startSquare, inSearch)...)
-
-	return moves
 }
 
-func (g *Game) generateCastlingMoves(startSquare int, inSearch bool) []Move {
-	moves := []Move{}
-
-	if inSearch {
-		return moves
+// Returns the bit of the castling right of the color on one side
+func castlingRight(white, kingside bool) int {
+	right := 0
+	if white {
+		right = 2
 	}
-
-	if g.currentGameState&0b1111 == 0 {
-		return moves
+	if kingside {
+		right++
 	}
+	return right
+}
 
-	castlingRights := g.currentGameState & 0b1111
-	if g.ColorToMove {
-		if ((castlingRights >> 3) & 1) == 1 {
-			bishopMoved := g.Board[util.FromChessNotation("f1")] == Piece{None}
-			knightMoved := g.Board[util.FromChessNotation("g1")] == Piece{None}
-			if bishopMoved && knightMoved {
-				isWhiteKingAttacked := g.isSquareAttacked(4, g.ColorToMove)
-				isWhiteKingsideAttacked := g.isSquareAttacked(5, g.ColorToMove)
-				if !isWhiteKingsideAttacked && !isWhiteKingAttacked {
-					moves = append(moves, Move{startSquare, 6, Castling})
-				}
-			}
-		}
-		if ((castlingRights >> 2) & 1) == 1 {
-			knightMoved := g.Board[util.FromChessNotation("b1")] == Piece{None}
-			bishopMoved := g.Board[util.FromChessNotation("c1")] == Piece{None}
-			queenMoved := g.Board[util.FromChessNotation("d1")] == Piece{None}
-			if knightMoved && bishopMoved && queenMoved {
-				isWhiteKingAttacked := g.isSquareAttacked(4, g.ColorToMove)
-				isWhiteQueensideAttacked := g.isSquareAttacked(3, g.ColorToMove)
-				if !isWhiteQueensideAttacked && !isWhiteKingAttacked {
-					moves = append(moves, Move{startSquare, 2, Castling})
-				}
-			}
-		}
-	} else {
-		if ((castlingRights >> 1) & 1) == 1 {
-			bishopMoved := g.Board[util.FromChessNotation("f8")] == Piece{None}
-			knightMoved := g.Board[util.FromChessNotation("g8")] == Piece{None}
-			if bishopMoved && knightMoved {
-				isBlackKingAttacked := g.isSquareAttacked(60, g.ColorToMove)
-				isBlackKingsideAttacked := g.isSquareAttacked(61, g.ColorToMove)
-				if !isBlackKingsideAttacked && !isBlackKingAttacked {
-					moves = append(moves, Move{startSquare, 62, Castling})
-				}
-			}
-		}
-		if (castlingRights & 1) == 1 {
-			knightMoved := g.Board[util.FromChessNotation("b8")] == Piece{None}
-			bishopMoved := g.Board[util.FromChessNotation("c8")] == Piece{None}
-			queenMoved := g.Board[util.FromChessNotation("d8")] == Piece{None}
-			if knightMoved && bishopMoved && queenMoved {
-				isBlackKingAttacked := g.isSquareAttacked(60, g.ColorToMove)
-				isBlackQueensideAttacked := g.isSquareAttacked(59, g.ColorToMove)
-				if !isBlackQueensideAttacked && !isBlackKingAttacked {
-					moves = append(moves, Move{startSquare, 58, Castling})
-				}
-			}
-		}
+// Returns the squares the king and rook end on when castling to one side
+func castlingTargets(white, kingside bool) (kingTo, rookTo int) {
+	rank := 0
+	if !white {
+		rank = (BoardSize - 1) * BoardSize
 	}
+	if kingside {
+		return rank + 6, rank + 5
+	}
+	return rank + 2, rank + 3
+}
 
-	return moves
+// Returns where the king of a castling move ends and where its rook starts
+// and ends. The target square of the move is the rook in Chess960 and the
+// square the king ends on otherwise, either way it tells the side
+func (g *Game) castlingSquares(move Move, white bool) (kingTo, rookFrom, rookTo int) {
+	kingside := move.TargetSquare > move.StartSquare
+	kingTo, rookTo = castlingTargets(white, kingside)
+	return kingTo, g.castlingRooks[castlingRight(white, kingside)], rookTo
 }
 
-func (g *Game) generatePawnMoves(startSquare int, inSearch bool) []Move {
-	piece := g.Board[startSquare]
+// Returns the squares from a to b on one rank, both included
+func rankSpan(a, b int) uint64 {
+	low, high := min(a, b), max(a, b)
+	return uint64(1)<<(high+1) - uint64(1)<<low
+}
 
-	moves := []Move{}
-	direction := 1
-	if piece.color() == Black {
-		direction = -1
-	}
+func (g *Game) generatePawnMoves(list *MoveList, startSquare int, mode generationMode, info *checkInfo) {
+	color := g.Board[startSquare].color()
+	attacks := pawnAttacks[color][startSquare]
 
-	targetSquare := startSquare + 8*direction
-	if targetSquare < 0 || targetSquare >= BoardSize*BoardSize {
-		fmt.Println("Start square: ", startSquare)
-		fmt.Println(g.CurrentFen())
+	direction, firstRank, doubleMoveRank, promotionRank, enPassantRank := BoardSize, 0, 1, 6, 5
+	opponent := Black
+	if color == Black {
+		direction, firstRank, doubleMoveRank, promotionRank, enPassantRank = -BoardSize, 7, 6, 1, 2
+		opponent = White
 	}
-
-	promotionMoveAllowed := startSquare/BoardSize == 6 && piece.color() == White || startSquare/BoardSize == 1 && piece.color() == Black
-	if g.Board[targetSquare].pieceType() == None {
-		if promotionMoveAllowed {
-			moves = append(moves, Move{startSquare, targetSquare, PromoteToQueen})
-			moves = append(moves, Move{startSquare, targetSquare, PromoteToKnight})
-			moves = append(moves, Move{startSquare, targetSquare, PromoteToRook})
-			moves = append(moves, Move{startSquare, targetSquare, PromoteToBishop})
-		} else {
-			moves = append(moves, Move{startSquare, targetSquare, NoFlag})
-		}
-		doubleMoveAllowed := startSquare/BoardSize == 1 && piece.color() == White || startSquare/BoardSize == 6 && piece.color() == Black
-		if doubleMoveAllowed {
-			targetSquare = startSquare + 16*direction
-			if g.Board[targetSquare].pieceType() == None {
-				moves = append(moves, Move{startSquare, targetSquare, PawnTwoForward})
+	occupancy := g.bitboards[White] | g.bitboards[Black]
+	rank := startSquare / BoardSize
+	targets := info.targets(startSquare)
+
+	targetSquare := startSquare + direction
+	pushAllowed := mode != generateCaptures && targetSquare >= 0 && targetSquare < BoardSize*BoardSize
+	if pushAllowed && occupancy&(1<<targetSquare) == 0 {
+		// When in check the double push can block even if the single push does not
+		if targets&(1<<targetSquare) != 0 {
+			if rank == promotionRank {
+				list.addPromotions(startSquare, targetSquare)
+			} else {
+				list.add(Move{startSquare, targetSquare, NoFlag})
 			}
 		}
-	}
-
-	for _, offset := range []int{7, 9} {
-		targetSquare = startSquare + offset*direction
-		if targetSquare < 0 || targetSquare >= BoardSize*BoardSize {
-			continue
+		doubleTargetSquare := targetSquare + direction
+		if rank == doubleMoveRank && occupancy&(1<<doubleTargetSquare) == 0 && targets&(1<<doubleTargetSquare) != 0 {
+			list.add(Move{startSquare, doubleTargetSquare, PawnTwoForward})
 		}
-
-		startFile := startSquare % BoardSize
-		targetFile := targetSquare % BoardSize
-		if util.Abs(startFile-targetFile) != 1 {
-			continue
+		// Pawns on the first rank, as in Horde, may also move two squares but
+		// cannot be taken en passant
+		if rank == firstRank && occupancy&(1<<doubleTargetSquare) == 0 && targets&(1<<doubleTargetSquare) != 0 {
+			list.add(Move{startSquare, doubleTargetSquare, NoFlag})
 		}
+	}
 
-		if inSearch {
-			moves = append(moves, Move{startSquare, targetSquare, NoFlag})
-			continue
-		}
+	if mode == generateQuiets {
+		return
+	}
 
-		if g.Board[targetSquare].color() != piece.color() && g.Board[targetSquare].color() != None {
-			if promotionMoveAllowed {
-				moves = append(moves, Move{startSquare, targetSquare, PromoteToQueen})
-				moves = append(moves, Move{startSquare, targetSquare, PromoteToKnight})
-				moves = append(moves, Move{startSquare, targetSquare, PromoteToRook})
-				moves = append(moves, Move{startSquare, targetSquare, PromoteToBishop})
-			} else {
-				moves = append(moves, Move{startSquare, targetSquare, NoFlag})
-			}
+	for captures := attacks & g.bitboards[opponent] & targets; captures != 0; {
+		targetSquare := popLSB(&captures)
+		if rank == promotionRank {
+			list.addPromotions(startSquare, targetSquare)
+		} else {
+			list.add(Move{startSquare, targetSquare, NoFlag})
 		}
+	}
 
-		enPassantFile := g.currentGameState >> 4 & 0b1111
-		if enPassantFile == 0 {
-			continue
-		}
-		enPassantRank := 5
-		if piece.color() == Black {
-			enPassantRank = 2
-		}
+	enPassantFile := g.currentGameState >> 4 & 0b1111
+	if enPassantFile != 0 {
 		enPassantSquare := enPassantRank*BoardSize + int(enPassantFile-1)
-		if targetSquare == enPassantSquare {
-			moves = append(moves, Move{startSquare, targetSquare, EnPassantCapture})
+		move := Move{startSquare, enPassantSquare, EnPassantCapture}
+		if attacks&(1<<enPassantSquare) != 0 && g.isEnPassantLegal(move, info) {
+			list.add(move)
 		}
 	}
-
-	return moves
 }
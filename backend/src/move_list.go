@@ -0,0 +1,48 @@
+package game
+
+// No legal position has more moves than this, Crazyhouse positions with full
+// pockets can have over 300
+const MaxMoves = 512
+
+// MoveList is a fixed size list of moves that the generators fill in place,
+// so generating moves does not allocate
+type MoveList struct {
+	moves [MaxMoves]Move
+	count int
+}
+
+func (l *MoveList) Len() int {
+	return l.count
+}
+
+func (l *MoveList) At(i int) Move {
+	return l.moves[i]
+}
+
+// Moves returns the moves in the list. The slice shares memory with the list
+// and is only valid until the list is filled again
+func (l *MoveList) Moves() []Move {
+	return l.moves[:l.count]
+}
+
+func (l *MoveList) Clear() {
+	l.count = 0
+}
+
+func (l *MoveList) add(move Move) {
+	l.moves[l.count] = move
+	l.count++
+}
+
+func (l *MoveList) addTargets(startSquare int, targets uint64) {
+	for targets != 0 {
+		l.add(Move{startSquare, popLSB(&targets), NoFlag})
+	}
+}
+
+func (l *MoveList) addPromotions(startSquare, targetSquare int) {
+	l.add(Move{startSquare, targetSquare, PromoteToQueen})
+	l.add(Move{startSquare, targetSquare, PromoteToKnight})
+	l.add(Move{startSquare, targetSquare, PromoteToRook})
+	l.add(Move{startSquare, targetSquare, PromoteToBishop})
+}
@@ -0,0 +1,31 @@
+package game
+
+// MakeNullMove passes the turn to the opponent without moving a piece, as the
+// search does to test whether a position is good even without a move. It is
+// not part of the move history and has to be undone with UnmakeNullMove before
+// any other move is unmade
+func (g *Game) MakeNullMove() {
+	// Keeps the castling rights and checks given, there is no en passant
+	// square after a pass
+	gameState := g.currentGameState&(0b1111|checksMask) | g.fiftyMoveCounter<<14
+	g.hash ^= gameStateKey(g.currentGameState) ^ gameStateKey(gameState) ^ zobristSideToMoveKey
+	g.currentGameState = gameState
+	g.gameStateHistory = append(g.gameStateHistory, gameState)
+	g.ColorToMove = !g.ColorToMove
+
+	// Positions from before the pass do not count as repetitions
+	g.fiftyMoveCounter = 0
+	g.hashHistory = append(g.hashHistory, g.hash)
+}
+
+func (g *Game) UnmakeNullMove() {
+	nullGameState := g.gameStateHistory[len(g.gameStateHistory)-1]
+	g.gameStateHistory = g.gameStateHistory[:len(g.gameStateHistory)-1]
+	g.hashHistory = g.hashHistory[:len(g.hashHistory)-1]
+	gameState := g.gameStateHistory[len(g.gameStateHistory)-1]
+
+	g.fiftyMoveCounter = (nullGameState >> 14) & 0b11111111
+	g.ColorToMove = !g.ColorToMove
+	g.hash ^= gameStateKey(g.currentGameState) ^ gameStateKey(gameState) ^ zobristSideToMoveKey
+	g.currentGameState = gameState
+}
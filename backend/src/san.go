@@ -0,0 +1,247 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+	"web-chess/backend/util"
+)
+
+// MoveToSAN returns the Standard Algebraic Notation of a legal move in the
+// current position, including the check or checkmate suffix
+func MoveToSAN(g *Game, move Move) (string, error) {
+	legalMoves := g.GenerateLegalMoves()
+	move, ok := findLegalMove(legalMoves, move)
+	if !ok {
+		return "", fmt.Errorf("no move from %s to %s", util.ToChessNotation(move.StartSquare), util.ToChessNotation(move.TargetSquare))
+	}
+
+	san := sanWithoutSuffix(g, legalMoves, move)
+
+	g.MakeMove(move)
+	if g.isKingInCheck(g.ColorToMove) {
+		if len(g.GenerateLegalMoves()) == 0 {
+			san += "#"
+		} else {
+			san += "+"
+		}
+	}
+	g.UnmakeMove(move)
+
+	return san, nil
+}
+
+// ParseSAN finds the legal move in the current position described by a SAN
+// string such as "e4", "Nbd7", "exd8=Q+", "O-O-O" or "N@f3"
+func ParseSAN(g *Game, san string) (Move, error) {
+	notation := strings.TrimRight(strings.TrimSpace(san), "+#!?")
+	if notation == "" {
+		return Move{}, fmt.Errorf("empty move")
+	}
+	if strings.Contains(notation, "@") {
+		return parseDrop(g, notation)
+	}
+
+	legalMoves := g.GenerateLegalMoves()
+
+	switch notation {
+	case "O-O", "0-0", "O-O-O", "0-0-0":
+		kingside := len(notation) == 3
+		for _, m := range legalMoves {
+			if m.Flag == Castling && (m.TargetSquare > m.StartSquare) == kingside {
+				return m, nil
+			}
+		}
+		return Move{}, fmt.Errorf("illegal move %s", san)
+	}
+
+	pieceType := Pawn
+	if strings.ContainsRune("NBRQK", rune(notation[0])) {
+		piece := createPiece(rune(notation[0]))
+		pieceType = piece.pieceType()
+		notation = notation[1:]
+	}
+
+	promotionFlag := NoFlag
+	if i := strings.IndexByte(notation, '='); i >= 0 {
+		if i != len(notation)-2 {
+			return Move{}, fmt.Errorf("invalid promotion in %s", san)
+		}
+		promotionFlag = promotionFlagForSymbol(notation[i+1])
+		notation = notation[:i]
+	} else if pieceType == Pawn && len(notation) > 0 && strings.ContainsRune("NBRQ", rune(notation[len(notation)-1])) {
+		promotionFlag = promotionFlagForSymbol(notation[len(notation)-1])
+		notation = notation[:len(notation)-1]
+	}
+	if promotionFlag == -1 {
+		return Move{}, fmt.Errorf("invalid promotion piece in %s", san)
+	}
+
+	if len(notation) < 2 || !isSquareNotation(notation[len(notation)-2:]) {
+		return Move{}, fmt.Errorf("invalid target square in %s", san)
+	}
+	targetSquare := util.FromChessNotation(notation[len(notation)-2:])
+
+	fromFile, fromRank := -1, -1
+	for _, char := range strings.ReplaceAll(notation[:len(notation)-2], "x", "") {
+		switch {
+		case char >= 'a' && char <= 'h':
+			fromFile = int(char - 'a')
+		case char >= '1' && char <= '8':
+			fromRank = int(char - '1')
+		default:
+			return Move{}, fmt.Errorf("invalid character %q in %s", char, san)
+		}
+	}
+
+	matches := []Move{}
+	for _, m := range legalMoves {
+		if m.TargetSquare != targetSquare || g.Board[m.StartSquare].pieceType() != pieceType {
+			continue
+		}
+		if m.Flag == Castling {
+			continue
+		}
+		if fromFile != -1 && m.StartSquare%BoardSize != fromFile {
+			continue
+		}
+		if fromRank != -1 && m.StartSquare/BoardSize != fromRank {
+			continue
+		}
+		if isPromotionFlag(m.Flag) && m.Flag != promotionFlag {
+			continue
+		}
+		if !isPromotionFlag(m.Flag) && promotionFlag != NoFlag {
+			continue
+		}
+		matches = append(matches, m)
+	}
+
+	switch len(matches) {
+	case 0:
+		return Move{}, fmt.Errorf("illegal move %s", san)
+	case 1:
+		return matches[0], nil
+	}
+	return Move{}, fmt.Errorf("ambiguous move %s", san)
+}
+
+func sanWithoutSuffix(g *Game, legalMoves []Move, move Move) string {
+	if isDropFlag(move.Flag) {
+		return move.UCI()
+	}
+	if move.Flag == Castling {
+		if move.TargetSquare > move.StartSquare {
+			return "O-O"
+		}
+		return "O-O-O"
+	}
+
+	piece := g.Board[move.StartSquare]
+	isCapture := g.Board[move.TargetSquare].pieceType() != None || move.Flag == EnPassantCapture
+	target := util.ToChessNotation(move.TargetSquare)
+
+	if piece.pieceType() == Pawn {
+		san := ""
+		if isCapture {
+			san = util.ToChessNotation(move.StartSquare)[:1] + "x"
+		}
+		san += target
+		if isPromotionFlag(move.Flag) {
+			san += "=" + pieceLetter(promotionPieceType(move.Flag))
+		}
+		return san
+	}
+
+	san := pieceLetter(piece.pieceType())
+
+	// Disambiguate by file if that is enough, otherwise by rank, otherwise both
+	ambiguous, sameFile, sameRank := false, false, false
+	for _, m := range legalMoves {
+		if m.TargetSquare != move.TargetSquare || m.StartSquare == move.StartSquare {
+			continue
+		}
+		if g.Board[m.StartSquare].pieceType() != piece.pieceType() {
+			continue
+		}
+		ambiguous = true
+		if m.StartSquare%BoardSize == move.StartSquare%BoardSize {
+			sameFile = true
+		}
+		if m.StartSquare/BoardSize == move.StartSquare/BoardSize {
+			sameRank = true
+		}
+	}
+	from := util.ToChessNotation(move.StartSquare)
+	if ambiguous {
+		switch {
+		case !sameFile:
+			san += from[:1]
+		case !sameRank:
+			san += from[1:]
+		default:
+			san += from
+		}
+	}
+
+	if isCapture {
+		san += "x"
+	}
+	return san + target
+}
+
+// Matches a move by its squares, filling in the flag from the legal move if
+// none was given. Drops only match the drop of the same piece
+func findLegalMove(legalMoves []Move, move Move) (Move, bool) {
+	for _, m := range legalMoves {
+		if m.StartSquare != move.StartSquare || m.TargetSquare != move.TargetSquare {
+			continue
+		}
+		if isDropFlag(m.Flag) && m.Flag != move.Flag {
+			continue
+		}
+		if move.Flag == NoFlag || move.Flag == m.Flag {
+			return m, true
+		}
+	}
+	return move, false
+}
+
+func pieceLetter(pieceType int) string {
+	return symbolForPiece(Piece{pieceType | White})
+}
+
+func isSquareNotation(notation string) bool {
+	return len(notation) == 2 && notation[0] >= 'a' && notation[0] <= 'h' && notation[1] >= '1' && notation[1] <= '8'
+}
+
+func isPromotionFlag(flag int) bool {
+	return flag == PromoteToQueen || flag == PromoteToKnight || flag == PromoteToRook || flag == PromoteToBishop
+}
+
+func promotionPieceType(flag int) int {
+	switch flag {
+	case PromoteToQueen:
+		return Queen
+	case PromoteToKnight:
+		return Knight
+	case PromoteToRook:
+		return Rook
+	case PromoteToBishop:
+		return Bishop
+	}
+	return None
+}
+
+func promotionFlagForSymbol(symbol byte) int {
+	switch symbol {
+	case 'Q', 'q':
+		return PromoteToQueen
+	case 'N', 'n':
+		return PromoteToKnight
+	case 'R', 'r':
+		return PromoteToRook
+	case 'B', 'b':
+		return PromoteToBishop
+	}
+	return -1
+}
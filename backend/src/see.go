@@ -0,0 +1,82 @@
+package game
+
+import "math/bits"
+
+// Piece values used to judge exchanges, indexed by piece type
+var seePieceValues = [...]int{
+	None:   0,
+	King:   20000,
+	Pawn:   100,
+	Knight: 320,
+	Bishop: 330,
+	Rook:   500,
+	Queen:  900,
+}
+
+// Cheapest pieces first, the order in which they join an exchange
+var seeCaptureOrder = [...]int{Pawn, Knight, Bishop, Rook, Queen, King}
+
+// SEE returns the material the side to move wins by playing the move, when
+// both sides go on capturing on the target square with their least valuable
+// piece and either side may stop when that is better for it. Pins are not
+// taken into account
+func (g *Game) SEE(move Move) int {
+	from, to := move.StartSquare, move.TargetSquare
+	occupancy := g.bitboards[White] | g.bitboards[Black]
+
+	captured := g.Board[to].pieceType()
+	if move.Flag == EnPassantCapture {
+		captured = Pawn
+		occupancy ^= 1 << (to&0b111 | from&^0b111)
+	}
+	attacker := g.Board[from].pieceType()
+
+	var gain [32]int
+	gain[0] = seePieceValues[captured]
+	if isPromotionFlag(move.Flag) {
+		attacker = promotionPieceType(move.Flag)
+		gain[0] += seePieceValues[attacker] - seePieceValues[Pawn]
+	}
+
+	occupancy ^= 1 << from
+	attackers := (g.attackersTo(to, true, occupancy) | g.attackersTo(to, false, occupancy)) & occupancy
+	diagonal := g.bitboards[Bishop|White] | g.bitboards[Bishop|Black] | g.bitboards[Queen|White] | g.bitboards[Queen|Black]
+	straight := g.bitboards[Rook|White] | g.bitboards[Rook|Black] | g.bitboards[Queen|White] | g.bitboards[Queen|Black]
+
+	color := !g.ColorToMove
+	depth := 0
+	for depth < len(gain)-1 {
+		depth++
+		// What the side to capture next wins if it takes the last capturer
+		gain[depth] = seePieceValues[attacker] - gain[depth-1]
+
+		ours := attackers & g.bitboards[colorIndex(color)]
+		if ours == 0 {
+			break
+		}
+		next, square := None, 0
+		for _, pieceType := range seeCaptureOrder {
+			if pieces := ours & g.bitboards[pieceType|colorIndex(color)]; pieces != 0 {
+				next, square = pieceType, bits.TrailingZeros64(pieces)
+				break
+			}
+		}
+		// The king cannot capture onto a square the opponent still covers
+		if next == King && attackers&g.bitboards[colorIndex(!color)] != 0 {
+			break
+		}
+
+		occupancy ^= 1 << square
+		// Sliders behind the piece that captured now see the square
+		attackers |= bishopAttacks(to, occupancy)&diagonal | rookAttacks(to, occupancy)&straight
+		attackers &= occupancy
+		attacker = next
+		color = !color
+	}
+
+	// Each side only continues the exchange when that does not lose material
+	for depth--; depth > 0; depth-- {
+		gain[depth-1] = -max(-gain[depth-1], gain[depth])
+	}
+	return gain[0]
+}
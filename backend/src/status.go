@@ -0,0 +1,246 @@
+package game
+
+import (
+	"errors"
+	"math/bits"
+)
+
+type Status int
+
+const (
+	Ongoing Status = iota
+	Checkmate
+	Stalemate
+	FiftyMoveRule
+	ThreefoldRepetition
+	InsufficientMaterial
+	SeventyFiveMoveRule
+	FivefoldRepetition
+	// The side to move ran out of time
+	Timeout
+	// The side to move ran out of time, but the opponent cannot checkmate
+	TimeoutVsInsufficientMaterial
+	// The king of the opponent reached the centre in King of the Hill
+	KingOnHill
+	// The opponent gave the third check in Three-check
+	ThirdCheck
+	// The king of the side to move was blown up in Atomic
+	KingExploded
+	// The side to move has no pieces left, as when the horde is captured
+	NoPiecesLeft
+)
+
+func (s Status) String() string {
+	switch s {
+	case Checkmate:
+		return "checkmate"
+	case Stalemate:
+		return "stalemate"
+	case FiftyMoveRule:
+		return "fifty-move-rule"
+	case ThreefoldRepetition:
+		return "threefold-repetition"
+	case InsufficientMaterial:
+		return "insufficient-material"
+	case SeventyFiveMoveRule:
+		return "seventy-five-move-rule"
+	case FivefoldRepetition:
+		return "fivefold-repetition"
+	case Timeout:
+		return "timeout"
+	case TimeoutVsInsufficientMaterial:
+		return "timeout-vs-insufficient-material"
+	case KingOnHill:
+		return "king-on-hill"
+	case ThirdCheck:
+		return "third-check"
+	case KingExploded:
+		return "king-exploded"
+	case NoPiecesLeft:
+		return "no-pieces-left"
+	}
+	return "ongoing"
+}
+
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s Status) IsOver() bool {
+	return s != Ongoing
+}
+
+func (s Status) IsDraw() bool {
+	return s != Ongoing && !s.isLoss()
+}
+
+// Whether the side to move lost
+func (s Status) isLoss() bool {
+	switch s {
+	case Checkmate, Timeout, KingOnHill, ThirdCheck, KingExploded, NoPiecesLeft:
+		return true
+	}
+	return false
+}
+
+func (g *Game) Status() Status {
+	if status := g.variantOutcome(); status != Ongoing {
+		return status
+	}
+	if len(g.GenerateLegalMoves()) == 0 {
+		if g.isKingInCheck(g.ColorToMove) {
+			return Checkmate
+		}
+		return Stalemate
+	}
+	if g.IsInsufficientMaterial() {
+		return InsufficientMaterial
+	}
+	if g.fiftyMoveCounter >= 150 {
+		return SeventyFiveMoveRule
+	}
+	if g.RepetitionCount() >= 5 {
+		return FivefoldRepetition
+	}
+	if g.endedBy != Ongoing && g.endedByPly == len(g.hashHistory) {
+		return g.endedBy
+	}
+	return Ongoing
+}
+
+// CanClaimDraw reports whether the side to move may claim a draw by the
+// fifty-move rule or threefold repetition
+func (g *Game) CanClaimDraw() bool {
+	return g.IsThreefoldRepetition() || g.fiftyMoveCounter >= 100
+}
+
+// ClaimDraw ends the game as a draw if the side to move is entitled to claim
+// one. The claim lapses when the last move is unmade
+func (g *Game) ClaimDraw() error {
+	if g.Status() != Ongoing {
+		return errors.New("game is already over")
+	}
+	switch {
+	case g.IsThreefoldRepetition():
+		g.endedBy = ThreefoldRepetition
+	case g.fiftyMoveCounter >= 100:
+		g.endedBy = FiftyMoveRule
+	default:
+		return errors.New("no draw can be claimed in this position")
+	}
+	g.endedByPly = len(g.hashHistory)
+	return nil
+}
+
+// Timeout ends the game because the side to move ran out of time. It is a
+// draw if the opponent does not have the material to checkmate. Like a draw
+// claim it lapses when the last move is unmade
+func (g *Game) Timeout() error {
+	if g.Status() != Ongoing {
+		return errors.New("game is already over")
+	}
+	g.endedBy = Timeout
+	if !g.HasMatingMaterial(!g.ColorToMove) {
+		g.endedBy = TimeoutVsInsufficientMaterial
+	}
+	g.endedByPly = len(g.hashHistory)
+	return nil
+}
+
+// Winner returns the color of the side that won, or None if the game is
+// ongoing or drawn
+func (g *Game) Winner() int {
+	if !g.Status().isLoss() {
+		return None
+	}
+	if g.ColorToMove {
+		return Black
+	}
+	return White
+}
+
+// Result returns the game result in PGN notation: "1-0", "0-1", "1/2-1/2" or "*"
+func (g *Game) Result() string {
+	status := g.Status()
+	switch {
+	case status.isLoss() && g.ColorToMove:
+		return "0-1"
+	case status.isLoss():
+		return "1-0"
+	case status.IsDraw():
+		return "1/2-1/2"
+	}
+	return "*"
+}
+
+// RepetitionCount returns how many times the current position has occurred,
+// including the current occurrence
+func (g *Game) RepetitionCount() int {
+	last := len(g.hashHistory) - 1
+	count := 1
+	// Only positions since the last capture or pawn move can repeat, and only
+	// every other position has the same side to move
+	for i := last - 2; i >= 0 && last-i <= int(g.fiftyMoveCounter); i -= 2 {
+		if g.hashHistory[i] == g.hashHistory[last] {
+			count++
+		}
+	}
+	return count
+}
+
+func (g *Game) IsThreefoldRepetition() bool {
+	return g.RepetitionCount() >= 3
+}
+
+// Squares where rank and file add up to an even number, starting with a1
+const darkSquares uint64 = 0xaa55aa55aa55aa55
+
+// IsInsufficientMaterial reports whether neither side has enough material
+// left to checkmate, or to win in another way in a variant
+func (g *Game) IsInsufficientMaterial() bool {
+	if g.variant != nil {
+		return !g.variant.canWin(g, true) && !g.variant.canWin(g, false)
+	}
+	return g.insufficientMaterial()
+}
+
+func (g *Game) insufficientMaterial() bool {
+	pawnsRooksQueens := g.bitboards[Pawn|White] | g.bitboards[Pawn|Black] |
+		g.bitboards[Rook|White] | g.bitboards[Rook|Black] |
+		g.bitboards[Queen|White] | g.bitboards[Queen|Black]
+	if pawnsRooksQueens != 0 {
+		return false
+	}
+	knights := bits.OnesCount64(g.bitboards[Knight|White] | g.bitboards[Knight|Black])
+	bishops := g.bitboards[Bishop|White] | g.bitboards[Bishop|Black]
+
+	// King against king with at most a single minor piece
+	if knights+bits.OnesCount64(bishops) <= 1 {
+		return true
+	}
+	// Any number of bishops that all stand on squares of the same color
+	return knights == 0 && (bishops&darkSquares == 0 || bishops&^darkSquares == 0)
+}
+
+// HasMatingMaterial reports whether any series of legal moves lets the color
+// checkmate, which decides a flag fall against the other side. Variants
+// decide by their own way of winning
+func (g *Game) HasMatingMaterial(color bool) bool {
+	return g.Variant().canWin(g, color)
+}
+
+// A lone king never mates. Any other material can, unless the pieces of both
+// sides together cannot, as the pieces of the opponent may hem in its king
+func (g *Game) hasMatingMaterial(color bool) bool {
+	us := colorIndex(color)
+	if g.bitboards[us] == g.bitboards[King|us] {
+		return false
+	}
+	return !g.insufficientMaterial()
+}
+
+// HalfmoveClock returns the number of half moves since the last capture or
+// pawn move
+func (g *Game) HalfmoveClock() int {
+	return int(g.fiftyMoveCounter)
+}
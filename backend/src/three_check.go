@@ -0,0 +1,112 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrFenChecks = errors.New("three-check counters must be like 3+3 or +0+0")
+
+// ThreeCheck is won by checkmate or by giving check for the third time
+var ThreeCheck Variant = threeCheck{}
+
+type threeCheck struct{ standardRules }
+
+// The checks given by white and by black take two bits each in the game state
+const (
+	checksShift        = 22
+	checksMask  uint32 = 0b1111 << checksShift
+	checksToWin        = 3
+)
+
+func checkShift(white bool) int {
+	if white {
+		return checksShift
+	}
+	return checksShift + 2
+}
+
+func checksGiven(gameState uint32, white bool) int {
+	return int(gameState >> checkShift(white) & 0b11)
+}
+
+func (threeCheck) Name() string { return "Three-check" }
+
+func (threeCheck) StartFen() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 3+3 0 1"
+}
+
+func (threeCheck) outcome(g *Game) Status {
+	if checksGiven(g.currentGameState, !g.ColorToMove) >= checksToWin {
+		return ThirdCheck
+	}
+	return Ongoing
+}
+
+// Carries the counters over and counts the check the move gives
+func (threeCheck) makeMove(g *Game, move Move, gameState uint32) uint32 {
+	gameState |= g.currentGameState & checksMask
+	if g.isKingInCheck(!g.ColorToMove) {
+		gameState += 1 << checkShift(g.ColorToMove)
+	}
+	return gameState
+}
+
+// Any piece but the king can give check
+func (threeCheck) canWin(g *Game, color bool) bool {
+	us := colorIndex(color)
+	return g.bitboards[us]&^g.bitboards[King|us] != 0
+}
+
+// The counters are read as the checks each side has left before the clocks,
+// "3+3" as Fairy-Stockfish and python-chess write them, or as the checks each
+// side has given at the end, "+0+0" as lichess used to
+func (threeCheck) parseFenFields(g *Game, fen string) (string, uint32, error) {
+	fields := strings.Fields(fen)
+	if len(fields) != 7 {
+		return fen, 0, nil
+	}
+
+	var counters []string
+	remaining := false
+	switch {
+	case strings.Contains(fields[4], "+"):
+		counters = strings.Split(fields[4], "+")
+		remaining = true
+		fields = append(fields[:4], fields[5:]...)
+	case strings.HasPrefix(fields[6], "+"):
+		counters = strings.Split(fields[6][1:], "+")
+		fields = fields[:6]
+	}
+	if len(counters) != 2 {
+		return fen, 0, ErrFenChecks
+	}
+
+	var gameState uint32
+	for i, counter := range counters {
+		checks, err := strconv.Atoi(counter)
+		if err != nil || checks < 0 || checks > checksToWin {
+			return fen, 0, fmt.Errorf("%w, got %q", ErrFenChecks, counter)
+		}
+		if remaining {
+			checks = checksToWin - checks
+		}
+		gameState |= uint32(checks) << checkShift(i == 0)
+	}
+	return strings.Join(fields, " "), gameState, nil
+}
+
+// Writes the checks each side has left before the clocks
+func (threeCheck) writeFenFields(g *Game, fen string) string {
+	fields := strings.Fields(fen)
+	counters := fmt.Sprintf("%d+%d", checksToWin-g.ChecksGiven(true), checksToWin-g.ChecksGiven(false))
+	return strings.Join(append(fields[:4], append([]string{counters}, fields[4:]...)...), " ")
+}
+
+// ChecksGiven returns how many times the color has given check, which only
+// counts in Three-check
+func (g *Game) ChecksGiven(color bool) int {
+	return checksGiven(g.currentGameState, color)
+}
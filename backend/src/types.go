@@ -28,7 +28,9 @@ type Game struct {
 	Board [BoardSize * BoardSize]Piece `json:"board"`
 	// 1: white, 0: black
 	ColorToMove bool `json:"ColorToMove"`
-	bitboards   [23]uint64
+	// Indexed by piece type, with the pieces of each color under White and
+	// Black
+	bitboards [23]uint64
 	// Bits 0-3: white and black kingside/queen side castling rights
 	//
 	// Bit 0: black queenside,
@@ -40,13 +42,45 @@ type Game struct {
 	//
 	// Bits 8-13: captured piece type
 	//
-	// Bits 14-19: fifty move counter
+	// Bits 14-21: fifty move counter before the move was made
 	//
-	// Bits 20-31: full move counter
+	// Bits 22-25: checks given by white and by black in Three-check
+	//
+	// Bit 26: the captured piece was promoted, so it went to the pocket as a
+	// pawn in Crazyhouse
+	//
+	// Bit 27: a pawn of the side to move stands next to the pawn that moved
+	// two squares, only then is the en passant square part of the hash
 	currentGameState uint32
 	gameStateHistory []uint32
+	moveHistory      []Move
+	initialFen       string
+	hashHistory      []uint64
 	fiftyMoveCounter uint32
 	plyCount         uint32
+	// Zobrist key of the current position, updated incrementally
+	hash uint64
+	// End of the game that does not follow from the position, a draw claim
+	// or a flag fall of the side to move, and the length of the history at
+	// the time, so it lapses once the move is undone
+	endedBy    Status
+	endedByPly int
+	// Square of the rook each castling right castles with, indexed by the bit
+	// of the right
+	castlingRooks [4]int
+	// Castling moves are encoded as the king taking its own rook and the FEN
+	// names the castling rooks by their files
+	chess960 bool
+	// Rules of the variant, nil for standard chess
+	variant Variant
+	// Pieces blown off the board by each capture in Atomic, to put back when
+	// the capture is unmade
+	explosions []explosion
+	// Pieces in hand in Crazyhouse, indexed by piece type like the bitboards
+	pockets [23]int
+	// Squares of the pieces that were promoted from pawns, which go back to
+	// the pocket as pawns when captured in Crazyhouse
+	promoted uint64
 }
 
 func (g *Game) BitBoards() [23]uint64 {
@@ -63,6 +97,13 @@ const (
 	PromoteToRook
 	PromoteToBishop
 	PawnTwoForward
+	// Drops put a piece from the pocket on an empty square in Crazyhouse.
+	// They have no start square, it is the target square
+	DropPawn
+	DropKnight
+	DropBishop
+	DropRook
+	DropQueen
 )
 
 type Move struct {
@@ -71,6 +112,22 @@ type Move struct {
 	Flag         int `json:"flag"`
 }
 
+// PackedMove stores a move in 16 bits: the start square in bits 0-5, the
+// target square in bits 6-11 and the flag in bits 12-15
+type PackedMove uint16
+
+func (m Move) Pack() PackedMove {
+	return PackedMove(m.StartSquare | m.TargetSquare<<6 | m.Flag<<12)
+}
+
+func (p PackedMove) Unpack() Move {
+	return Move{
+		StartSquare:  int(p & 0x3f),
+		TargetSquare: int(p >> 6 & 0x3f),
+		Flag:         int(p >> 12),
+	}
+}
+
 type Piece struct {
 	Type int `json:"type"`
 }
@@ -82,3 +139,8 @@ func (p *Piece) color() int {
 func (p *Piece) pieceType() int {
 	return p.Type & 7
 }
+
+// PieceType returns the type of the piece without its color
+func (p Piece) PieceType() int {
+	return p.Type & 7
+}
@@ -0,0 +1,92 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+	"web-chess/backend/util"
+)
+
+// UCI returns the move in long algebraic notation as used by the Universal
+// Chess Interface, e.g. "e2e4", "e1g1" or "e7e8q". Castling in Chess960 is
+// written as the king taking its rook, e.g. "e1h1", and drops as the piece
+// and its square, e.g. "N@f3"
+func (m Move) UCI() string {
+	if isDropFlag(m.Flag) {
+		return pieceLetter(dropPieceType(m.Flag)) + "@" + util.ToChessNotation(m.TargetSquare)
+	}
+	uci := util.ToChessNotation(m.StartSquare) + util.ToChessNotation(m.TargetSquare)
+	if isPromotionFlag(m.Flag) {
+		uci += strings.ToLower(pieceLetter(promotionPieceType(m.Flag)))
+	}
+	return uci
+}
+
+// ParseUCIMove finds the legal move in the current position described by a
+// long algebraic string such as "e2e4", "e7e8q" or "P@e4"
+func ParseUCIMove(g *Game, uci string) (Move, error) {
+	if len(uci) == 4 && uci[1] == '@' {
+		return parseDrop(g, uci)
+	}
+	if len(uci) != 4 && len(uci) != 5 {
+		return Move{}, fmt.Errorf("invalid move %q", uci)
+	}
+	if !isSquareNotation(uci[0:2]) || !isSquareNotation(uci[2:4]) {
+		return Move{}, fmt.Errorf("invalid squares in move %q", uci)
+	}
+
+	promotionFlag := NoFlag
+	if len(uci) == 5 {
+		promotionFlag = promotionFlagForSymbol(uci[4])
+		if promotionFlag == -1 {
+			return Move{}, fmt.Errorf("invalid promotion piece in move %q", uci)
+		}
+	}
+
+	startSquare := util.FromChessNotation(uci[0:2])
+	targetSquare := util.FromChessNotation(uci[2:4])
+	for _, m := range g.GenerateLegalMoves() {
+		if m.StartSquare != startSquare || m.TargetSquare != targetSquare {
+			continue
+		}
+		if isPromotionFlag(m.Flag) != (promotionFlag != NoFlag) {
+			continue
+		}
+		if promotionFlag != NoFlag && m.Flag != promotionFlag {
+			continue
+		}
+		return m, nil
+	}
+	// Castling is written as the king taking its own rook in Chess960 and as
+	// the king moving two squares otherwise, either is understood when it is
+	// not another move
+	for _, m := range g.GenerateLegalMoves() {
+		if m.Flag != Castling || m.StartSquare != startSquare || promotionFlag != NoFlag {
+			continue
+		}
+		if kingTo, rookFrom, _ := g.castlingSquares(m, g.ColorToMove); targetSquare == kingTo || targetSquare == rookFrom {
+			return m, nil
+		}
+	}
+	return Move{}, fmt.Errorf("illegal move %s", uci)
+}
+
+// Finds the legal drop described by a piece letter, an @ and a square, such as
+// "N@f3". The letter may be left out for a pawn, as SAN does
+func parseDrop(g *Game, notation string) (Move, error) {
+	pieceType := Pawn
+	if notation[0] != '@' {
+		pieceType = createPiece(rune(notation[0])).PieceType()
+		notation = notation[1:]
+	}
+	if len(notation) != 3 || notation[0] != '@' || !isSquareNotation(notation[1:]) || pieceType == None || pieceType == King {
+		return Move{}, fmt.Errorf("invalid drop %q", notation)
+	}
+
+	move := Move{util.FromChessNotation(notation[1:]), util.FromChessNotation(notation[1:]), dropFlag(pieceType)}
+	for _, m := range g.GenerateLegalMoves() {
+		if m == move {
+			return m, nil
+		}
+	}
+	return Move{}, fmt.Errorf("illegal drop %s", move.UCI())
+}
@@ -0,0 +1,152 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Variant changes the rules of standard chess. The hooks are called by the
+// game where the rules of a variant differ, so variants are only implemented
+// in this package
+type Variant interface {
+	// Name as written in the Variant tag of PGN
+	Name() string
+	// StartFen is the position games of the variant start from
+	StartFen() string
+
+	// Returns how the rules of the variant ended the game, a loss for the side
+	// to move, or Ongoing. No moves are legal once the game has ended
+	outcome(g *Game) Status
+	// Generates the legal moves into the list in place of the standard
+	// generation, or returns false to leave it to the standard generation
+	generateMoves(g *Game, list *MoveList, mode generationMode) bool
+	// Called by MakeMove with the board updated but the side to move not yet
+	// switched. Returns the new game state, which the side effects of the
+	// move may change
+	makeMove(g *Game, move Move, gameState uint32) uint32
+	// Called by UnmakeMove before the move is taken back, to undo what
+	// makeMove did to the board
+	unmakeMove(g *Game, move Move)
+	// Squares on which the king of the color cannot be in check
+	safeSquares(g *Game, color bool) uint64
+	// Whether the color has the material to win, which decides insufficient
+	// material and flag falls
+	canWin(g *Game, color bool) bool
+	// Checks the kings and pawns of a FEN, the other fields are checked the
+	// same in every variant
+	validatePieces(board *[BoardSize * BoardSize]Piece) error
+	// Takes the fields the variant adds to a FEN out of it. Returns the six
+	// standard fields and the game state the others stand for, and loads
+	// what is not part of the game state into g
+	parseFenFields(g *Game, fen string) (string, uint32, error)
+	// Adds the fields of the variant to the FEN of the position
+	writeFenFields(g *Game, fen string) string
+}
+
+// Standard is the variant of games without one
+var Standard Variant = standardRules{}
+
+// Variants lists every variant other than standard chess
+var Variants = []Variant{KingOfTheHill, ThreeCheck, Atomic, Horde, Crazyhouse}
+
+// The rules of standard chess, embedded by variants so they only implement
+// the hooks they change
+type standardRules struct{}
+
+func (standardRules) Name() string     { return "Standard" }
+func (standardRules) StartFen() string { return StartingFen }
+
+func (standardRules) outcome(g *Game) Status { return Ongoing }
+
+func (standardRules) generateMoves(g *Game, list *MoveList, mode generationMode) bool {
+	return false
+}
+
+func (standardRules) makeMove(g *Game, move Move, gameState uint32) uint32 { return gameState }
+func (standardRules) unmakeMove(g *Game, move Move)                        {}
+func (standardRules) safeSquares(g *Game, color bool) uint64               { return 0 }
+
+func (standardRules) canWin(g *Game, color bool) bool {
+	return g.hasMatingMaterial(color)
+}
+
+func (standardRules) validatePieces(board *[BoardSize * BoardSize]Piece) error {
+	return validateKingsAndPawns(board, 1, 1, false)
+}
+
+func (standardRules) parseFenFields(g *Game, fen string) (string, uint32, error) { return fen, 0, nil }
+func (standardRules) writeFenFields(g *Game, fen string) string                  { return fen }
+
+// ParseVariant finds a variant by its name, ignoring case, spaces and dashes,
+// so the Variant tags of PGN and names such as "threecheck" are recognized
+func ParseVariant(name string) (Variant, error) {
+	switch normalized := VariantKey(name); normalized {
+	case "", "standard", "chess":
+		return Standard, nil
+	case "koth":
+		return KingOfTheHill, nil
+	case "3check":
+		return ThreeCheck, nil
+	case "zh":
+		return Crazyhouse, nil
+	default:
+		for _, variant := range Variants {
+			if VariantKey(variant.Name()) == normalized {
+				return variant, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown variant %q", name)
+}
+
+// VariantKey returns the name in lower case without spaces and dashes, such
+// as "kingofthehill"
+func VariantKey(name string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(name))
+}
+
+// NewVariantGame starts a game of the variant from its start position
+func NewVariantGame(variant Variant) *Game {
+	return NewVariantGameFromFen(variant, variant.StartFen())
+}
+
+// NewVariantGameFromFen loads a FEN of the variant without validating it, see
+// ParseVariantFen for untrusted input
+func NewVariantGameFromFen(variant Variant, fen string) *Game {
+	precomputeOnce.Do(precomputedMoveData)
+	g := &Game{}
+	g.setVariant(variant)
+	g.loadPositionFromFen(fen)
+	return g
+}
+
+// Variant returns the rules the game is played by
+func (g *Game) Variant() Variant {
+	if g.variant == nil {
+		return Standard
+	}
+	return g.variant
+}
+
+// Standard chess is stored as nil, so the hooks are skipped in its games
+func (g *Game) setVariant(variant Variant) {
+	g.variant = variant
+	if variant == Standard {
+		g.variant = nil
+	}
+}
+
+// The outcome of the game by the rules of its variant, Ongoing for standard
+// chess
+func (g *Game) variantOutcome() Status {
+	if g.variant == nil {
+		return Ongoing
+	}
+	return g.variant.outcome(g)
+}
+
+// IsVariantLoss reports whether the rules of the variant, rather than
+// checkmate, ended the game with a loss for the side to move
+func (g *Game) IsVariantLoss() bool {
+	return g.variantOutcome() != Ongoing
+}
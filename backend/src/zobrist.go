@@ -0,0 +1,126 @@
+package game
+
+import "fmt"
+
+// Set to panic as soon as the incrementally updated key diverges from a
+// full recompute after MakeMove or UnmakeMove
+var DebugHashCheck = false
+
+var (
+	zobristPieceKeys     [23][BoardSize * BoardSize]uint64
+	zobristCastlingKeys  [16]uint64
+	zobristEnPassantKeys [BoardSize + 1]uint64 // index 0 means no en passant square
+	zobristSideToMoveKey uint64
+	// Indexed by the checks given in Three-check, index 0 means none
+	zobristCheckKeys [16]uint64
+	// Indexed by the piece and how many of it are in the pocket in
+	// Crazyhouse, so each piece added toggles one more key
+	zobristPocketKeys [23][maxPocketCount + 1]uint64
+)
+
+func init() {
+	// Fixed seed so keys are identical across runs and can be stored
+	var seed uint64 = 0x9e3779b97f4a7c15
+	next := func() uint64 {
+		// xorshift64*
+		seed ^= seed >> 12
+		seed ^= seed << 25
+		seed ^= seed >> 27
+		return seed * 0x2545f4914f6cdd1d
+	}
+
+	for _, color := range []int{White, Black} {
+		for pieceType := King; pieceType <= Queen; pieceType++ {
+			for square := range zobristPieceKeys[color|pieceType] {
+				zobristPieceKeys[color|pieceType][square] = next()
+			}
+		}
+	}
+	for i := range zobristCastlingKeys {
+		zobristCastlingKeys[i] = next()
+	}
+	for i := 1; i < len(zobristEnPassantKeys); i++ {
+		zobristEnPassantKeys[i] = next()
+	}
+	zobristSideToMoveKey = next()
+	for i := 1; i < len(zobristCheckKeys); i++ {
+		zobristCheckKeys[i] = next()
+	}
+	for _, color := range []int{White, Black} {
+		for pieceType := Pawn; pieceType <= Queen; pieceType++ {
+			for count := 1; count <= maxPocketCount; count++ {
+				zobristPocketKeys[color|pieceType][count] = next()
+			}
+		}
+	}
+}
+
+// Hash returns the Zobrist key of the current position
+func (g *Game) Hash() uint64 {
+	return g.hash
+}
+
+// VerifyHash compares the incrementally updated key against a full recompute
+func (g *Game) VerifyHash() error {
+	expected := g.computeHash()
+	if g.hash != expected {
+		return fmt.Errorf("hash mismatch for %s: incremental %016x, recomputed %016x", g.CurrentFen(), g.hash, expected)
+	}
+	return nil
+}
+
+func (g *Game) mustVerifyHash(move Move) {
+	if err := g.VerifyHash(); err != nil {
+		panic(fmt.Sprintf("after move %v: %v", move, err))
+	}
+}
+
+func (g *Game) computeHash() uint64 {
+	var hash uint64 = 0
+	for square, piece := range g.Board {
+		if piece.pieceType() != None {
+			hash ^= zobristPieceKeys[piece.Type][square]
+		}
+	}
+	for piece, count := range g.pockets {
+		for ; count > 0; count-- {
+			hash ^= zobristPocketKeys[piece][count]
+		}
+	}
+	hash ^= gameStateKey(g.currentGameState)
+	if !g.ColorToMove {
+		hash ^= zobristSideToMoveKey
+	}
+	return hash
+}
+
+// Bit of the game state set when an en passant capture may be possible
+const enPassantCapturable uint32 = 1 << 27
+
+// The en passant square only counts when a pawn could take on it, otherwise
+// the position repeats the ones with the same pieces before the double push
+func gameStateKey(gameState uint32) uint64 {
+	key := zobristCastlingKeys[gameState&0b1111] ^ zobristCheckKeys[gameState>>checksShift&0b1111]
+	if gameState&enPassantCapturable != 0 {
+		key ^= zobristEnPassantKeys[(gameState>>4)&0b1111]
+	}
+	return key
+}
+
+// Reports whether a pawn of the color stands next to the opponent's pawn that
+// just moved two squares on the file
+func (g *Game) canCaptureEnPassant(file int, color bool) bool {
+	// The pawn that moved stands on the fourth rank of its side
+	rank := 4
+	if !color {
+		rank = 3
+	}
+	var neighbours uint64
+	if file > 0 {
+		neighbours |= 1 << (rank*BoardSize + file - 1)
+	}
+	if file < BoardSize-1 {
+		neighbours |= 1 << (rank*BoardSize + file + 1)
+	}
+	return g.bitboards[Pawn|colorIndex(color)]&neighbours != 0
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var gamesBucket = []byte("games")
+
+// BoltRepository keeps the games as JSON in a single BoltDB file
+type BoltRepository struct {
+	db *bolt.DB
+}
+
+// NewBoltRepository opens the database file, creating it if needed. Only one
+// process can have the file open at a time
+func NewBoltRepository(path string) (*BoltRepository, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(gamesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltRepository{db: db}, nil
+}
+
+func (r *BoltRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r *BoltRepository) Save(record Record) error {
+	if record.ID == "" {
+		return fmt.Errorf("invalid game id %q", record.ID)
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(gamesBucket).Put([]byte(record.ID), data)
+	})
+}
+
+func (r *BoltRepository) Load(id string) (Record, error) {
+	var record Record
+	err := r.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(gamesBucket).Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, &record)
+	})
+	return record, err
+}
+
+func (r *BoltRepository) List() ([]Record, error) {
+	var records []Record
+	var errs []error
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(gamesBucket).ForEach(func(id, data []byte) error {
+			var record Record
+			if err := json.Unmarshal(data, &record); err != nil {
+				errs = append(errs, fmt.Errorf("%w: game %s: %w", ErrUnreadable, id, err))
+				return nil
+			}
+			records = append(records, record)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, errors.Join(errs...)
+}
+
+func (r *BoltRepository) Delete(id string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(gamesBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// JSONRepository keeps every game in its own JSON file in a directory
+type JSONRepository struct {
+	dir string
+}
+
+// NewJSONRepository stores games in the directory, creating it if needed
+func NewJSONRepository(dir string) (*JSONRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &JSONRepository{dir: dir}, nil
+}
+
+func (r *JSONRepository) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return "", fmt.Errorf("invalid game id %q", id)
+	}
+	return filepath.Join(r.dir, id+".json"), nil
+}
+
+// Save writes the record to a temporary file first and renames it, so a
+// crash never leaves a partly written game behind
+func (r *JSONRepository) Save(record Record) error {
+	path, err := r.path(record.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(r.dir, record.ID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+func (r *JSONRepository) Load(id string) (Record, error) {
+	path, err := r.path(id)
+	if err != nil {
+		return Record{}, err
+	}
+	return readRecord(path)
+}
+
+func readRecord(path string) (Record, error) {
+	var record Record
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return record, ErrNotFound
+	}
+	if err != nil {
+		return record, err
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return record, fmt.Errorf("%s: %w", path, err)
+	}
+	return record, nil
+}
+
+func (r *JSONRepository) List() ([]Record, error) {
+	paths, err := filepath.Glob(filepath.Join(r.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(paths))
+	var errs []error
+	for _, path := range paths {
+		record, err := readRecord(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: %w", ErrUnreadable, err))
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, errors.Join(errs...)
+}
+
+func (r *JSONRepository) Delete(id string) error {
+	path, err := r.path(id)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
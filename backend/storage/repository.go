@@ -0,0 +1,113 @@
+// Package storage keeps the games played through the server, so they survive
+// a restart
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"web-chess/backend/clock"
+	game "web-chess/backend/src"
+)
+
+var (
+	ErrNotFound = errors.New("game not found")
+	// Wrapped by the error of List for each stored game it could not decode
+	ErrUnreadable = errors.New("stored game cannot be read")
+)
+
+// GameRepository saves and loads game records by id. Implementations are safe
+// for use by several goroutines
+type GameRepository interface {
+	// Save adds the record or replaces the one with the same id
+	Save(record Record) error
+	Load(id string) (Record, error)
+	// List returns every record in no particular order. Records that cannot
+	// be decoded are left out and reported by an error wrapping ErrUnreadable
+	List() ([]Record, error)
+	Delete(id string) error
+}
+
+// Record is what is stored of a game. The position is not stored, the game
+// is rebuilt by replaying the moves from the start position
+type Record struct {
+	ID       string    `json:"id"`
+	Created  time.Time `json:"created"`
+	StartFen string    `json:"startFen"`
+	// Name of the variant, empty for standard chess
+	Variant string `json:"variant,omitempty"`
+	// In UCI notation
+	Moves []string `json:"moves"`
+	// Status of a game that ended in a way that does not follow from the
+	// moves, a flag fall or a claimed draw
+	Ended    string            `json:"ended,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	Opponent *Opponent         `json:"opponent,omitempty"`
+	Clock    *clock.Snapshot   `json:"clock,omitempty"`
+}
+
+// Opponent is the engine playing one side of the game
+type Opponent struct {
+	EngineColor string `json:"engineColor"`
+	Level       int    `json:"level"`
+	MoveTimeMs  int    `json:"moveTimeMs"`
+}
+
+// NewRecord records the start position, moves and ending of the game. The
+// other fields are left to the caller
+func NewRecord(g *game.Game) Record {
+	moves := g.Moves()
+	record := Record{
+		StartFen: g.InitialFen(),
+		Moves:    make([]string, len(moves)),
+	}
+	for i, move := range moves {
+		record.Moves[i] = move.UCI()
+	}
+	if variant := g.Variant(); variant != game.Standard {
+		record.Variant = variant.Name()
+	}
+
+	switch status := g.Status(); status {
+	case game.Timeout, game.TimeoutVsInsufficientMaterial, game.ThreefoldRepetition, game.FiftyMoveRule:
+		record.Ended = status.String()
+	}
+	return record
+}
+
+// Replay rebuilds the game by playing the moves from the start position
+func (r Record) Replay() (*game.Game, error) {
+	variant, err := game.ParseVariant(r.Variant)
+	if err != nil {
+		return nil, err
+	}
+	g, err := game.ParseVariantFen(variant, r.StartFen)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, uci := range r.Moves {
+		move, err := game.ParseUCIMove(g, uci)
+		if err == nil {
+			err = g.Move(move)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("move %d %s: %w", i+1, uci, err)
+		}
+	}
+
+	switch r.Ended {
+	case "":
+	case game.Timeout.String(), game.TimeoutVsInsufficientMaterial.String():
+		err = g.Timeout()
+	case game.ThreefoldRepetition.String(), game.FiftyMoveRule.String():
+		err = g.ClaimDraw()
+	default:
+		err = fmt.Errorf("unknown ending %q", r.Ended)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
@@ -0,0 +1,725 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"web-chess/backend/api"
+	game "web-chess/backend/src"
+	"web-chess/backend/storage"
+
+	"github.com/gorilla/websocket"
+)
+
+type apiGame struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+func postJSON(t *testing.T, url, body string, out any) *http.Response {
+	t.Helper()
+	response, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer response.Body.Close()
+	if out != nil && response.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+			t.Fatalf("Error decoding response: %v", err)
+		}
+	}
+	return response
+}
+
+func TestServerGamesAreIndependent(t *testing.T) {
+	server := httptest.NewServer(api.NewServer())
+	defer server.Close()
+
+	var first, second apiGame
+	postJSON(t, server.URL+"/new-game", "", &first)
+	postJSON(t, server.URL+"/new-game-from-fen", `{"fen": "4k3/8/8/8/8/8/8/4K2R w K - 0 1"}`, &second)
+	if first.ID == "" || first.ID == second.ID {
+		t.Fatalf("Expected two different game ids, got %q and %q", first.ID, second.ID)
+	}
+
+	response := postJSON(t, server.URL+"/games/"+first.ID+"/move", `{"san": "e4"}`, nil)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 for move, got %d", response.StatusCode)
+	}
+	response = postJSON(t, server.URL+"/games/"+second.ID+"/move", `{"san": "e4"}`, nil)
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for illegal move in second game, got %d", response.StatusCode)
+	}
+
+	var summaries []struct {
+		ID        string `json:"id"`
+		Fen       string `json:"fen"`
+		MoveCount int    `json:"moveCount"`
+	}
+	listResponse, err := http.Get(server.URL + "/games")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	json.NewDecoder(listResponse.Body).Decode(&summaries)
+	listResponse.Body.Close()
+	if len(summaries) != 2 || summaries[0].ID != first.ID || summaries[0].MoveCount != 1 || summaries[1].MoveCount != 0 {
+		t.Errorf("Unexpected game list %+v", summaries)
+	}
+
+	request, _ := http.NewRequest(http.MethodDelete, server.URL+"/games/"+first.ID, nil)
+	deleteResponse, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	deleteResponse.Body.Close()
+	if deleteResponse.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected 204 for delete, got %d", deleteResponse.StatusCode)
+	}
+
+	stateResponse, err := http.Get(server.URL + "/games/" + first.ID + "/current-state")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	stateResponse.Body.Close()
+	if stateResponse.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for deleted game, got %d", stateResponse.StatusCode)
+	}
+}
+
+func TestServerRejectsInvalidFen(t *testing.T) {
+	server := httptest.NewServer(api.NewServer())
+	defer server.Close()
+
+	response := postJSON(t, server.URL+"/new-game-from-fen", `{"fen": "8/8/8 w - - 0 1"}`, nil)
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid fen, got %d", response.StatusCode)
+	}
+}
+
+func TestServerConcurrentRequests(t *testing.T) {
+	server := httptest.NewServer(api.NewServer())
+	defer server.Close()
+
+	var g apiGame
+	postJSON(t, server.URL+"/new-game", "", &g)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				for _, path := range []string{"/current-state", "/legal-moves/12", "/pgn"} {
+					response, err := http.Get(server.URL + "/games/" + g.ID + path)
+					if err != nil {
+						t.Errorf("Error: %v", err)
+						return
+					}
+					response.Body.Close()
+				}
+				if response, err := http.Post(server.URL+"/new-game", "application/json", nil); err == nil {
+					response.Body.Close()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var state apiGame
+	response, err := http.Get(server.URL + "/games/" + g.ID)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer response.Body.Close()
+	json.NewDecoder(response.Body).Decode(&state)
+	if state.ID != g.ID || state.Status != "ongoing" {
+		t.Errorf("Unexpected state after concurrent requests %+v", state)
+	}
+}
+
+func TestServerLiveUpdates(t *testing.T) {
+	server := httptest.NewServer(api.NewServer())
+	defer server.Close()
+
+	var g apiGame
+	postJSON(t, server.URL+"/new-game", "", &g)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/games/" + g.ID + "/live"
+	var connections []*websocket.Conn
+	for i := 0; i < 2; i++ {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("Error connecting: %v", err)
+		}
+		defer conn.Close()
+		connections = append(connections, conn)
+	}
+
+	type update struct {
+		ID          string     `json:"id"`
+		Fen         string     `json:"fen"`
+		ColorToMove bool       `json:"ColorToMove"`
+		LastMove    *game.Move `json:"lastMove"`
+		Status      string     `json:"status"`
+	}
+	readUpdate := func(conn *websocket.Conn) update {
+		t.Helper()
+		var u update
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.ReadJSON(&u); err != nil {
+			t.Fatalf("Error reading update: %v", err)
+		}
+		return u
+	}
+
+	for _, conn := range connections {
+		if u := readUpdate(conn); u.Fen != game.StartingFen || u.LastMove != nil {
+			t.Errorf("Unexpected initial update %+v", u)
+		}
+	}
+
+	postJSON(t, server.URL+"/games/"+g.ID+"/move", `{"san": "e4"}`, nil)
+	for _, conn := range connections {
+		u := readUpdate(conn)
+		if u.ID != g.ID || u.ColorToMove || u.Status != "ongoing" || u.LastMove == nil || u.LastMove.UCI() != "e2e4" {
+			t.Errorf("Unexpected update after move %+v", u)
+		}
+	}
+
+	postJSON(t, server.URL+"/games/"+g.ID+"/undo-move", "", nil)
+	for _, conn := range connections {
+		if u := readUpdate(conn); u.Fen != game.StartingFen || u.LastMove != nil {
+			t.Errorf("Unexpected update after undo %+v", u)
+		}
+	}
+
+	request, _ := http.NewRequest(http.MethodDelete, server.URL+"/games/"+g.ID, nil)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	response.Body.Close()
+	connections[0].SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := connections[0].ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("Expected connection to close after delete, got %v", err)
+	}
+}
+
+func TestServerEvaluation(t *testing.T) {
+	server := httptest.NewServer(api.NewServer())
+	defer server.Close()
+
+	var created apiGame
+	postJSON(t, server.URL+"/new-game-from-fen", `{"fen": "4k3/8/8/8/8/8/8/Q3K3 b - - 0 1"}`, &created)
+
+	response, err := http.Get(server.URL + "/games/" + created.ID + "/evaluation")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer response.Body.Close()
+
+	var evaluation struct {
+		Material int `json:"material"`
+		Total    int `json:"total"`
+	}
+	json.NewDecoder(response.Body).Decode(&evaluation)
+	if response.StatusCode != http.StatusOK || evaluation.Material <= 0 || evaluation.Total <= 0 {
+		t.Errorf("Expected a positive evaluation for white, got %d %+v", response.StatusCode, evaluation)
+	}
+}
+
+type engineGame struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	Winner      string `json:"winner"`
+	ColorToMove bool   `json:"ColorToMove"`
+	Opponent    *struct {
+		EngineColor string `json:"engineColor"`
+		Level       int    `json:"level"`
+		MoveTimeMs  int    `json:"moveTimeMs"`
+	} `json:"opponent"`
+}
+
+func moveCount(t *testing.T, serverURL, id string) int {
+	t.Helper()
+	response, err := http.Get(serverURL + "/games")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer response.Body.Close()
+
+	var summaries []struct {
+		ID        string `json:"id"`
+		MoveCount int    `json:"moveCount"`
+	}
+	json.NewDecoder(response.Body).Decode(&summaries)
+	for _, summary := range summaries {
+		if summary.ID == id {
+			return summary.MoveCount
+		}
+	}
+	t.Fatalf("Game %s not listed", id)
+	return 0
+}
+
+func TestServerEngineReplies(t *testing.T) {
+	server := httptest.NewServer(api.NewServer())
+	defer server.Close()
+
+	var g engineGame
+	postJSON(t, server.URL+"/new-game", `{"opponent": "engine", "engineColor": "black", "level": 3, "moveTimeMs": 200}`, &g)
+	if g.Opponent == nil || g.Opponent.EngineColor != "black" || g.Opponent.Level != 3 || g.Opponent.MoveTimeMs != 200 {
+		t.Fatalf("Unexpected opponent %+v", g.Opponent)
+	}
+	if !g.ColorToMove {
+		t.Fatalf("Expected white to move first against an engine playing black")
+	}
+
+	var afterMove engineGame
+	response := postJSON(t, server.URL+"/games/"+g.ID+"/move", `{"san": "e4"}`, &afterMove)
+	if response.StatusCode != http.StatusOK || !afterMove.ColorToMove {
+		t.Fatalf("Expected the engine to reply, got %d with white to move %v", response.StatusCode, afterMove.ColorToMove)
+	}
+	if count := moveCount(t, server.URL, g.ID); count != 2 {
+		t.Errorf("Expected 2 moves after the engine replied, got %d", count)
+	}
+
+	var afterUndo engineGame
+	postJSON(t, server.URL+"/games/"+g.ID+"/undo-move", "", &afterUndo)
+	if count := moveCount(t, server.URL, g.ID); count != 0 || !afterUndo.ColorToMove {
+		t.Errorf("Expected undo to take back the reply and the move, got %d moves", count)
+	}
+}
+
+func TestServerEngineMovesFirst(t *testing.T) {
+	server := httptest.NewServer(api.NewServer())
+	defer server.Close()
+
+	var g engineGame
+	postJSON(t, server.URL+"/new-game", `{"opponent": "engine", "engineColor": "white", "level": 1, "moveTimeMs": 200}`, &g)
+	if g.ColorToMove || moveCount(t, server.URL, g.ID) != 1 {
+		t.Errorf("Expected the engine to play the first move")
+	}
+}
+
+func TestServerEngineFindsMate(t *testing.T) {
+	server := httptest.NewServer(api.NewServer())
+	defer server.Close()
+
+	start := time.Now()
+	var g engineGame
+	postJSON(t, server.URL+"/new-game-from-fen", `{"fen": "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "opponent": "engine", "engineColor": "white", "level": 8, "moveTimeMs": 500}`, &g)
+	if g.Status != "checkmate" || g.Winner != "white" {
+		t.Errorf("Expected the engine to mate, got %+v", g)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the engine to respect its move time, took %v", elapsed)
+	}
+}
+
+func TestServerReadsGameWhileEngineThinks(t *testing.T) {
+	server := httptest.NewServer(api.NewServer())
+	defer server.Close()
+
+	var g engineGame
+	postJSON(t, server.URL+"/new-game", `{"opponent": "engine", "engineColor": "black", "level": 8, "moveTimeMs": 3000}`, &g)
+	replied := make(chan engineGame)
+	go func() {
+		var afterMove engineGame
+		postJSON(t, server.URL+"/games/"+g.ID+"/move", `{"san": "e4"}`, &afterMove)
+		replied <- afterMove
+	}()
+
+	// The move is made before the engine starts searching
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	var thinking engineGame
+	getGame(t, server.URL, g.ID, &thinking)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the game to be readable while the engine thinks, took %v", elapsed)
+	}
+	if thinking.ColorToMove {
+		t.Errorf("Expected the engine to be still thinking with black to move")
+	}
+
+	if afterMove := <-replied; !afterMove.ColorToMove {
+		t.Errorf("Expected the engine to reply, got %+v", afterMove)
+	}
+}
+
+func TestServerEngineWinsVariants(t *testing.T) {
+	server := httptest.NewServer(api.NewServer())
+	defer server.Close()
+
+	tests := []struct {
+		variant     string
+		fen         string
+		engineColor string
+		status      string
+	}{
+		{"kingofthehill", "8/8/4k3/8/8/8/8/K7 b - - 0 1", "black", "king-on-hill"},
+		{"threecheck", "4k3/8/8/8/8/8/8/R3K3 w - - 1+3 0 1", "white", "third-check"},
+		{"atomic", "3qk3/8/8/8/8/8/8/3QK2r w - - 0 1", "white", "king-exploded"},
+	}
+	for _, test := range tests {
+		// The weakest level picks among moves close to the best one, a win
+		// must outscore all of them
+		var g engineGame
+		body := fmt.Sprintf(`{"fen": %q, "variant": %q, "opponent": "engine", "engineColor": %q, "level": 1, "moveTimeMs": 200}`, test.fen, test.variant, test.engineColor)
+		postJSON(t, server.URL+"/new-game-from-fen", body, &g)
+		if g.Status != test.status || g.Winner != test.engineColor {
+			t.Errorf("%s: expected the engine to win by %s, got %+v", test.variant, test.status, g)
+		}
+	}
+}
+
+func TestServerRejectsInvalidOpponent(t *testing.T) {
+	server := httptest.NewServer(api.NewServer())
+	defer server.Close()
+
+	for _, body := range []string{
+		`{"opponent": "robot"}`,
+		`{"opponent": "engine", "engineColor": "green"}`,
+		`{"opponent": "engine", "level": 20}`,
+		`{"opponent": "engine", "moveTimeMs": -5}`,
+		`{"opponent": `,
+	} {
+		response := postJSON(t, server.URL+"/new-game", body, nil)
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, response.StatusCode)
+		}
+	}
+}
+
+type timedGame struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Winner string `json:"winner"`
+	Result string `json:"result"`
+	Clock  *struct {
+		TimeControl string `json:"timeControl"`
+		WhiteMs     int64  `json:"whiteMs"`
+		BlackMs     int64  `json:"blackMs"`
+		Running     string `json:"running"`
+	} `json:"clock"`
+}
+
+func newConfiguredServer(t *testing.T, config api.Config) *httptest.Server {
+	t.Helper()
+	srv, err := api.NewServerWithConfig(config)
+	if err != nil {
+		t.Fatalf("Error creating server: %v", err)
+	}
+	return httptest.NewServer(srv)
+}
+
+func getGame(t *testing.T, serverURL, id string, out any) {
+	t.Helper()
+	response, err := http.Get(serverURL + "/games/" + id)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer response.Body.Close()
+	json.NewDecoder(response.Body).Decode(out)
+}
+
+func TestServerClocks(t *testing.T) {
+	source := newFakeClock()
+	server := newConfiguredServer(t, api.Config{Clock: source})
+	defer server.Close()
+
+	var g timedGame
+	postJSON(t, server.URL+"/new-game", `{"timeControl": "1+2"}`, &g)
+	if g.Clock == nil || g.Clock.TimeControl != "1+2" || g.Clock.WhiteMs != 60000 || g.Clock.Running != "" {
+		t.Fatalf("Unexpected clock %+v", g.Clock)
+	}
+
+	postJSON(t, server.URL+"/games/"+g.ID+"/move", `{"san": "e4"}`, &g)
+	source.Advance(10 * time.Second)
+	postJSON(t, server.URL+"/games/"+g.ID+"/move", `{"san": "e5"}`, &g)
+	if g.Clock.BlackMs != 52000 || g.Clock.WhiteMs != 60000 || g.Clock.Running != "white" {
+		t.Errorf("Unexpected clock after two moves %+v", g.Clock)
+	}
+
+	response := postJSON(t, server.URL+"/games/"+g.ID+"/undo-move", "", nil)
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for undo in a timed game, got %d", response.StatusCode)
+	}
+
+	source.Advance(time.Minute)
+	response = postJSON(t, server.URL+"/games/"+g.ID+"/move", `{"san": "Nf3"}`, nil)
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a move after the flag fell, got %d", response.StatusCode)
+	}
+	var final timedGame
+	getGame(t, server.URL, g.ID, &final)
+	if final.Status != "timeout" || final.Winner != "black" || final.Result != "0-1" || final.Clock.WhiteMs != 0 || final.Clock.Running != "" {
+		t.Errorf("Expected white to lose on time, got %+v %+v", final, final.Clock)
+	}
+
+	pgnResponse, err := http.Get(server.URL + "/games/" + g.ID + "/pgn")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer pgnResponse.Body.Close()
+	var text strings.Builder
+	io.Copy(&text, pgnResponse.Body)
+	if !strings.Contains(text.String(), `[Termination "time forfeit"]`) || !strings.Contains(text.String(), `[Result "0-1"]`) {
+		t.Errorf("Expected a time forfeit in the PGN, got %s", text.String())
+	}
+}
+
+func TestServerTimeoutVsInsufficientMaterial(t *testing.T) {
+	source := newFakeClock()
+	server := newConfiguredServer(t, api.Config{Clock: source})
+	defer server.Close()
+
+	tests := []struct {
+		moves  []string
+		status string
+		winner string
+	}{
+		// Black flags against a rook
+		{[]string{"Ra2"}, "timeout", "white"},
+		// White flags against a lone king
+		{[]string{"Ra2", "Kd7"}, "timeout-vs-insufficient-material", ""},
+	}
+
+	for _, test := range tests {
+		var g timedGame
+		postJSON(t, server.URL+"/new-game-from-fen", `{"fen": "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "timeControl": "1"}`, &g)
+		for _, san := range test.moves {
+			postJSON(t, server.URL+"/games/"+g.ID+"/move", `{"san": "`+san+`"}`, nil)
+		}
+		source.Advance(2 * time.Minute)
+
+		getGame(t, server.URL, g.ID, &g)
+		if g.Status != test.status || g.Winner != test.winner {
+			t.Errorf("%v: expected %s won by %q, got %s won by %q", test.moves, test.status, test.winner, g.Status, g.Winner)
+		}
+	}
+}
+
+func TestServerRejectsInvalidTimeControl(t *testing.T) {
+	server := httptest.NewServer(api.NewServer())
+	defer server.Close()
+
+	response := postJSON(t, server.URL+"/new-game", `{"timeControl": "5+x"}`, nil)
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid time control, got %d", response.StatusCode)
+	}
+}
+
+func TestServerRestoresStoredGames(t *testing.T) {
+	source := newFakeClock()
+	repository, err := storage.NewJSONRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	config := api.Config{Clock: source, Repository: repository}
+
+	server := newConfiguredServer(t, config)
+	var timed, deleted timedGame
+	var vsEngine engineGame
+	postJSON(t, server.URL+"/new-game", `{"timeControl": "1+2"}`, &timed)
+	postJSON(t, server.URL+"/games/"+timed.ID+"/move", `{"san": "e4"}`, nil)
+	source.Advance(10 * time.Second)
+	postJSON(t, server.URL+"/games/"+timed.ID+"/move", `{"san": "e5"}`, nil)
+	postJSON(t, server.URL+"/new-game", `{"opponent": "engine", "engineColor": "white", "level": 1, "moveTimeMs": 100}`, &vsEngine)
+	postJSON(t, server.URL+"/new-game", "", &deleted)
+	request, _ := http.NewRequest(http.MethodDelete, server.URL+"/games/"+deleted.ID, nil)
+	if response, err := http.DefaultClient.Do(request); err == nil {
+		response.Body.Close()
+	}
+
+	var before engineGame
+	getGame(t, server.URL, vsEngine.ID, &before)
+	server.Close()
+
+	// A new server with the same repository picks up where the old one stopped
+	restarted := newConfiguredServer(t, config)
+	defer restarted.Close()
+	if count := moveCount(t, restarted.URL, timed.ID); count != 2 {
+		t.Errorf("Expected 2 moves after restart, got %d", count)
+	}
+	var restored timedGame
+	getGame(t, restarted.URL, timed.ID, &restored)
+	if restored.Clock == nil || restored.Clock.BlackMs != 52000 || restored.Clock.Running != "white" {
+		t.Errorf("Expected the clock to be restored, got %+v", restored.Clock)
+	}
+
+	var restoredEngine engineGame
+	getGame(t, restarted.URL, vsEngine.ID, &restoredEngine)
+	if restoredEngine.Opponent == nil || restoredEngine.Opponent.EngineColor != "white" || restoredEngine.ColorToMove != before.ColorToMove {
+		t.Errorf("Expected the engine game to be restored, got %+v", restoredEngine)
+	}
+
+	response, err := http.Get(restarted.URL + "/games/" + deleted.ID)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected the deleted game to stay deleted, got %d", response.StatusCode)
+	}
+
+	// The clock kept running while the server was down
+	source.Advance(time.Minute)
+	getGame(t, restarted.URL, timed.ID, &restored)
+	if restored.Status != "timeout" {
+		t.Errorf("Expected white to lose on time after restart, got %s", restored.Status)
+	}
+	record, err := repository.Load(timed.ID)
+	if err != nil || record.Ended != "timeout" {
+		t.Errorf("Expected the timeout to be saved, got %q %v", record.Ended, err)
+	}
+}
+
+func TestServerSkipsBrokenStoredGames(t *testing.T) {
+	dir := t.TempDir()
+	repository, err := storage.NewJSONRepository(dir)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	records := []storage.Record{
+		{ID: "000000000000000a", StartFen: game.StartingFen, Moves: []string{"e2e4"}},
+		{ID: "000000000000000b", StartFen: game.StartingFen, Moves: []string{"e2e5"}},
+		{ID: "000000000000000c", StartFen: game.StartingFen, Variant: "Shogi"},
+	}
+	for _, record := range records {
+		if err := repository.Save(record); err != nil {
+			t.Fatalf("Error saving: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "000000000000000d.json"), []byte("{"), 0o644); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	server := newConfiguredServer(t, api.Config{Repository: repository})
+	defer server.Close()
+	if count := moveCount(t, server.URL, "000000000000000a"); count != 1 {
+		t.Errorf("Expected the valid game with 1 move, got %d", count)
+	}
+	for _, id := range []string{"000000000000000b", "000000000000000c", "000000000000000d"} {
+		response, err := http.Get(server.URL + "/games/" + id)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected broken game %s to be skipped, got %d", id, response.StatusCode)
+		}
+	}
+}
+
+func TestServerPGNDateIsCreationDate(t *testing.T) {
+	repository, err := storage.NewJSONRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	record := storage.Record{ID: "0123456789abcdef", Created: created, StartFen: game.StartingFen, Moves: []string{"e2e4"}}
+	if err := repository.Save(record); err != nil {
+		t.Fatalf("Error saving: %v", err)
+	}
+
+	server := newConfiguredServer(t, api.Config{Repository: repository})
+	defer server.Close()
+	response, err := http.Get(server.URL + "/games/" + record.ID + "/pgn")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer response.Body.Close()
+	var text strings.Builder
+	io.Copy(&text, response.Body)
+	if !strings.Contains(text.String(), `[Date "2024.05.01"]`) {
+		t.Errorf("Expected the date the game was created, got %s", text.String())
+	}
+}
+
+type variantGame struct {
+	ID      string `json:"id"`
+	Variant string `json:"variant"`
+	Board   []struct {
+		Type int `json:"type"`
+	} `json:"board"`
+}
+
+func TestServerChess960(t *testing.T) {
+	server := httptest.NewServer(api.NewServer())
+	defer server.Close()
+
+	var g variantGame
+	postJSON(t, server.URL+"/new-game", `{"variant": "chess960", "startPosition": 0}`, &g)
+	if g.Variant != "chess960" || len(g.Board) != 64 || g.Board[0].Type != game.Bishop|game.White || g.Board[6].Type != game.King|game.White {
+		t.Fatalf("Expected chess960 position 0, got %+v", g)
+	}
+
+	// Castling is sent as the king taking its rook
+	postJSON(t, server.URL+"/new-game-from-fen", `{"fen": "1r4kr/8/8/8/8/8/8/1R4KR w HBhb - 0 1"}`, &g)
+	postJSON(t, server.URL+"/games/"+g.ID+"/move", `{"startSquare": 6, "targetSquare": 1}`, &g)
+	if g.Board[2].Type != game.King|game.White || g.Board[3].Type != game.Rook|game.White || g.Board[1].Type != game.None {
+		t.Errorf("Expected the king on c1 and the rook on d1, got %+v", g.Board[:8])
+	}
+
+	for _, body := range []string{`{"variant": "shogi"}`, `{"variant": "chess960", "startPosition": 960}`, `{"startPosition": 3}`} {
+		response := postJSON(t, server.URL+"/new-game", body, nil)
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, response.StatusCode)
+		}
+	}
+}
+
+func TestServerVariants(t *testing.T) {
+	server := httptest.NewServer(api.NewServer())
+	defer server.Close()
+
+	var g variantGame
+	postJSON(t, server.URL+"/new-game", `{"variant": "horde"}`, &g)
+	if g.Variant != "horde" || g.Board[0].Type != game.Pawn|game.White || g.Board[60].Type != game.King|game.Black {
+		t.Fatalf("Expected the horde start position, got %+v", g)
+	}
+
+	var state struct {
+		Variant string `json:"variant"`
+		Status  string `json:"status"`
+		Checks  struct {
+			White int `json:"white"`
+			Black int `json:"black"`
+		} `json:"checks"`
+	}
+	postJSON(t, server.URL+"/new-game-from-fen", `{"fen": "4k3/8/8/8/8/8/8/R3K3 w - - 2+3 0 1", "variant": "threecheck"}`, &g)
+	postJSON(t, server.URL+"/games/"+g.ID+"/move", `{"startSquare": 0, "targetSquare": 56}`, &state)
+	if state.Variant != "threecheck" || state.Status != "ongoing" || state.Checks.White != 2 || state.Checks.Black != 0 {
+		t.Errorf("Expected the second check by white, got %+v", state)
+	}
+
+	var crazyhouse struct {
+		Board []struct {
+			Type int `json:"type"`
+		} `json:"board"`
+		Pockets struct {
+			White []int `json:"white"`
+			Black []int `json:"black"`
+		} `json:"pockets"`
+	}
+	postJSON(t, server.URL+"/new-game-from-fen", `{"fen": "4k3/8/8/8/8/8/8/4K3[Nq] w - - 0 1", "variant": "crazyhouse"}`, &g)
+	postJSON(t, server.URL+"/games/"+g.ID+"/move", `{"startSquare": 21, "targetSquare": 21, "flag": 9}`, &crazyhouse)
+	if crazyhouse.Board[21].Type != game.Knight|game.White || crazyhouse.Pockets.White[game.Knight] != 0 || crazyhouse.Pockets.Black[game.Queen] != 1 {
+		t.Errorf("Expected the knight dropped on f3, got %+v", crazyhouse)
+	}
+
+	// The horde is not a valid position of standard chess
+	fen := game.Horde.StartFen()
+	if response := postJSON(t, server.URL+"/new-game-from-fen", `{"fen": "`+fen+`"}`, nil); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", response.StatusCode)
+	}
+}
@@ -0,0 +1,170 @@
+package test
+
+import (
+	"slices"
+	"testing"
+	game "web-chess/backend/src"
+)
+
+// Legal moves found the slow way, by making each pseudo-legal move and
+// checking whether the opponent could then capture the king
+func legalMovesByMakeUnmake(g *game.Game) []game.Move {
+	legal := []game.Move{}
+	for _, move := range g.GeneratePseudoLegalMoves() {
+		g.MakeMove(move)
+		kingCaptured := false
+		for _, reply := range g.GeneratePseudoLegalMoves() {
+			if g.Board[reply.TargetSquare].Type&7 == game.King {
+				kingCaptured = true
+				break
+			}
+		}
+		g.UnmakeMove(move)
+		if !kingCaptured {
+			legal = append(legal, move)
+		}
+	}
+	return legal
+}
+
+func sameMoves(first, second []game.Move) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for _, move := range first {
+		if !slices.Contains(second, move) {
+			return false
+		}
+	}
+	return true
+}
+
+func verifyLegalTree(t *testing.T, g *game.Game, depth int) {
+	moves := g.GenerateLegalMoves()
+	if expected := legalMovesByMakeUnmake(g); !sameMoves(expected, moves) {
+		t.Fatalf("%s:%s", g.CurrentFen(), compareMovesErrorMessage(expected, moves))
+	}
+	if depth == 0 {
+		return
+	}
+	for _, move := range moves {
+		g.MakeMove(move)
+		verifyLegalTree(t, g, depth-1)
+		g.UnmakeMove(move)
+	}
+}
+
+func TestLegalMovesMatchMakeUnmake(t *testing.T) {
+	for _, fen := range perftFens {
+		verifyLegalTree(t, game.NewGameFromFen(fen), 2)
+	}
+}
+
+func TestCheckEvasions(t *testing.T) {
+	tests := []struct {
+		fen      string
+		expected []game.Move
+	}{
+		// Rook check: block with the knight or bishop, or step aside
+		{"4r1k1/8/8/8/8/2N5/3B4/4K3 w - - 0 1", []game.Move{
+			{StartSquare: 18, TargetSquare: 12},
+			{StartSquare: 18, TargetSquare: 28},
+			{StartSquare: 11, TargetSquare: 20},
+			{StartSquare: 4, TargetSquare: 3},
+			{StartSquare: 4, TargetSquare: 5},
+			{StartSquare: 4, TargetSquare: 13},
+		}},
+		// Capture the checking knight, or step aside
+		{"6k1/8/8/8/8/3n4/8/3RK3 w - - 0 1", []game.Move{
+			{StartSquare: 3, TargetSquare: 19},
+			{StartSquare: 4, TargetSquare: 5},
+			{StartSquare: 4, TargetSquare: 11},
+			{StartSquare: 4, TargetSquare: 12},
+		}},
+		// Double check, only the king can move
+		{"4r1k1/8/8/8/1b6/8/8/2B1K3 w - - 0 1", []game.Move{
+			{StartSquare: 4, TargetSquare: 3},
+			{StartSquare: 4, TargetSquare: 5},
+			{StartSquare: 4, TargetSquare: 13},
+		}},
+		// The king cannot step back along the line of the checking rook
+		{"6k1/8/8/8/8/8/8/r3K3 w - - 0 1", []game.Move{
+			{StartSquare: 4, TargetSquare: 11},
+			{StartSquare: 4, TargetSquare: 12},
+			{StartSquare: 4, TargetSquare: 13},
+		}},
+		// A double push blocks the check when a single push would not
+		{"6k1/8/8/8/r6K/8/1P6/8 w - - 0 1", []game.Move{
+			{StartSquare: 9, TargetSquare: 25, Flag: game.PawnTwoForward},
+			{StartSquare: 31, TargetSquare: 22},
+			{StartSquare: 31, TargetSquare: 23},
+			{StartSquare: 31, TargetSquare: 38},
+			{StartSquare: 31, TargetSquare: 39},
+		}},
+	}
+
+	for _, test := range tests {
+		g := game.NewGameFromFen(test.fen)
+		if !g.InCheck() {
+			t.Errorf("%s: expected check", test.fen)
+		}
+		if moves := g.GenerateLegalMoves(); !sameMoves(test.expected, moves) {
+			t.Errorf("%s:%s", test.fen, compareMovesErrorMessage(test.expected, moves))
+		}
+	}
+}
+
+func TestPinnedPieceMovesAlongPin(t *testing.T) {
+	g := game.NewGameFromFen("4k3/4r3/8/8/8/8/4R3/4K3 w - - 0 1")
+
+	expected := []game.Move{
+		{StartSquare: 12, TargetSquare: 20},
+		{StartSquare: 12, TargetSquare: 28},
+		{StartSquare: 12, TargetSquare: 36},
+		{StartSquare: 12, TargetSquare: 44},
+		{StartSquare: 12, TargetSquare: 52},
+	}
+	if moves := g.LegalMovesAtIndex(12); !sameMoves(expected, moves) {
+		t.Error(compareMovesErrorMessage(expected, moves))
+	}
+	if pinned := g.Pinned(); !slices.Equal(pinned, []int{12}) {
+		t.Errorf("Expected rook on e2 to be pinned, got %v", pinned)
+	}
+}
+
+func TestEnPassantDiscoveredCheck(t *testing.T) {
+	// Capturing en passant would take both pawns off the fifth rank and
+	// expose the king to the rook, although neither pawn is pinned on its own
+	g := game.NewGameFromFen("8/8/8/K2pP2r/8/8/8/7k w - d6 0 1")
+
+	for _, move := range g.GenerateLegalMoves() {
+		if move.Flag == game.EnPassantCapture {
+			t.Errorf("Expected no en passant capture, got %v", move)
+		}
+	}
+	if pinned := g.Pinned(); len(pinned) != 0 {
+		t.Errorf("Expected no pinned pieces, got %v", pinned)
+	}
+
+	// Capturing en passant removes the pawn that gives check
+	g = game.NewGameFromFen("8/8/8/2k5/3Pp3/8/8/4K3 b - d3 0 1")
+	if !g.InCheck() {
+		t.Fatal("Expected check")
+	}
+	expected := game.Move{StartSquare: 28, TargetSquare: 19, Flag: game.EnPassantCapture}
+	if !slices.Contains(g.GenerateLegalMoves(), expected) {
+		t.Errorf("Expected en passant capture out of check %v", expected)
+	}
+}
+
+func TestCheckers(t *testing.T) {
+	g := game.NewGameFromFen("4r1k1/8/8/8/1b6/8/8/2B1K3 w - - 0 1")
+	if checkers := g.Checkers(); !slices.Equal(checkers, []int{25, 60}) {
+		t.Errorf("Expected checkers on b4 and e8, got %v", checkers)
+	}
+
+	g = game.NewGame()
+	if g.InCheck() || len(g.Checkers()) != 0 || len(g.Pinned()) != 0 {
+		t.Errorf("Expected no check or pins in the starting position")
+	}
+}
@@ -0,0 +1,172 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	game "web-chess/backend/src"
+)
+
+func TestChess960StartFen(t *testing.T) {
+	fen, err := game.Chess960StartFen(game.Chess960StandardPosition)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if expected := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1"; fen != expected {
+		t.Error(compareFenStringErrorMessage(expected, fen))
+	}
+	if fen, _ := game.Chess960StartFen(0); !strings.HasPrefix(fen, "bbqnnrkr/") {
+		t.Errorf("Expected position 0 to be bbqnnrkr, got %s", fen)
+	}
+
+	seen := map[string]bool{}
+	for position := 0; position < game.Chess960Positions; position++ {
+		fen, err := game.Chess960StartFen(position)
+		if err != nil {
+			t.Fatalf("Position %d: %v", position, err)
+		}
+		backRank := strings.Split(fen, "/")[0]
+		bishops := strings.Index(backRank, "b") + strings.LastIndex(backRank, "b")
+		king := strings.Index(backRank, "k")
+		if bishops%2 == 0 || king < strings.Index(backRank, "r") || king > strings.LastIndex(backRank, "r") {
+			t.Errorf("Position %d has an invalid back rank %s", position, backRank)
+		}
+		if seen[backRank] {
+			t.Errorf("Position %d repeats %s", position, backRank)
+		}
+		seen[backRank] = true
+		if _, err := game.ParseFen(fen); err != nil {
+			t.Errorf("Position %d: %v", position, err)
+		}
+	}
+
+	for _, position := range []int{-1, game.Chess960Positions} {
+		if _, err := game.Chess960StartFen(position); err == nil {
+			t.Errorf("Expected an error for position %d", position)
+		}
+	}
+}
+
+func TestChess960CastlingRightsFen(t *testing.T) {
+	tests := []struct {
+		fen      string
+		expected string
+		chess960 bool
+	}{
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", false},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w HAha - 0 1", "r3k2r/8/8/8/8/8/8/R3K2R w HAha - 0 1", true},
+		// X-FEN names the outermost rooks with KQkq
+		{"rk5r/8/8/8/8/8/8/RK5R w KQkq - 0 1", "rk5r/8/8/8/8/8/8/RK5R w HAha - 0 1", true},
+		{"1r1k1r1r/8/8/8/8/8/8/1R1K1RR1 w KQk - 0 1", "1r1k1r1r/8/8/8/8/8/8/1R1K1RR1 w GBh - 0 1", true},
+		// and the inner rook with its file
+		{"1r1k1r1r/8/8/8/8/8/8/1R1K1RR1 w FBf - 0 1", "1r1k1r1r/8/8/8/8/8/8/1R1K1RR1 w FBf - 0 1", true},
+	}
+
+	for _, test := range tests {
+		g, err := game.ParseFen(test.fen)
+		if err != nil {
+			t.Errorf("%s: %v", test.fen, err)
+			continue
+		}
+		if g.CurrentFen() != test.expected {
+			t.Error(compareFenStringErrorMessage(test.expected, g.CurrentFen()))
+		}
+		if g.Chess960() != test.chess960 {
+			t.Errorf("%s: expected chess960 %v", test.fen, test.chess960)
+		}
+	}
+}
+
+func TestChess960Castling(t *testing.T) {
+	tests := []struct {
+		fen   string
+		uci   string
+		after string
+	}{
+		// The king stays where it is and only the rook moves
+		{"1r4kr/8/8/8/8/8/8/1R4KR w HBhb - 0 1", "g1h1", "1r4kr/8/8/8/8/8/8/1R3RK1 b hb - 1 1"},
+		{"1r4kr/8/8/8/8/8/8/1R4KR w HBhb - 0 1", "g1b1", "1r4kr/8/8/8/8/8/8/2KR3R b hb - 1 1"},
+		// The king and rook swap squares
+		{"4k3/8/8/8/8/8/8/5KR1 w G - 0 1", "f1g1", "4k3/8/8/8/8/8/8/5RK1 b - - 1 1"},
+		{"rk6/8/8/8/8/8/8/4K3 b a - 0 1", "b8a8", "2kr4/8/8/8/8/8/8/4K3 w - - 1 2"},
+	}
+
+	for _, test := range tests {
+		g := game.NewGameFromFen(test.fen)
+		move, err := game.ParseUCIMove(g, test.uci)
+		if err != nil {
+			t.Errorf("%s %s: %v", test.fen, test.uci, err)
+			continue
+		}
+		if move.Flag != game.Castling || move.UCI() != test.uci {
+			t.Errorf("%s: expected castling move %s, got %+v", test.fen, test.uci, move)
+		}
+		if err := g.Move(move); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if g.CurrentFen() != test.after {
+			t.Error(compareFenStringErrorMessage(test.after, g.CurrentFen()))
+		}
+		if err := g.VerifyHash(); err != nil {
+			t.Error(err)
+		}
+		g.UndoMove()
+		if g.CurrentFen() != test.fen {
+			t.Error(compareFenStringErrorMessage(test.fen, g.CurrentFen()))
+		}
+	}
+}
+
+func TestChess960CastlingLegality(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		uci  string
+	}{
+		// The rook shields the square the king stays on
+		{"rook shields king", "4k3/8/8/8/8/8/8/rRK5 w B - 0 1", "c1b1"},
+		// The rook has to pass the knight on its way to d1
+		{"rook path blocked", "4k3/8/8/8/8/8/8/RNK5 w A - 0 1", "c1a1"},
+		{"king path attacked", "4k3/8/8/8/8/8/5r2/1K5R w H - 0 1", "b1h1"},
+	}
+
+	for _, test := range tests {
+		g := game.NewGameFromFen(test.fen)
+		if move, err := game.ParseUCIMove(g, test.uci); err == nil {
+			t.Errorf("%s: expected castling to be illegal, got %+v", test.name, move)
+		}
+	}
+}
+
+func TestCastlingEncodings(t *testing.T) {
+	standard := game.NewGameFromFen("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	chess960 := game.NewGameFromFen("r3k2r/8/8/8/8/8/8/R3K2R w HAha - 0 1")
+
+	for _, uci := range []string{"e1g1", "e1h1"} {
+		if move, err := game.ParseUCIMove(standard, uci); err != nil || move != (game.Move{StartSquare: 4, TargetSquare: 6, Flag: game.Castling}) {
+			t.Errorf("Standard %s: got %+v %v", uci, move, err)
+		}
+		if move, err := game.ParseUCIMove(chess960, uci); err != nil || move != (game.Move{StartSquare: 4, TargetSquare: 7, Flag: game.Castling}) {
+			t.Errorf("Chess960 %s: got %+v %v", uci, move, err)
+		}
+	}
+
+	g, _ := game.NewChess960Game(game.Chess960StandardPosition)
+	if move, err := game.ParseSAN(g, "e4"); err == nil {
+		g.Move(move)
+	}
+	if err := g.SetChess960(false); err == nil {
+		t.Error("Expected an error changing the castling mode after a move")
+	}
+
+	g = game.NewGame()
+	if err := g.SetChess960(true); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !strings.Contains(g.InitialFen(), " HAha ") {
+		t.Errorf("Expected the initial fen to keep the mode, got %s", g.InitialFen())
+	}
+	if err := game.NewGameFromFen("rk5r/8/8/8/8/8/8/RK5R w HAha - 0 1").SetChess960(false); err == nil {
+		t.Error("Expected an error for castling rights that need chess960")
+	}
+}
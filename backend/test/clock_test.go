@@ -0,0 +1,191 @@
+package test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"web-chess/backend/clock"
+)
+
+// Time source that only moves when told to
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func newTestClock(t *testing.T, timeControl string) (*clock.Clock, *fakeClock) {
+	t.Helper()
+	control, err := clock.ParseTimeControl(timeControl)
+	if err != nil {
+		t.Fatalf("Error parsing %s: %v", timeControl, err)
+	}
+	source := newFakeClock()
+	return clock.New(control, source), source
+}
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected clock.TimeControl
+	}{
+		{"5+3", clock.TimeControl{{Time: 5 * time.Minute, BonusTime: 3 * time.Second}}},
+		{"3", clock.TimeControl{{Time: 3 * time.Minute}}},
+		{"0.5+1", clock.TimeControl{{Time: 30 * time.Second, BonusTime: time.Second}}},
+		{"15d5", clock.TimeControl{{Time: 15 * time.Minute, Bonus: clock.Delay, BonusTime: 5 * time.Second}}},
+		{"10b2", clock.TimeControl{{Time: 10 * time.Minute, Bonus: clock.Bronstein, BonusTime: 2 * time.Second}}},
+		{"40/90+30", clock.TimeControl{{Moves: 40, Time: 90 * time.Minute, BonusTime: 30 * time.Second}}},
+		{"40/90+30:30+30", clock.TimeControl{
+			{Moves: 40, Time: 90 * time.Minute, BonusTime: 30 * time.Second},
+			{Time: 30 * time.Minute, BonusTime: 30 * time.Second},
+		}},
+	}
+
+	for _, test := range tests {
+		control, err := clock.ParseTimeControl(test.input)
+		if err != nil {
+			t.Errorf("%s: error: %v", test.input, err)
+			continue
+		}
+		if len(control) != len(test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.input, test.expected, control)
+			continue
+		}
+		for i := range control {
+			if control[i] != test.expected[i] {
+				t.Errorf("%s: expected %+v, got %+v", test.input, test.expected, control)
+			}
+		}
+		if control.String() != test.input {
+			t.Errorf("Expected %s to format as itself, got %s", test.input, control.String())
+		}
+	}
+
+	for _, input := range []string{"", "+3", "5+", "-5+3", "5+-3", "0/5", "x/5", "5+3:40/90", "5 + 3", "5x3"} {
+		if _, err := clock.ParseTimeControl(input); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+func TestClockIncrement(t *testing.T) {
+	c, source := newTestClock(t, "1+2")
+
+	// The first move is not timed
+	source.Advance(30 * time.Second)
+	c.Punch(true)
+	if c.Remaining(true) != time.Minute {
+		t.Errorf("Expected the first move to be free, got %v", c.Remaining(true))
+	}
+
+	source.Advance(10 * time.Second)
+	if c.Remaining(false) != 50*time.Second {
+		t.Errorf("Expected black's clock to run, got %v", c.Remaining(false))
+	}
+	c.Punch(false)
+	if c.Remaining(false) != 52*time.Second {
+		t.Errorf("Expected 52s after the increment, got %v", c.Remaining(false))
+	}
+
+	source.Advance(time.Minute)
+	if !c.Expired() {
+		t.Errorf("Expected white's flag to fall")
+	}
+	c.Stop()
+	state := c.State()
+	if state.WhiteMs != 0 || state.BlackMs != 52000 || state.Running != "" || state.TimeControl != "1+2" {
+		t.Errorf("Unexpected state after stop %+v", state)
+	}
+}
+
+func TestClockDelay(t *testing.T) {
+	c, source := newTestClock(t, "1d5")
+	c.Punch(true)
+
+	source.Advance(3 * time.Second)
+	if c.Remaining(false) != time.Minute {
+		t.Errorf("Expected the clock to wait during the delay, got %v", c.Remaining(false))
+	}
+	source.Advance(5 * time.Second)
+	c.Punch(false)
+	if c.Remaining(false) != 57*time.Second {
+		t.Errorf("Expected 57s after moving 3s past the delay, got %v", c.Remaining(false))
+	}
+
+	source.Advance(64 * time.Second)
+	if c.Expired() {
+		t.Errorf("Expected white to have time left with the delay")
+	}
+	if untilExpiry, ok := c.UntilExpiry(); !ok || untilExpiry != time.Second {
+		t.Errorf("Expected the flag to fall in 1s, got %v %v", untilExpiry, ok)
+	}
+	source.Advance(time.Second)
+	if !c.Expired() {
+		t.Errorf("Expected white's flag to fall after the delay and the minute")
+	}
+}
+
+func TestClockBronstein(t *testing.T) {
+	c, source := newTestClock(t, "1b5")
+	c.Punch(true)
+
+	source.Advance(3 * time.Second)
+	c.Punch(false)
+	if c.Remaining(false) != time.Minute {
+		t.Errorf("Expected the used time to be given back, got %v", c.Remaining(false))
+	}
+
+	source.Advance(8 * time.Second)
+	c.Punch(true)
+	if c.Remaining(true) != 57*time.Second {
+		t.Errorf("Expected at most the delay to be given back, got %v", c.Remaining(true))
+	}
+}
+
+func TestClockPeriods(t *testing.T) {
+	c, source := newTestClock(t, "2/1:1/2:3")
+	white, black := true, false
+
+	c.Punch(white)
+	source.Advance(10 * time.Second)
+	c.Punch(black)
+	source.Advance(10 * time.Second)
+	c.Punch(white)
+	// White made the two moves of the first period
+	if c.Remaining(white) != 50*time.Second+2*time.Minute {
+		t.Errorf("Expected the second period to be added, got %v", c.Remaining(white))
+	}
+	if c.Remaining(black) != 50*time.Second {
+		t.Errorf("Expected black to still be in the first period, got %v", c.Remaining(black))
+	}
+
+	source.Advance(10 * time.Second)
+	c.Punch(black)
+	c.Punch(white)
+	if c.Remaining(white) != 50*time.Second+5*time.Minute {
+		t.Errorf("Expected the last period to be added, got %v", c.Remaining(white))
+	}
+
+	c, _ = newTestClock(t, "1/1")
+	c.Punch(white)
+	c.Punch(white)
+	if c.Remaining(white) != 3*time.Minute {
+		t.Errorf("Expected the last period to repeat, got %v", c.Remaining(white))
+	}
+}
@@ -0,0 +1,160 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	game "web-chess/backend/src"
+)
+
+func TestCrazyhouseFen(t *testing.T) {
+	tests := []struct {
+		fen      string
+		expected string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1"},
+		{"4k3/1Q~6/8/8/8/8/8/4K3[pQnN] w - - 0 1", "4k3/1Q~6/8/8/8/8/8/4K3[QNnp] w - - 0 1"},
+		// The pocket can also be given as a ninth rank
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR/Pp w KQkq - 0 1", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[Pp] w KQkq - 0 1"},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", "4k3/8/8/8/8/8/8/4K3[] w - - 0 1"},
+	}
+	for _, test := range tests {
+		g, err := game.ParseVariantFen(game.Crazyhouse, test.fen)
+		if err != nil {
+			t.Errorf("%s: %v", test.fen, err)
+			continue
+		}
+		if g.CurrentFen() != test.expected {
+			t.Error(compareFenStringErrorMessage(test.expected, g.CurrentFen()))
+		}
+	}
+
+	errorTests := map[string]error{
+		"4k3/8/8/8/8/8/8/4K3[K] w - - 0 1":   game.ErrFenPocket,
+		"4k3/8/8/8/8/8/8/4K3[Qx] w - - 0 1":  game.ErrFenPocket,
+		"4k3/8/8/8/8/8/8/4K3[Q w - - 0 1":    game.ErrFenPocket,
+		"4k3/1P~6/8/8/8/8/8/4K3[] w - - 0 1": game.ErrFenPromoted,
+	}
+	for fen, expected := range errorTests {
+		if _, err := game.ParseVariantFen(game.Crazyhouse, fen); !errors.Is(err, expected) {
+			t.Errorf("%s: expected %v, got %v", fen, expected, err)
+		}
+	}
+
+	a := game.NewVariantGameFromFen(game.Crazyhouse, "4k3/8/8/8/8/8/8/4K3[N] w - - 0 1")
+	b := game.NewVariantGameFromFen(game.Crazyhouse, "4k3/8/8/8/8/8/8/4K3[NN] w - - 0 1")
+	if a.Hash() == b.Hash() {
+		t.Error("Expected the pockets to change the hash")
+	}
+}
+
+func TestCrazyhouseDrops(t *testing.T) {
+	g := game.NewVariantGame(game.Crazyhouse)
+	playUCI(t, g, "e2e4", "d7d5", "e4d5", "d8d5")
+	fen := g.CurrentFen()
+	if expected := "rnb1kbnr/ppp1pppp/8/3q4/8/8/PPPP1PPP/RNBQKBNR[Pp] w KQkq - 0 3"; fen != expected {
+		t.Fatal(compareFenStringErrorMessage(expected, fen))
+	}
+
+	// A drop only needs the target square and the flag
+	if err := g.Move(game.Move{TargetSquare: 20, Flag: game.DropPawn}); err != nil {
+		t.Fatalf("Error dropping a pawn: %v", err)
+	}
+	move, err := game.ParseSAN(g, "@e6")
+	if err != nil {
+		t.Fatalf("Error parsing a pawn drop: %v", err)
+	}
+	if err := g.Move(move); err != nil {
+		t.Fatalf("Error dropping a pawn: %v", err)
+	}
+	if expected := "rnb1kbnr/ppp1pppp/4p3/3q4/8/4P3/PPPP1PPP/RNBQKBNR[] w KQkq - 0 4"; g.CurrentFen() != expected {
+		t.Error(compareFenStringErrorMessage(expected, g.CurrentFen()))
+	}
+	if pocket := g.Pocket(true); pocket != [game.Queen + 1]int{} {
+		t.Errorf("Expected an empty pocket, got %v", pocket)
+	}
+	if err := g.VerifyHash(); err != nil {
+		t.Error(err)
+	}
+
+	for range 2 {
+		if err := g.UndoMove(); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.VerifyHash(); err != nil {
+			t.Error(err)
+		}
+	}
+	if g.CurrentFen() != fen {
+		t.Error(compareFenStringErrorMessage(fen, g.CurrentFen()))
+	}
+	if pocket := g.Pocket(false); pocket[game.Pawn] != 1 {
+		t.Errorf("Expected a pawn in the black pocket, got %v", pocket)
+	}
+}
+
+func TestCrazyhousePromotedPieces(t *testing.T) {
+	fen := "1r2k3/P2n4/8/8/8/8/8/4K3[] w - - 0 1"
+	g := game.NewVariantGameFromFen(game.Crazyhouse, fen)
+	playUCI(t, g, "a7b8q")
+	promoted := g.CurrentFen()
+	if expected := "1Q~2k3/3n4/8/8/8/8/8/4K3[R] b - - 0 1"; promoted != expected {
+		t.Fatal(compareFenStringErrorMessage(expected, promoted))
+	}
+
+	// The queen goes back to the pocket as the pawn it was
+	playUCI(t, g, "d7b8")
+	if expected := "1n2k3/8/8/8/8/8/8/4K3[Rp] w - - 0 2"; g.CurrentFen() != expected {
+		t.Error(compareFenStringErrorMessage(expected, g.CurrentFen()))
+	}
+
+	g.UndoMove()
+	if g.CurrentFen() != promoted {
+		t.Error(compareFenStringErrorMessage(promoted, g.CurrentFen()))
+	}
+	g.UndoMove()
+	if g.CurrentFen() != fen {
+		t.Error(compareFenStringErrorMessage(fen, g.CurrentFen()))
+	}
+	if err := g.VerifyHash(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCrazyhouseDropLegality(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		uci   string
+		legal bool
+	}{
+		{"drop blocking a check", "k7/8/8/8/8/8/8/K6r[N] w - - 0 1", "N@d1", true},
+		{"drop not blocking a check", "k7/8/8/8/8/8/8/K6r[N] w - - 0 1", "N@d4", false},
+		{"pawn on the last rank", "4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", "P@a8", false},
+		{"pawn on the first rank", "4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", "P@a1", false},
+		{"pawn on the second rank", "4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", "P@a2", true},
+		{"piece not in the pocket", "4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", "Q@d4", false},
+		{"piece in the other pocket", "4k3/8/8/8/8/8/8/4K3[q] w - - 0 1", "Q@d4", false},
+		{"occupied square", "4k3/8/8/8/8/8/8/4K3[N] w - - 0 1", "N@e8", false},
+	}
+
+	for _, test := range tests {
+		g := game.NewVariantGameFromFen(game.Crazyhouse, test.fen)
+		if _, err := game.ParseUCIMove(g, test.uci); (err == nil) != test.legal {
+			t.Errorf("%s: expected legal %v, got %v", test.name, test.legal, err)
+		}
+	}
+
+	g := game.NewVariantGameFromFen(game.Crazyhouse, "k7/8/1K6/8/8/8/8/8[Q] w - - 0 1")
+	move, err := game.ParseUCIMove(g, "Q@b7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if san, _ := game.MoveToSAN(g, move); san != "Q@b7#" {
+		t.Errorf("Expected Q@b7#, got %s", san)
+	}
+	g.Move(move)
+	if g.Status() != game.Checkmate {
+		t.Error(compareStatusErrorMessage(game.Checkmate, g.Status()))
+	}
+}
@@ -0,0 +1,185 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"web-chess/backend/engine"
+	game "web-chess/backend/src"
+)
+
+func TestSearchLeavesGameUnchanged(t *testing.T) {
+	for _, fen := range perftFens {
+		g := game.NewGameFromFen(fen)
+		hash := g.Hash()
+
+		if _, err := engine.Search(g, engine.Limits{Depth: 3}); err != nil {
+			t.Fatalf("%s: error: %v", fen, err)
+		}
+		if g.CurrentFen() != fen {
+			t.Errorf("Expected fen %s after search, got %s", fen, g.CurrentFen())
+		}
+		if g.Hash() != hash || len(g.Moves()) != 0 {
+			t.Errorf("%s: search left moves or a different hash behind", fen)
+		}
+	}
+}
+
+func TestSearchFindsMate(t *testing.T) {
+	tests := []struct {
+		fen    string
+		mateIn int
+		move   string
+	}{
+		// Back rank mate
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 1, "a1a8"},
+		// Rook ladder, the second rook cuts off the seventh rank first
+		{"7k/8/8/8/8/8/R7/1R4K1 w - - 0 1", 2, "b1b7"},
+	}
+
+	for _, test := range tests {
+		result, err := engine.Search(game.NewGameFromFen(test.fen), engine.Limits{Depth: 6})
+		if err != nil {
+			t.Fatalf("%s: error: %v", test.fen, err)
+		}
+		if result.Move.UCI() != test.move {
+			t.Errorf("%s: expected %s, got %s", test.fen, test.move, result.Move.UCI())
+		}
+		if result.Score.MateIn() != test.mateIn {
+			t.Errorf("%s: expected mate in %d, got %v", test.fen, test.mateIn, result.Score)
+		}
+		if len(result.PV) != 2*test.mateIn-1 {
+			t.Errorf("%s: expected a principal variation of %d moves, got %d", test.fen, 2*test.mateIn-1, len(result.PV))
+		}
+	}
+}
+
+func TestSearchWinsMaterial(t *testing.T) {
+	g := game.NewGameFromFen("4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1")
+
+	result, err := engine.Search(g, engine.Limits{Depth: 2})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if result.Move.UCI() != "d2d5" {
+		t.Errorf("Expected d2d5, got %s", result.Move.UCI())
+	}
+	if result.Score.IsMate() || result.Score < 400 {
+		t.Errorf("Expected a score of at least a rook, got %v", result.Score)
+	}
+}
+
+func TestSearchPrincipalVariationIsLegal(t *testing.T) {
+	for _, fen := range perftFens {
+		g := game.NewGameFromFen(fen)
+		result, err := engine.Search(g, engine.Limits{Depth: 4})
+		if err != nil {
+			t.Fatalf("%s: error: %v", fen, err)
+		}
+		if len(result.PV) == 0 || result.PV[0] != result.Move {
+			t.Errorf("%s: expected the principal variation to start with %s, got %v", fen, result.Move.UCI(), result.PV)
+		}
+		for _, move := range result.PV {
+			if err := g.Move(move); err != nil {
+				t.Errorf("%s: illegal move %s in principal variation: %v", fen, move.UCI(), err)
+				break
+			}
+		}
+	}
+}
+
+func TestSearchNoLegalMoves(t *testing.T) {
+	for _, fen := range []string{
+		"R5k1/5ppp/8/8/8/8/8/6K1 b - - 0 1",
+		"7k/5Q2/6K1/8/8/8/8/8 b - - 0 1",
+	} {
+		_, err := engine.Search(game.NewGameFromFen(fen), engine.Limits{Depth: 2})
+		if !errors.Is(err, engine.ErrNoLegalMoves) {
+			t.Errorf("%s: expected ErrNoLegalMoves, got %v", fen, err)
+		}
+	}
+}
+
+func TestSearchLimits(t *testing.T) {
+	kiwipete := perftFens[1]
+
+	result, err := engine.Search(game.NewGameFromFen(kiwipete), engine.Limits{Depth: 3})
+	if err != nil || result.Depth != 3 {
+		t.Errorf("Expected depth 3, got %d (error %v)", result.Depth, err)
+	}
+
+	const nodes = 20000
+	result, err = engine.Search(game.NewGameFromFen(kiwipete), engine.Limits{Nodes: nodes})
+	if err != nil || result.Nodes > 2*nodes || result.Depth == 0 {
+		t.Errorf("Expected about %d nodes, got %d at depth %d (error %v)", nodes, result.Nodes, result.Depth, err)
+	}
+
+	start := time.Now()
+	result, err = engine.Search(game.NewGameFromFen(kiwipete), engine.Limits{Time: 50 * time.Millisecond})
+	if elapsed := time.Since(start); err != nil || elapsed > 500*time.Millisecond || result.Depth == 0 {
+		t.Errorf("Expected a search of about 50ms, took %v to depth %d (error %v)", elapsed, result.Depth, err)
+	}
+}
+
+func TestEngineStop(t *testing.T) {
+	e := engine.New()
+	time.AfterFunc(20*time.Millisecond, e.Stop)
+
+	start := time.Now()
+	result, err := e.Search(game.NewGameFromFen(perftFens[1]), engine.Limits{Time: time.Minute})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the search to stop, took %v", elapsed)
+	}
+	if result.Depth == 0 || len(result.PV) == 0 {
+		t.Errorf("Expected the result of a completed iteration, got depth %d", result.Depth)
+	}
+}
+
+func TestSearchWithThreads(t *testing.T) {
+	e := engine.New()
+	e.SetThreads(4)
+	iterations := 0
+	e.OnIteration = func(engine.Result) { iterations++ }
+
+	for _, fen := range perftFens {
+		g := game.NewGameFromFen(fen)
+		iterations = 0
+		result, err := e.Search(g, engine.Limits{Depth: 4})
+		if err != nil {
+			t.Fatalf("%s: error: %v", fen, err)
+		}
+		if g.CurrentFen() != fen {
+			t.Errorf("Expected fen %s after search, got %s", fen, g.CurrentFen())
+		}
+		if _, err := game.ParseUCIMove(g, result.Move.UCI()); err != nil {
+			t.Errorf("%s: best move %s is not legal", fen, result.Move.UCI())
+		}
+		if iterations != result.Depth {
+			t.Errorf("%s: expected %d iterations reported, got %d", fen, result.Depth, iterations)
+		}
+	}
+}
+
+func TestScoreString(t *testing.T) {
+	tests := []struct {
+		score    engine.Score
+		expected string
+	}{
+		{35, "cp 35"},
+		{-120, "cp -120"},
+		{engine.MateScore - 1, "mate 1"},
+		{engine.MateScore - 3, "mate 2"},
+		{-engine.MateScore + 2, "mate -1"},
+		{-engine.MateScore + 4, "mate -2"},
+	}
+
+	for _, test := range tests {
+		if test.score.String() != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, test.score.String())
+		}
+	}
+}
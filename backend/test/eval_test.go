@@ -0,0 +1,151 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+
+	"web-chess/backend/engine"
+	game "web-chess/backend/src"
+)
+
+// Flips the board vertically and swaps the colors, which gives the same
+// position for the other side
+func mirrorFen(fen string) string {
+	fields := strings.Fields(fen)
+
+	ranks := strings.Split(fields[0], "/")
+	for i, j := 0, len(ranks)-1; i < j; i, j = i+1, j-1 {
+		ranks[i], ranks[j] = ranks[j], ranks[i]
+	}
+	fields[0] = swapCase(strings.Join(ranks, "/"))
+
+	fields[1] = map[string]string{"w": "b", "b": "w"}[fields[1]]
+
+	if fields[2] != "-" {
+		castling := swapCase(fields[2])
+		upper := strings.Map(func(r rune) rune {
+			if unicode.IsUpper(r) {
+				return r
+			}
+			return -1
+		}, castling)
+		lower := strings.Map(func(r rune) rune {
+			if unicode.IsLower(r) {
+				return r
+			}
+			return -1
+		}, castling)
+		fields[2] = upper + lower
+	}
+
+	if fields[3] != "-" {
+		fields[3] = fields[3][:1] + map[byte]string{'3': "6", '6': "3"}[fields[3][1]]
+	}
+	return strings.Join(fields, " ")
+}
+
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
+
+var evalFens = append([]string{
+	"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+	"8/2p5/1p6/pP1P4/P7/2k5/6K1/8 b - - 0 40",
+	"2kr3r/ppp2ppp/2n5/3q4/3P4/2PB1N2/P4PPP/R2Q1RK1 w - - 0 15",
+	"6k1/5ppp/8/3PP3/8/8/5PPP/6K1 w - - 0 1",
+}, perftFens...)
+
+func TestEvaluateMirrorSymmetry(t *testing.T) {
+	for _, fen := range evalFens {
+		g := game.NewGameFromFen(fen)
+		mirrored := game.NewGameFromFen(mirrorFen(fen))
+
+		if engine.Evaluate(g) != engine.Evaluate(mirrored) {
+			t.Errorf("%s: evaluates to %d, mirrored %s to %d", fen, engine.Evaluate(g), mirrorFen(fen), engine.Evaluate(mirrored))
+		}
+
+		terms, mirroredTerms := engine.EvaluateTerms(g), engine.EvaluateTerms(mirrored)
+		negated := engine.Terms{
+			Material:      -mirroredTerms.Material,
+			PieceSquare:   -mirroredTerms.PieceSquare,
+			PawnStructure: -mirroredTerms.PawnStructure,
+			KingSafety:    -mirroredTerms.KingSafety,
+			Mobility:      -mirroredTerms.Mobility,
+			Phase:         mirroredTerms.Phase,
+		}
+		if terms != negated {
+			t.Errorf("%s: terms %+v are not the negated mirrored terms %+v", fen, terms, mirroredTerms)
+		}
+	}
+}
+
+func TestEvaluateSideToMove(t *testing.T) {
+	for _, fen := range evalFens {
+		g := game.NewGameFromFen(fen)
+		total := engine.EvaluateTerms(g).Total()
+		if g.ColorToMove && engine.Evaluate(g) != total || !g.ColorToMove && engine.Evaluate(g) != -total {
+			t.Errorf("%s: expected the terms to add up to the evaluation for the side to move", fen)
+		}
+	}
+}
+
+func TestEvaluateStartPosition(t *testing.T) {
+	terms := engine.EvaluateTerms(game.NewGame())
+	if terms.Total() != 0 || terms.Phase != 24 {
+		t.Errorf("Expected an even start position in the middlegame, got %+v", terms)
+	}
+}
+
+func TestEvaluateTerms(t *testing.T) {
+	tests := []struct {
+		name   string
+		better string
+		worse  string
+		term   func(engine.Terms) int
+	}{
+		{
+			"passed pawn",
+			"4k3/8/8/3P4/8/8/8/4K3 w - - 0 1",
+			"4k3/3p4/8/3P4/8/8/8/4K3 w - - 0 1",
+			func(t engine.Terms) int { return t.PawnStructure },
+		},
+		{
+			"doubled pawns",
+			"4k3/pp6/8/8/8/8/PP6/4K3 w - - 0 1",
+			"4k3/pp6/8/8/8/1P6/1P6/4K3 w - - 0 1",
+			func(t engine.Terms) int { return t.PawnStructure },
+		},
+		{
+			"isolated pawn",
+			"4k3/8/8/8/8/8/2PP4/4K3 w - - 0 1",
+			"4k3/8/8/8/8/8/2P1P3/4K3 w - - 0 1",
+			func(t engine.Terms) int { return t.PawnStructure },
+		},
+		{
+			"pawn shield",
+			"rq4k1/5ppp/8/8/8/8/5PPP/RQ4K1 w - - 0 1",
+			"rq4k1/5ppp/8/8/5PPP/8/8/RQ4K1 w - - 0 1",
+			func(t engine.Terms) int { return t.KingSafety },
+		},
+		{
+			"mobility",
+			"4k3/8/8/8/3B4/8/8/4K3 w - - 0 1",
+			"4k3/8/8/8/8/8/8/B3K3 w - - 0 1",
+			func(t engine.Terms) int { return t.Mobility },
+		},
+	}
+
+	for _, test := range tests {
+		better := test.term(engine.EvaluateTerms(game.NewGameFromFen(test.better)))
+		worse := test.term(engine.EvaluateTerms(game.NewGameFromFen(test.worse)))
+		if better <= worse {
+			t.Errorf("%s: expected %d for %s to be more than %d for %s", test.name, better, test.better, worse, test.worse)
+		}
+	}
+}
@@ -68,6 +68,23 @@ func TestFenAfterCastling(t *testing.T) {
 	}
 }
 
+func TestFenAfterRookTakesRook(t *testing.T) {
+	g := game.NewGameFromFen("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+
+	err := g.Move(game.Move{StartSquare: 0, TargetSquare: 56}) // Rxa8+
+
+	if err != nil {
+		t.Errorf("Error: %v", err)
+	}
+
+	// Both queenside rooks are gone, so neither side can castle queenside
+	expectedFenString := "R3k2r/8/8/8/8/8/8/4K2R b Kk - 0 1"
+
+	if g.CurrentFen() != expectedFenString {
+		t.Error(compareFenStringErrorMessage(expectedFenString, g.CurrentFen()))
+	}
+}
+
 func TestFenAfterEnPassant(t *testing.T) {
 	g := game.NewGame()
 
@@ -151,3 +168,19 @@ func TestFenAfterPromotionAgainAgain(t *testing.T) {
 		t.Error(compareFenStringErrorMessage(expectedFenString, g.CurrentFen()))
 	}
 }
+
+func TestFenClampsHalfmoveClock(t *testing.T) {
+	g := game.NewVariantGameFromFen(game.ThreeCheck, "4k3/8/8/8/8/8/8/R3K3 w - - 2+3 1000 1")
+	expected := "4k3/8/8/8/8/8/8/R3K3 w - - 2+3 150 1"
+	if g.CurrentFen() != expected {
+		t.Error(compareFenStringErrorMessage(expected, g.CurrentFen()))
+	}
+
+	// The counter must not run into the check counters next to it
+	move := g.GenerateLegalMoves()[0]
+	g.MakeMove(move)
+	g.UnmakeMove(move)
+	if g.CurrentFen() != expected {
+		t.Error(compareFenStringErrorMessage(expected, g.CurrentFen()))
+	}
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	game "web-chess/backend/src"
+)
+
+func TestParseFenValid(t *testing.T) {
+	for _, fen := range append(perftFens,
+		"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"4k3/8/8/8/8/8/8/4K2R w K - 0 1",
+	) {
+		g, err := game.ParseFen(fen)
+		if err != nil {
+			t.Errorf("%s: error: %v", fen, err)
+			continue
+		}
+		if g.CurrentFen() != fen {
+			t.Error(compareFenStringErrorMessage(fen, g.CurrentFen()))
+		}
+	}
+}
+
+func TestParseFenErrors(t *testing.T) {
+	tests := []struct {
+		fen      string
+		expected error
+	}{
+		{"", game.ErrFenFieldCount},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq", game.ErrFenFieldCount},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1", game.ErrFenRankCount},
+		{"r3k3/1P6/8/8/8/8/7/4K3 w - - 0 1", game.ErrFenRankLength},
+		{"4k3/8/8/8/8/8/1B7/4K3 w - - 0 1", game.ErrFenRankLength},
+		{"4k3/8/8/8/8/8/44/4K3 w - - 0 1", game.ErrFenRankLength},
+		{"4k3/8/8/8/8/8/8p/4K3 w - - 0 1", game.ErrFenRankLength},
+		{"4k3/8/8/8/8/8/8k/4K3 w - - 0 1", game.ErrFenRankLength},
+		{"4k3/8/8/8/8/8/7pp/4K3 w - - 0 1", game.ErrFenRankLength},
+		{"4k3/8/8/8/8/8/3X4/4K3 w - - 0 1", game.ErrFenPiece},
+		{"8/8/8/8/8/8/8/4K3 w - - 0 1", game.ErrFenKingCount},
+		{"4k3/8/8/8/8/8/8/3KK3 w - - 0 1", game.ErrFenKingCount},
+		{"4k2P/8/8/8/8/8/8/4K3 w - - 0 1", game.ErrFenPawnOnBackRank},
+		{"4k3/8/8/8/8/8/8/p3K3 w - - 0 1", game.ErrFenPawnOnBackRank},
+		{"4k3/8/8/8/8/8/8/4K3 x - - 0 1", game.ErrFenSideToMove},
+		{"4k2R/8/8/8/8/8/8/4K3 w - - 0 1", game.ErrFenOpponentInCheck},
+		{"4k3/8/8/8/8/8/8/4K2R w Q - 0 1", game.ErrFenCastlingRights},
+		{"4k3/8/8/8/8/8/8/R6K w K - 0 1", game.ErrFenCastlingRights},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w qkQK - 0 1", game.ErrFenCastlingRights},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KK - 0 1", game.ErrFenCastlingRights},
+		{"4k3/8/8/8/8/8/8/4K2R w G - 0 1", game.ErrFenCastlingRights},
+		{"4k3/8/8/8/8/8/8/R3K2R w AH - 0 1", game.ErrFenCastlingRights},
+		{"4k3/8/8/8/8/8/8/R3K2R w HX - 0 1", game.ErrFenCastlingRights},
+		{"4k3/8/8/8/8/8/8/4K3 w - e3 0 1", game.ErrFenEnPassant},
+		{"4k3/8/8/8/8/8/8/4K3 w - e6 0 1", game.ErrFenEnPassant},
+		{"4k3/8/8/8/8/8/8/4K3 w - z9 0 1", game.ErrFenEnPassant},
+		{"4k3/4p3/8/4p3/8/8/8/4K3 w - e6 0 1", game.ErrFenEnPassant},
+		{"4k3/8/8/8/8/8/8/4K3 w - - -1 1", game.ErrFenHalfmoveClock},
+		{"4k3/8/8/8/8/8/8/4K3 w - - x 1", game.ErrFenHalfmoveClock},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 151 1", game.ErrFenHalfmoveClock},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 1000 1", game.ErrFenHalfmoveClock},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 0", game.ErrFenFullmoveNumber},
+	}
+
+	for _, test := range tests {
+		_, err := game.ParseFen(test.fen)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.fen, test.expected, err)
+		}
+		var fenError *game.FenError
+		if err != nil && !errors.As(err, &fenError) {
+			t.Errorf("%q: expected FenError, got %T", test.fen, err)
+		}
+	}
+}
+
+func TestNewGameFromShortFen(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("Panic loading short fen: %v", r)
+		}
+	}()
+	game.NewGameFromFen("4k3/8/8/8/8/8/8/4K3 w")
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"slices"
+	"testing"
+	game "web-chess/backend/src"
+)
+
+func isCapture(g *game.Game, move game.Move) bool {
+	return move.Flag == game.EnPassantCapture || g.Board[move.TargetSquare].Type != game.None
+}
+
+func verifyPartitionTree(t *testing.T, g *game.Game, depth int) {
+	legal := g.GenerateLegalMoves()
+	captures := g.GenerateCaptures()
+	quiets := g.GenerateQuiets()
+
+	if len(captures)+len(quiets) != len(legal) {
+		t.Fatalf("%s: %d captures and %d quiets, but %d legal moves", g.CurrentFen(), len(captures), len(quiets), len(legal))
+	}
+	if !sameMoves(legal, append(captures, quiets...)) {
+		t.Fatalf("%s: captures and quiets do not make up the legal moves:%s", g.CurrentFen(), compareMovesErrorMessage(legal, append(captures, quiets...)))
+	}
+	for _, move := range captures {
+		if !isCapture(g, move) {
+			t.Fatalf("%s: %v is not a capture", g.CurrentFen(), move)
+		}
+	}
+	for _, move := range quiets {
+		if isCapture(g, move) {
+			t.Fatalf("%s: %v is not quiet", g.CurrentFen(), move)
+		}
+	}
+
+	if depth == 0 {
+		return
+	}
+	for _, move := range legal {
+		g.MakeMove(move)
+		verifyPartitionTree(t, g, depth-1)
+		g.UnmakeMove(move)
+	}
+}
+
+func TestCapturesAndQuietsPartitionLegalMoves(t *testing.T) {
+	for _, fen := range perftFens {
+		verifyPartitionTree(t, game.NewGameFromFen(fen), 2)
+	}
+}
+
+func TestGenerateCapturesIncludesSpecialCaptures(t *testing.T) {
+	// En passant and capture promotions are captures, the push promotion is quiet
+	g := game.NewGameFromFen("1n2k3/P7/8/3pP3/8/8/8/4K3 w - d6 0 1")
+
+	captures := g.GenerateCaptures()
+	expected := []game.Move{
+		{StartSquare: 36, TargetSquare: 43, Flag: game.EnPassantCapture},
+		{StartSquare: 48, TargetSquare: 57, Flag: game.PromoteToQueen},
+		{StartSquare: 48, TargetSquare: 57, Flag: game.PromoteToKnight},
+		{StartSquare: 48, TargetSquare: 57, Flag: game.PromoteToRook},
+		{StartSquare: 48, TargetSquare: 57, Flag: game.PromoteToBishop},
+	}
+	if !sameMoves(expected, captures) {
+		t.Error(compareMovesErrorMessage(expected, captures))
+	}
+
+	quiets := g.GenerateQuiets()
+	for _, flag := range []int{game.PromoteToQueen, game.PromoteToKnight, game.PromoteToRook, game.PromoteToBishop} {
+		move := game.Move{StartSquare: 48, TargetSquare: 56, Flag: flag}
+		if !slices.Contains(quiets, move) {
+			t.Errorf("Expected quiet promotion %v", move)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package test
+
+import (
+	"testing"
+	game "web-chess/backend/src"
+)
+
+func TestPackedMoveRoundTrip(t *testing.T) {
+	for _, fen := range perftFens {
+		g := game.NewGameFromFen(fen)
+		for _, move := range g.GenerateLegalMoves() {
+			if unpacked := move.Pack().Unpack(); unpacked != move {
+				t.Errorf("%s: expected %v after packing, got %v", fen, move, unpacked)
+			}
+		}
+	}
+
+	move := game.Move{StartSquare: 63, TargetSquare: 0, Flag: game.PawnTwoForward}
+	if packed := move.Pack(); packed != 63|0<<6|7<<12 {
+		t.Errorf("Unexpected packed value %016b", packed)
+	}
+}
+
+func TestGenerateLegalMovesIntoDoesNotAllocate(t *testing.T) {
+	var list game.MoveList
+	for _, fen := range perftFens {
+		g := game.NewGameFromFen(fen)
+		allocs := testing.AllocsPerRun(100, func() {
+			g.GenerateLegalMovesInto(&list)
+			for _, move := range list.Moves() {
+				g.MakeMove(move)
+				g.UnmakeMove(move)
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("%s: expected no allocations, got %v", fen, allocs)
+		}
+		if expected := g.GenerateLegalMoves(); !sameMoves(expected, list.Moves()) {
+			t.Errorf("%s:%s", fen, compareMovesErrorMessage(expected, list.Moves()))
+		}
+	}
+}
+
+func BenchmarkGenerateLegalMoves(b *testing.B) {
+	g := game.NewGameFromFen(perftFens[1])
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		g.GenerateLegalMoves()
+	}
+}
+
+func BenchmarkGenerateLegalMovesInto(b *testing.B) {
+	g := game.NewGameFromFen(perftFens[1])
+	var list game.MoveList
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		g.GenerateLegalMovesInto(&list)
+	}
+}
@@ -362,3 +362,23 @@ func TestPin(t *testing.T) {
 		t.Error(compareMovesErrorMessage(expectedMoves, knightMoves))
 	}
 }
+
+func TestMoveRejectsWrongFlag(t *testing.T) {
+	g := game.NewGame()
+	// A knight move sent as a promotion must not promote the knight
+	if err := g.Move(game.Move{StartSquare: 6, TargetSquare: 21, Flag: game.PromoteToQueen}); err == nil {
+		t.Errorf("Expected an error for a knight move with a promotion flag, got %s", g.CurrentFen())
+	}
+	for _, move := range []game.Move{{StartSquare: 100, TargetSquare: 21}, {StartSquare: 6, TargetSquare: -1}} {
+		if err := g.Move(move); err == nil {
+			t.Errorf("Expected an error for %v", move)
+		}
+	}
+
+	if err := g.Move(game.Move{StartSquare: 12, TargetSquare: 28}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if moves := g.Moves(); moves[0].Flag != game.PawnTwoForward {
+		t.Errorf("Expected the flag of the legal move to be filled in, got %v", moves[0])
+	}
+}
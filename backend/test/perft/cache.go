@@ -0,0 +1,80 @@
+package perft
+
+import (
+	"math/bits"
+	"sync/atomic"
+
+	game "web-chess/backend/src"
+)
+
+// Cache remembers the node counts of positions by hash and depth, so
+// transpositions in the move tree are only counted once. Like the search
+// table it can be shared by goroutines
+type Cache struct {
+	slots []cacheSlot
+	mask  uint64
+}
+
+// The key is stored xored with the count, so a torn write reads as a miss
+type cacheSlot struct {
+	check atomic.Uint64
+	nodes atomic.Uint64
+}
+
+const cacheSlotBytes = 16
+
+// NewCache creates a cache that uses at most sizeMB megabytes
+func NewCache(sizeMB int) *Cache {
+	count := uint64(max(sizeMB, 1)) << 20 / cacheSlotBytes
+	count = 1 << (bits.Len64(count) - 1)
+	return &Cache{slots: make([]cacheSlot, count), mask: count - 1}
+}
+
+// Mixes the depth into the hash, so counts of different depths do not match
+func cacheKey(hash uint64, depth int) uint64 {
+	return hash ^ uint64(depth)*0x9e3779b97f4a7c15
+}
+
+func (c *Cache) probe(key uint64) (uint64, bool) {
+	slot := &c.slots[key&c.mask]
+	nodes := slot.nodes.Load()
+	if nodes == 0 || slot.check.Load()^nodes != key {
+		return 0, false
+	}
+	return nodes, true
+}
+
+func (c *Cache) store(key, nodes uint64) {
+	slot := &c.slots[key&c.mask]
+	slot.check.Store(key ^ nodes)
+	slot.nodes.Store(nodes)
+}
+
+// PerftCached counts the same nodes as Perft, looking up and storing the
+// counts of inner nodes in the cache
+func PerftCached(g *game.Game, depth int, cache *Cache) uint64 {
+	return perftCached(g, depth, cache, make([]game.MoveList, depth))
+}
+
+func perftCached(g *game.Game, depth int, cache *Cache, lists []game.MoveList) uint64 {
+	moves := &lists[depth-1]
+	g.GenerateLegalMovesInto(moves)
+
+	if depth == 1 {
+		return uint64(moves.Len())
+	}
+	key := cacheKey(g.Hash(), depth)
+	if nodes, ok := cache.probe(key); ok {
+		return nodes
+	}
+
+	var numPositions uint64 = 0
+	for _, move := range moves.Moves() {
+		g.MakeMove(move)
+		numPositions += perftCached(g, depth-1, cache, lists)
+		g.UnmakeMove(move)
+	}
+
+	cache.store(key, numPositions)
+	return numPositions
+}
@@ -56,35 +56,110 @@ var actualResults = map[int]map[int]uint64{
 	},
 }
 
-func perft(g *game.Game, depth int) uint64 {
-	moves := g.GenerateLegalMoves()
+// Position is a position with its known perft results, Nodes[i] being the
+// count at depth i+1
+type Position struct {
+	Fen   string
+	Nodes []uint64
+}
+
+// Chess960Positions are the first positions of
+// https://www.chessprogramming.org/Chess960_Perft_Results
+var Chess960Positions = []Position{
+	{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []uint64{21, 528, 12189, 326672, 8146062}},
+	{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []uint64{21, 807, 18002, 667366, 16253601}},
+	{"b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []uint64{20, 479, 10471, 273318, 6417013}},
+	{"qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9", []uint64{22, 593, 13440, 382958, 9183776}},
+	{"1nbbnrkr/p1p1ppp1/3p4/1p3P1p/3Pq2P/8/PPP1P1P1/QNBBNRKR w HFhf - 0 9", []uint64{28, 1120, 31058, 1171749, 34030312}},
+	{"qnbnr1kr/ppp1b1pp/4p3/3p1p2/8/2NPP3/PPP1BPPP/QNB1R1KR w HEhe - 1 9", []uint64{29, 899, 26578, 824055, 24851983}},
+	{"q1bnrkr1/ppppp2p/2n2p2/4b1p1/2NP4/8/PPP1PPPP/QNB1RRKB w ge - 1 9", []uint64{30, 860, 24566, 732757, 21093346}},
+	{"qbn1brkr/ppp1p1p1/2n4p/3p1p2/P7/6PP/QPPPPP2/1BNNBRKR w HFhf - 0 9", []uint64{25, 635, 17054, 465806, 13203304}},
+	{"qnnbbrkr/1p2ppp1/2pp3p/p7/1P5P/2NP4/P1P1PPP1/Q1NBBRKR w HFhf - 0 9", []uint64{24, 572, 15243, 384260, 11110203}},
+	{"qn1rbbkr/ppp2p1p/1n1pp1p1/8/3P4/P6P/1PP1PPPK/QNNRBB1R w hd - 2 9", []uint64{28, 811, 23175, 679699, 19836606}},
+}
+
+// VariantPosition is a position of a variant with its known perft results.
+// Once the variant ends the game there are no moves left to count
+type VariantPosition struct {
+	Variant game.Variant
+	Position
+}
+
+// VariantPositions are from the variant perft suites of python-chess and
+// Fairy-Stockfish
+var VariantPositions = []VariantPosition{
+	{game.KingOfTheHill, Position{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []uint64{20, 400, 8902, 197281, 4865609}}},
+	// Kiwipete, neither king reaches the hill within four plies so the counts
+	// are the published ones of standard chess
+	{game.KingOfTheHill, Position{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []uint64{48, 2039, 97862, 4085603}}},
+	// The kings reach the hill within a few plies. Not from the suites, the
+	// counts are checked against standard perft stopped on the hill by
+	// TestPerftKingOfTheHillCutoff
+	{game.KingOfTheHill, Position{"8/2p5/8/8/2k5/8/4KP2/8 w - - 0 1", []uint64{8, 74, 414, 3452, 22694}}},
+	{game.KingOfTheHill, Position{"r3k2r/8/8/8/3p4/2n1K3/8/R6R w kq - 0 1", []uint64{6, 174, 4516, 132604, 3102640}}},
+	{game.ThreeCheck, Position{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 3+3 0 1", []uint64{20, 400, 8902, 197281, 4865609}}},
+	{game.ThreeCheck, Position{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 1+1 0 1", []uint64{48, 2039, 97848, 4081798}}},
+	{game.Atomic, Position{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []uint64{20, 400, 8902, 197326}}},
+	{game.Atomic, Position{"rn2kb1r/1pp1p2p/p2q1pp1/3P4/2P3b1/4PN2/PP3PPP/R2QKB1R b KQkq - 0 1", []uint64{40, 1238, 45237, 1434825}}},
+	{game.Atomic, Position{"rn1qkb1r/p5pp/2p5/3p4/N3P3/5P2/PPP4P/R1BQK3 w Qkq - 0 1", []uint64{28, 833, 23353, 714499}}},
+	{game.Atomic, Position{"8/8/8/8/8/8/2k5/rR4KR w KQ - 0 1", []uint64{18, 180, 4364, 61401}}},
+	{game.Atomic, Position{"r3k1rR/5K2/8/8/8/8/8/8 b kq - 0 1", []uint64{25, 282, 6753, 98729, 2587730}}},
+	{game.Atomic, Position{"Rr2k1rR/3K4/3p4/8/8/8/7P/8 w kq - 0 1", []uint64{21, 465, 10631, 241478, 5800275}}},
+	{game.Atomic, Position{"1R4kr/4K3/8/8/8/8/8/8 b k - 0 1", []uint64{4, 77, 1021, 17915}}},
+	{game.Horde, Position{"rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1", []uint64{8, 128, 1274, 23310, 265223}}},
+	{game.Horde, Position{"4k3/pp4q1/3P2p1/8/P3PP2/PPP2r2/PPP5/PPPP4 b - - 0 1", []uint64{30, 241, 6633, 56539}}},
+	{game.Horde, Position{"k7/5p2/4p2P/3p2P1/2p2P2/1p2P2P/p2P2P1/2P2P2 w - - 0 1", []uint64{13, 172, 2205, 33781}}},
+	{game.Crazyhouse, Position{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1", []uint64{20, 400, 8902, 197281, 4888832}}},
+	{game.Crazyhouse, Position{"r1bqk2r/pppp1ppp/2n1p3/4P3/1b1Pn3/2NB1N2/PPP2PPP/R1BQK2R[] b KQkq - 0 1", []uint64{42, 1347, 58057, 2083382}}},
+	{game.Crazyhouse, Position{"4k3/1Q~6/8/8/4b3/8/Kpp5/8[] b - - 0 1", []uint64{20, 360, 5445, 132758, 2455349}}},
+	{game.Crazyhouse, Position{"2k5/8/8/8/8/8/8/4K3[QRBNPqrbnp] w - - 0 1", []uint64{301, 75353}}},
+}
+
+// Perft counts the leaf nodes of the legal move tree to the given depth
+func Perft(g *game.Game, depth int) uint64 {
+	return perft(g, depth, make([]game.MoveList, depth))
+}
+
+// Move lists are large, one for each depth is allocated up front
+func perft(g *game.Game, depth int, lists []game.MoveList) uint64 {
+	moves := &lists[depth-1]
+	g.GenerateLegalMovesInto(moves)
 
 	if depth == 1 {
-		return uint64(len(moves))
+		return uint64(moves.Len())
 	}
 	var numPositions uint64 = 0
 
-	for _, move := range moves {
+	for _, move := range moves.Moves() {
 		g.MakeMove(move)
-		numPositions += perft(g, depth-1)
+		numPositions += perft(g, depth-1, lists)
 		g.UnmakeMove(move)
 	}
-
 	return numPositions
 }
 
-func RunPerftTest() {
-	RunPerft(1, 4)
-	RunPerft(2, 4)
-	RunPerft(3, 4)
-	RunPerft(4, 4)
-	RunPerft(5, 4)
-	RunPerft(6, 4)
+func RunPerftTest(cache *Cache) {
+	RunPerft(1, 4, cache)
+	RunPerft(2, 4, cache)
+	RunPerft(3, 4, cache)
+	RunPerft(4, 4, cache)
+	RunPerft(5, 4, cache)
+	RunPerft(6, 4, cache)
+}
+
+// Counts with the cache when there is one
+func count(g *game.Game, depth int, cache *Cache) uint64 {
+	if cache != nil {
+		return PerftCached(g, depth, cache)
+	}
+	return Perft(g, depth)
 }
 
 // https://www.chessprogramming.org/Perft_Results#Initial_Position
-func RunPerft(position, depth int) {
-	fen := getFENPosition(position)
+//
+// The cache is optional and may be nil
+func RunPerft(position, depth int, cache *Cache) {
+	fen := PositionFen(position)
 
 	fmt.Printf("Running perft with depth %d with position %d\n", depth, position)
 
@@ -96,7 +171,7 @@ func RunPerft(position, depth int) {
 	for _, d := range depths {
 		start := time.Now()
 		g := game.NewGameFromFen(fen)
-		numPositions := perft(g, d)
+		numPositions := count(g, d, cache)
 		fmt.Printf("Depth: %d, Result: %d, Time: %v", d, numPositions, time.Since(start))
 		actual := actualResults[position][d]
 		if numPositions != actual {
@@ -107,39 +182,28 @@ func RunPerft(position, depth int) {
 	}
 }
 
-func perftDivide(g *game.Game, startDepth, currentDepth int) (map[string]uint64, uint64) {
-	moves := g.GenerateLegalMoves()
-
-	if currentDepth == 1 {
-		return nil, uint64(len(moves))
-	}
+func perftDivide(g *game.Game, depth int, cache *Cache) (map[string]uint64, uint64) {
 	results := make(map[string]uint64)
-	numLocalNodes := uint64(0)
-
-	for _, move := range moves {
-		g.MakeMove(move)
-		_, numMovesForThisNode := perftDivide(g, startDepth, currentDepth-1)
-		numLocalNodes += numMovesForThisNode
-		g.UnmakeMove(move)
-
-		fromFile := move.StartSquare % 8
-		fromRank := move.StartSquare / 8
-		fromMoveString := string(rune(fromFile+'a')) + string(rune(fromRank+'1'))
-		toFile := move.TargetSquare % 8
-		toRank := move.TargetSquare / 8
-		toMoveString := string(rune(toFile+'a')) + string(rune(toRank+'1'))
-		if currentDepth == startDepth {
-			results[fromMoveString+toMoveString] += numMovesForThisNode
+	numNodes := uint64(0)
+
+	for _, move := range g.GenerateLegalMoves() {
+		numMovesForThisNode := uint64(1)
+		if depth > 1 {
+			g.MakeMove(move)
+			numMovesForThisNode = count(g, depth-1, cache)
+			g.UnmakeMove(move)
 		}
+		results[move.UCI()] = numMovesForThisNode
+		numNodes += numMovesForThisNode
 	}
-	return results, numLocalNodes
+	return results, numNodes
 }
 
-func RunPerftDivide(position, depth int) {
-	fen := getFENPosition(position)
+func RunPerftDivide(position, depth int, cache *Cache) {
+	fen := PositionFen(position)
 
 	g := game.NewGameFromFen(fen)
-	results, numNodes := perftDivide(g, depth, depth)
+	results, numNodes := perftDivide(g, depth, cache)
 
 	keys := make([]string, 0, len(results))
 	for key := range results {
@@ -153,7 +217,14 @@ func RunPerftDivide(position, depth int) {
 	fmt.Printf("Total nodes: %d\n", numNodes)
 }
 
-func getFENPosition(position int) string {
+// ExpectedNodes returns the known perft result for a numbered position
+func ExpectedNodes(position, depth int) (uint64, bool) {
+	nodes, ok := actualResults[position][depth]
+	return nodes, ok
+}
+
+// PositionFen returns the FEN of a numbered perft position
+func PositionFen(position int) string {
 	fen := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
 	if position == 2 {
 		fen = "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"
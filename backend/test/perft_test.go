@@ -0,0 +1,147 @@
+package test
+
+import (
+	"testing"
+	"time"
+	game "web-chess/backend/src"
+	"web-chess/backend/test/perft"
+)
+
+// Perft results above this are skipped to keep the test run short
+const perftNodeLimit = 5_000_000
+
+func TestPerft(t *testing.T) {
+	for position := 1; position <= 6; position++ {
+		fen := perft.PositionFen(position)
+		for depth := 1; ; depth++ {
+			expected, ok := perft.ExpectedNodes(position, depth)
+			if !ok || expected > perftNodeLimit || testing.Short() && expected > 100_000 {
+				break
+			}
+			g := game.NewGameFromFen(fen)
+			if nodes := perft.Perft(g, depth); nodes != expected {
+				t.Errorf("Position %d depth %d: expected %d nodes, got %d", position, depth, expected, nodes)
+			}
+			if g.CurrentFen() != fen {
+				t.Error(compareFenStringErrorMessage(fen, g.CurrentFen()))
+			}
+		}
+	}
+}
+
+func TestPerftChess960(t *testing.T) {
+	for i, position := range perft.Chess960Positions {
+		for depth, expected := range position.Nodes {
+			if expected > perftNodeLimit || testing.Short() && expected > 100_000 {
+				break
+			}
+			g := game.NewGameFromFen(position.Fen)
+			if nodes := perft.Perft(g, depth+1); nodes != expected {
+				t.Errorf("Chess960 position %d depth %d: expected %d nodes, got %d", i+1, depth+1, expected, nodes)
+			}
+			if g.CurrentFen() != position.Fen {
+				t.Error(compareFenStringErrorMessage(position.Fen, g.CurrentFen()))
+			}
+		}
+	}
+}
+
+func TestPerftVariants(t *testing.T) {
+	for _, position := range perft.VariantPositions {
+		name := position.Variant.Name()
+		for depth, expected := range position.Nodes {
+			if expected > perftNodeLimit || testing.Short() && expected > 100_000 {
+				break
+			}
+			g := game.NewVariantGameFromFen(position.Variant, position.Fen)
+			// Some castling rights are read as X-FEN and written as Shredder-FEN
+			fen := g.CurrentFen()
+			if nodes := perft.Perft(g, depth+1); nodes != expected {
+				t.Errorf("%s %s depth %d: expected %d nodes, got %d", name, position.Fen, depth+1, expected, nodes)
+			}
+			if g.CurrentFen() != fen {
+				t.Error(compareFenStringErrorMessage(fen, g.CurrentFen()))
+			}
+			if err := g.VerifyHash(); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
+// Counts the standard perft, ending the game when a king stands on the hill
+func hillPerft(g *game.Game, depth int) uint64 {
+	moves := g.GenerateLegalMoves()
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+	var nodes uint64
+	for _, move := range moves {
+		g.MakeMove(move)
+		onHill := false
+		for _, square := range []int{27, 28, 35, 36} {
+			onHill = onHill || g.Board[square].PieceType() == game.King
+		}
+		if !onHill {
+			nodes += hillPerft(g, depth-1)
+		}
+		g.UnmakeMove(move)
+	}
+	return nodes
+}
+
+func TestPerftKingOfTheHillCutoff(t *testing.T) {
+	for _, position := range perft.VariantPositions {
+		if position.Variant != game.KingOfTheHill {
+			continue
+		}
+		for depth, expected := range position.Nodes {
+			if expected > perftNodeLimit || testing.Short() && expected > 100_000 {
+				break
+			}
+			if nodes := hillPerft(game.NewGameFromFen(position.Fen), depth+1); nodes != expected {
+				t.Errorf("%s depth %d: expected %d nodes, got %d with standard rules", position.Fen, depth+1, expected, nodes)
+			}
+		}
+	}
+}
+
+func TestPerftCached(t *testing.T) {
+	// Small enough that different positions share slots
+	cache := perft.NewCache(1)
+	for position := 1; position <= 6; position++ {
+		fen := perft.PositionFen(position)
+		for depth := 1; depth <= 4; depth++ {
+			expected, _ := perft.ExpectedNodes(position, depth)
+			if testing.Short() && expected > 100_000 {
+				break
+			}
+			g := game.NewGameFromFen(fen)
+			if nodes := perft.PerftCached(g, depth, cache); nodes != expected {
+				t.Errorf("Position %d depth %d: expected %d nodes, got %d", position, depth, expected, nodes)
+			}
+			if g.CurrentFen() != fen {
+				t.Error(compareFenStringErrorMessage(fen, g.CurrentFen()))
+			}
+		}
+	}
+}
+
+func benchmarkPerft(b *testing.B, position, depth int) {
+	fen := perft.PositionFen(position)
+	nodes := uint64(0)
+	b.ReportAllocs()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		nodes += perft.Perft(game.NewGameFromFen(fen), depth)
+	}
+	b.ReportMetric(float64(nodes)/time.Since(start).Seconds(), "nodes/s")
+}
+
+func BenchmarkPerftStart(b *testing.B) {
+	benchmarkPerft(b, 1, 4)
+}
+
+func BenchmarkPerftKiwipete(b *testing.B) {
+	benchmarkPerft(b, 2, 3)
+}
@@ -0,0 +1,308 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"web-chess/backend/pgn"
+	game "web-chess/backend/src"
+)
+
+func playSAN(t *testing.T, g *game.Game, moves ...string) {
+	for _, san := range moves {
+		move, err := game.ParseSAN(g, san)
+		if err != nil {
+			t.Fatalf("Error parsing %s: %v", san, err)
+		}
+		if err := g.Move(move); err != nil {
+			t.Fatalf("Error playing %s: %v", san, err)
+		}
+	}
+}
+
+func TestExportPGN(t *testing.T) {
+	g := game.NewGame()
+	playSAN(t, g, "f3", "e5", "g4", "Qh4#")
+
+	text, err := pgn.Export(g, pgn.Tags{"Event": "Test", "White": "A", "Black": "B", "Annotator": "C"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	expected := `[Event "Test"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "A"]
+[Black "B"]
+[Result "0-1"]
+[Annotator "C"]
+
+1. f3 e5 2. g4 Qh4# 0-1
+`
+	if text != expected {
+		t.Errorf("\nExpected:\n%s\nGot:\n%s", expected, text)
+	}
+}
+
+func TestExportPGNFromFen(t *testing.T) {
+	fen := "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 3 20"
+	g := game.NewGameFromFen(fen)
+	playSAN(t, g, "O-O-O", "O-O")
+
+	text, err := pgn.Export(g, nil)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	for _, expected := range []string{
+		`[Result "*"]`,
+		`[SetUp "1"]`,
+		`[FEN "` + fen + `"]`,
+		"\n20... O-O-O 21. O-O *\n",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, text)
+		}
+	}
+}
+
+func TestExportPGNWrapsLines(t *testing.T) {
+	g := game.NewGame()
+	playSAN(t, g, "e4", "e5", "Nf3", "Nc6", "Bc4", "Bc5", "c3", "Nf6", "d3", "d6",
+		"O-O", "O-O", "Re1", "a6", "a4", "h6", "h3", "Re8", "Nbd2", "Be6", "Bxe6", "Rxe6",
+		"b4", "Ba7", "Nf1", "d5", "exd5", "Nxd5", "Ng3", "Qd7")
+
+	text, err := pgn.Export(g, nil)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if len(line) > 80 {
+			t.Errorf("Line longer than 80 characters: %q", line)
+		}
+	}
+}
+
+const multiGamePGN = `% exported by a test
+[Event "First"]
+[White "A \"the\" player"]
+[Result "1-0"]
+
+{Opening comment} 1. e4 e5 2. Nf3 $1 Nc6 (2... d6 {Philidor} 3. d4 (3. Bc4 Be7) exd4)
+(2... a6?! ; not on the main line
+) 3. Bb5!! a6 4. Ba4 1-0
+
+[Event "Second"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/8/R3K3 w Q - 0 30"]
+
+30. O-O-O Kf7 *
+`
+
+func TestParsePGN(t *testing.T) {
+	games, err := pgn.ParseString(multiGamePGN)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("Expected 2 games, got %d", len(games))
+	}
+
+	first := games[0]
+	if first.Tags["White"] != `A "the" player` {
+		t.Errorf("Expected escaped tag value, got %q", first.Tags["White"])
+	}
+	if first.Result != "1-0" {
+		t.Errorf("Expected result 1-0, got %s", first.Result)
+	}
+	if first.Comment != "Opening comment" {
+		t.Errorf("Expected opening comment, got %q", first.Comment)
+	}
+
+	sans := []string{}
+	for _, move := range first.Moves {
+		sans = append(sans, move.SAN)
+	}
+	if strings.Join(sans, " ") != "e4 e5 Nf3 Nc6 Bb5 a6 Ba4" {
+		t.Errorf("Unexpected mainline %v", sans)
+	}
+
+	nf3 := first.Moves[2]
+	if len(nf3.NAGs) != 1 || nf3.NAGs[0] != 1 {
+		t.Errorf("Expected NAG 1 on Nf3, got %v", nf3.NAGs)
+	}
+
+	nc6 := first.Moves[3]
+	if len(nc6.Variations) != 2 {
+		t.Fatalf("Expected 2 variations on Nc6, got %d", len(nc6.Variations))
+	}
+	philidor := nc6.Variations[0]
+	if len(philidor) != 3 || philidor[0].SAN != "d6" || philidor[0].Comments[0] != "Philidor" {
+		t.Errorf("Unexpected variation %+v", philidor)
+	}
+	if len(philidor[1].Variations) != 1 || philidor[1].Variations[0][0].SAN != "Bc4" {
+		t.Errorf("Expected nested variation on d4, got %+v", philidor[1].Variations)
+	}
+	second := nc6.Variations[1]
+	if len(second) != 1 || second[0].NAGs[0] != 6 || second[0].Comments[0] != "not on the main line" {
+		t.Errorf("Unexpected variation %+v", second)
+	}
+
+	bb5 := first.Moves[4]
+	if len(bb5.NAGs) != 1 || bb5.NAGs[0] != 3 {
+		t.Errorf("Expected NAG 3 on Bb5, got %v", bb5.NAGs)
+	}
+
+	g, err := first.Replay()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	expectedFen := "r1bqkbnr/1ppp1ppp/p1n5/4p3/B3P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 1 4"
+	if g.CurrentFen() != expectedFen {
+		t.Error(compareFenStringErrorMessage(expectedFen, g.CurrentFen()))
+	}
+
+	g, err = games[1].Replay()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	expectedFen = "8/5k2/8/8/8/8/8/2KR4 w - - 2 31"
+	if g.CurrentFen() != expectedFen {
+		t.Error(compareFenStringErrorMessage(expectedFen, g.CurrentFen()))
+	}
+}
+
+func TestReplayPGNIllegalMove(t *testing.T) {
+	games, err := pgn.ParseString("1. e4 e5 2. Ke3 *")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	_, err = games[0].Replay()
+	var moveError *pgn.MoveError
+	if !errors.As(err, &moveError) {
+		t.Fatalf("Expected MoveError, got %v", err)
+	}
+	if moveError.Ply != 3 || moveError.SAN != "Ke3" {
+		t.Errorf("Expected error at ply 3 (Ke3), got %v", moveError)
+	}
+}
+
+func TestParsePGNSyntaxErrors(t *testing.T) {
+	for _, text := range []string{
+		"1. e4 (e5",
+		"1. e4 ) *",
+		"1. e4 {unterminated",
+		"[Event \"x] 1. e4 *",
+		"( 1. e4 ) *",
+	} {
+		if _, err := pgn.ParseString(text); err == nil {
+			t.Errorf("Expected error parsing %q", text)
+		}
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	g := game.NewGame()
+	playSAN(t, g, "d4", "d5", "c4", "dxc4", "e4", "b5", "a4", "c6", "axb5", "cxb5", "Qf3")
+
+	text, err := pgn.Export(g, nil)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	games, err := pgn.ParseString(text)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	replayed, err := games[0].Replay()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if replayed.CurrentFen() != g.CurrentFen() {
+		t.Error(compareFenStringErrorMessage(g.CurrentFen(), replayed.CurrentFen()))
+	}
+}
+
+func TestChess960PGNRoundTrip(t *testing.T) {
+	g, err := game.NewChess960Game(game.Chess960StandardPosition)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	playSAN(t, g, "e4", "e5", "Nf3", "Nc6", "Bc4", "Bc5", "O-O")
+
+	text, err := pgn.Export(g, nil)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	for _, expected := range []string{`[Variant "Chess960"]`, `[FEN "` + g.InitialFen() + `"]`, "4. O-O *"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, text)
+		}
+	}
+
+	// Other tools write the standard position in X-FEN
+	text = strings.ReplaceAll(text, "HAha", "KQkq")
+	games, err := pgn.ParseString(text)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	replayed, err := games[0].Replay()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !replayed.Chess960() || replayed.CurrentFen() != g.CurrentFen() {
+		t.Error(compareFenStringErrorMessage(g.CurrentFen(), replayed.CurrentFen()))
+	}
+	if moves := replayed.Moves(); moves[len(moves)-1].UCI() != "e1h1" {
+		t.Errorf("Expected castling as e1h1, got %s", moves[len(moves)-1].UCI())
+	}
+}
+
+func TestVariantPGNRoundTrip(t *testing.T) {
+	tests := []struct {
+		variant game.Variant
+		moves   []string
+	}{
+		// The explosion is the last move
+		{game.Atomic, []string{"Nf3", "d5", "Ng5", "e6", "Nxh7"}},
+		// The queen moves to the square the pawns exploded on
+		{game.Atomic, []string{"e4", "d5", "exd5", "Qd5"}},
+		{game.ThreeCheck, []string{"e4", "e5", "Bc4", "Nc6", "Bxf7+"}},
+		{game.Crazyhouse, []string{"e4", "d5", "exd5", "Qxd5", "P@e4"}},
+	}
+
+	for _, test := range tests {
+		name := test.variant.Name()
+		g := game.NewVariantGame(test.variant)
+		playSAN(t, g, test.moves...)
+
+		text, err := pgn.Export(g, nil)
+		if err != nil {
+			t.Errorf("%s: error: %v", name, err)
+			continue
+		}
+		if !strings.Contains(text, `[Variant "`+name+`"]`) || strings.Contains(text, "[FEN ") {
+			t.Errorf("Expected a Variant tag and no FEN in:\n%s", text)
+		}
+		tokens := " " + strings.Join(strings.Fields(text), " ") + " "
+		for _, san := range test.moves {
+			if !strings.Contains(tokens, " "+san+" ") {
+				t.Errorf("%s: expected %s in:\n%s", name, san, text)
+			}
+		}
+
+		games, err := pgn.ParseString(text)
+		if err != nil {
+			t.Fatalf("%s: error: %v", name, err)
+		}
+		replayed, err := games[0].Replay()
+		if err != nil {
+			t.Fatalf("%s: error: %v", name, err)
+		}
+		if replayed.Variant() != test.variant || replayed.CurrentFen() != g.CurrentFen() {
+			t.Error(compareFenStringErrorMessage(g.CurrentFen(), replayed.CurrentFen()))
+		}
+	}
+}
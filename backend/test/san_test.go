@@ -0,0 +1,95 @@
+package test
+
+import (
+	"testing"
+	game "web-chess/backend/src"
+)
+
+func TestMoveToSAN(t *testing.T) {
+	tests := []struct {
+		fen      string
+		move     game.Move
+		expected string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", game.Move{StartSquare: 12, TargetSquare: 28}, "e4"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", game.Move{StartSquare: 6, TargetSquare: 21}, "Nf3"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", game.Move{StartSquare: 4, TargetSquare: 6}, "O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", game.Move{StartSquare: 60, TargetSquare: 58}, "O-O-O"},
+		{"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4", game.Move{StartSquare: 39, TargetSquare: 53}, "Qxf7#"},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", game.Move{StartSquare: 0, TargetSquare: 3}, "Rad1"},
+		{"4k3/8/8/8/8/R7/8/R3K3 w - - 0 1", game.Move{StartSquare: 16, TargetSquare: 8}, "R3a2"},
+		{"k7/8/8/8/8/2Q1Q3/8/2Q1K3 w - - 0 1", game.Move{StartSquare: 18, TargetSquare: 11}, "Qc3d2"},
+		{"rnbqkbnr/pppp1ppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1", game.Move{StartSquare: 62, TargetSquare: 52}, "Ne7"},
+		{"r1bqkbnr/pppp1ppp/2n5/8/8/5N2/PPPPPPPP/RNBQKB1R b KQkq - 0 1", game.Move{StartSquare: 62, TargetSquare: 52}, "Nge7"},
+		{"7k/3P4/8/8/8/8/8/4K3 w - - 0 1", game.Move{StartSquare: 51, TargetSquare: 59, Flag: game.PromoteToQueen}, "d8=Q+"},
+		{"2r1k3/1P6/8/8/8/8/8/4K3 w - - 0 1", game.Move{StartSquare: 49, TargetSquare: 58, Flag: game.PromoteToKnight}, "bxc8=N"},
+		{"4k3/8/8/3Pp3/8/8/8/4K3 w - e6 0 1", game.Move{StartSquare: 35, TargetSquare: 44}, "dxe6"},
+	}
+
+	for _, test := range tests {
+		g := game.NewGameFromFen(test.fen)
+		san, err := game.MoveToSAN(g, test.move)
+		if err != nil {
+			t.Errorf("%s: error: %v", test.fen, err)
+			continue
+		}
+		if san != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.fen, test.expected, san)
+		}
+
+		parsed, err := game.ParseSAN(g, test.expected)
+		if err != nil {
+			t.Errorf("%s: error parsing %s: %v", test.fen, test.expected, err)
+			continue
+		}
+		if !movesEqualIgnoreFlag(parsed, test.move) || test.move.Flag != game.NoFlag && parsed.Flag != test.move.Flag {
+			t.Errorf("%s: expected %v from %s, got %v", test.fen, test.move, test.expected, parsed)
+		}
+	}
+}
+
+func TestParseSANErrors(t *testing.T) {
+	g := game.NewGameFromFen("k7/8/8/8/8/2Q1Q3/8/2Q1K3 w - - 0 1")
+
+	for _, san := range []string{"", "Qd2", "e4", "Qz9", "Nd2", "O-O"} {
+		if _, err := game.ParseSAN(g, san); err == nil {
+			t.Errorf("Expected error parsing %q", san)
+		}
+	}
+}
+
+func TestParseSANLenientForms(t *testing.T) {
+	g := game.NewGameFromFen("7k/3P4/8/8/8/8/8/4K3 w - - 0 1")
+
+	for _, san := range []string{"d8Q", "d8=Q", "d8=Q+", "d7d8=Q"} {
+		move, err := game.ParseSAN(g, san)
+		if err != nil {
+			t.Errorf("Error parsing %q: %v", san, err)
+			continue
+		}
+		if move.Flag != game.PromoteToQueen {
+			t.Errorf("Expected queen promotion from %q, got flag %d", san, move.Flag)
+		}
+	}
+}
+
+func TestSANRoundTripPerftPositions(t *testing.T) {
+	for _, fen := range perftFens {
+		g := game.NewGameFromFen(fen)
+		for _, move := range g.GenerateLegalMoves() {
+			san, err := game.MoveToSAN(g, move)
+			if err != nil {
+				t.Errorf("%s: error: %v", fen, err)
+				continue
+			}
+			parsed, err := game.ParseSAN(g, san)
+			if err != nil {
+				t.Errorf("%s: error parsing %s: %v", fen, san, err)
+				continue
+			}
+			if parsed != move {
+				t.Errorf("%s: expected %v from %s, got %v", fen, move, san, parsed)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"testing"
+
+	game "web-chess/backend/src"
+)
+
+func TestSEE(t *testing.T) {
+	tests := []struct {
+		fen      string
+		uci      string
+		expected int
+	}{
+		// Undefended pawn
+		{"1k1r4/1pp4p/p7/4p3/8/P5P1/1PP4P/2K1R3 w - - 0 1", "e1e5", 100},
+		// Both sides pile up on e5, with the queens x-raying through their
+		// own pieces
+		{"1k1r3q/1ppn3p/p4b2/4p3/8/P2N2P1/1PP1R1BP/2K1Q3 w - - 0 1", "d3e5", -220},
+		// Pawn trade
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "e4d5", 100},
+		// Queen takes a pawn defended by a pawn
+		{"4k3/8/2p5/3p4/8/8/8/3QK3 w - - 0 1", "d1d5", -800},
+		// The king cannot recapture while the second rook covers the square
+		{"8/8/4k3/3p4/8/8/3R4/3RK3 w - - 0 1", "d2d5", 100},
+		// With one rook it can
+		{"8/8/4k3/3p4/8/8/3R4/4K3 w - - 0 1", "d2d5", -400},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", 100},
+		// Promoting with capture wins the rook and the difference between
+		// queen and pawn
+		{"1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7b8q", 500 + 800},
+	}
+
+	for _, test := range tests {
+		g := game.NewGameFromFen(test.fen)
+		move, err := game.ParseUCIMove(g, test.uci)
+		if err != nil {
+			t.Fatalf("%s: error parsing %s: %v", test.fen, test.uci, err)
+		}
+		if see := g.SEE(move); see != test.expected {
+			t.Errorf("%s: expected SEE %d for %s, got %d", test.fen, test.expected, test.uci, see)
+		}
+	}
+}
+
+func TestNullMove(t *testing.T) {
+	for _, fen := range append(perftFens, "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1") {
+		g := game.NewGameFromFen(fen)
+		hash := g.Hash()
+
+		g.MakeNullMove()
+		if err := g.VerifyHash(); err != nil {
+			t.Errorf("%s: hash after null move: %v", fen, err)
+		}
+		if g.ColorToMove == game.NewGameFromFen(fen).ColorToMove {
+			t.Errorf("%s: expected the other side to move", fen)
+		}
+		for _, move := range g.GenerateLegalMoves() {
+			if move.Flag == game.EnPassantCapture {
+				t.Errorf("%s: expected no en passant capture after a null move", fen)
+			}
+		}
+
+		g.UnmakeNullMove()
+		if g.CurrentFen() != fen || g.Hash() != hash {
+			t.Error(compareFenStringErrorMessage(fen, g.CurrentFen()))
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package test
+
+import (
+	"testing"
+	game "web-chess/backend/src"
+)
+
+func compareStatusErrorMessage(expected, got game.Status) string {
+	return "Expected:\n" + expected.String() + "\nGot:\n" + got.String()
+}
+
+func TestStatusNewGame(t *testing.T) {
+	g := game.NewGame()
+
+	if g.Status() != game.Ongoing {
+		t.Error(compareStatusErrorMessage(game.Ongoing, g.Status()))
+	}
+	if g.Result() != "*" {
+		t.Errorf("Expected result *, got %s", g.Result())
+	}
+}
+
+func TestStatusFoolsMate(t *testing.T) {
+	g := game.NewGame()
+
+	g.Move(game.Move{StartSquare: 13, TargetSquare: 21}) // f3
+	g.Move(game.Move{StartSquare: 52, TargetSquare: 36}) // e5
+	g.Move(game.Move{StartSquare: 14, TargetSquare: 30}) // g4
+	g.Move(game.Move{StartSquare: 59, TargetSquare: 31}) // Qh4#
+
+	if g.Status() != game.Checkmate {
+		t.Error(compareStatusErrorMessage(game.Checkmate, g.Status()))
+	}
+	if g.Winner() != game.Black {
+		t.Errorf("Expected winner %d, got %d", game.Black, g.Winner())
+	}
+	if g.Result() != "0-1" {
+		t.Errorf("Expected result 0-1, got %s", g.Result())
+	}
+}
+
+func TestStatusStalemate(t *testing.T) {
+	g := game.NewGameFromFen("7k/5Q2/6K1/8/8/8/8/8 b - - 0 1")
+
+	if g.Status() != game.Stalemate {
+		t.Error(compareStatusErrorMessage(game.Stalemate, g.Status()))
+	}
+	if g.Result() != "1/2-1/2" {
+		t.Errorf("Expected result 1/2-1/2, got %s", g.Result())
+	}
+}
+
+func TestStatusFiftyMoveRule(t *testing.T) {
+	g := game.NewGameFromFen("4k3/8/8/8/8/8/8/R3K3 w - - 99 80")
+
+	if g.CanClaimDraw() {
+		t.Error("Expected no draw claim before the hundredth half move")
+	}
+
+	g.Move(game.Move{StartSquare: 0, TargetSquare: 8}) // Ra2
+
+	if g.Status() != game.Ongoing {
+		t.Error(compareStatusErrorMessage(game.Ongoing, g.Status()))
+	}
+	if err := g.ClaimDraw(); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if g.Status() != game.FiftyMoveRule {
+		t.Error(compareStatusErrorMessage(game.FiftyMoveRule, g.Status()))
+	}
+
+	g.UnmakeMove(game.Move{StartSquare: 0, TargetSquare: 8})
+
+	if g.Status() != game.Ongoing {
+		t.Error(compareStatusErrorMessage(game.Ongoing, g.Status()))
+	}
+}
+
+func TestStatusSeventyFiveMoveRule(t *testing.T) {
+	g := game.NewGameFromFen("4k3/8/8/8/8/8/8/R3K3 w - - 149 80")
+
+	g.Move(game.Move{StartSquare: 0, TargetSquare: 8}) // Ra2
+
+	if g.Status() != game.SeventyFiveMoveRule {
+		t.Error(compareStatusErrorMessage(game.SeventyFiveMoveRule, g.Status()))
+	}
+
+	err := g.Move(game.Move{StartSquare: 60, TargetSquare: 59})
+	if err == nil {
+		t.Error("Expected error moving after the game is over")
+	}
+}
+
+func playKnightShuffle(g *game.Game) {
+	g.Move(game.Move{StartSquare: 6, TargetSquare: 21})  // Nf3
+	g.Move(game.Move{StartSquare: 62, TargetSquare: 45}) // Nf6
+	g.Move(game.Move{StartSquare: 21, TargetSquare: 6})  // Ng1
+	g.Move(game.Move{StartSquare: 45, TargetSquare: 62}) // Ng8
+}
+
+func TestStatusThreefoldRepetition(t *testing.T) {
+	g := game.NewGame()
+
+	for i := 0; i < 2; i++ {
+		if g.IsThreefoldRepetition() {
+			t.Errorf("Expected no threefold repetition after %d shuffles", i)
+		}
+		playKnightShuffle(g)
+	}
+
+	if g.RepetitionCount() != 3 {
+		t.Errorf("Expected repetition count 3, got %d", g.RepetitionCount())
+	}
+	if g.Status() != game.Ongoing {
+		t.Error(compareStatusErrorMessage(game.Ongoing, g.Status()))
+	}
+	if err := g.ClaimDraw(); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+	if g.Status() != game.ThreefoldRepetition {
+		t.Error(compareStatusErrorMessage(game.ThreefoldRepetition, g.Status()))
+	}
+}
+
+func TestRepetitionAfterDoublePush(t *testing.T) {
+	// No black pawn can take on e3, so the position after e4 repeats
+	g := game.NewGame()
+	g.Move(game.Move{StartSquare: 12, TargetSquare: 28}) // e4
+	for i := 0; i < 2; i++ {
+		g.Move(game.Move{StartSquare: 62, TargetSquare: 45}) // Nf6
+		g.Move(game.Move{StartSquare: 6, TargetSquare: 21})  // Nf3
+		g.Move(game.Move{StartSquare: 45, TargetSquare: 62}) // Ng8
+		g.Move(game.Move{StartSquare: 21, TargetSquare: 6})  // Ng1
+	}
+	if g.RepetitionCount() != 3 {
+		t.Errorf("Expected repetition count 3, got %d", g.RepetitionCount())
+	}
+
+	// The pawn on d4 could take en passant right after e4 only
+	g = game.NewGameFromFen("4k3/8/8/8/3p4/8/4P3/4K3 w - - 0 1")
+	g.Move(game.Move{StartSquare: 12, TargetSquare: 28}) // e4
+	g.Move(game.Move{StartSquare: 60, TargetSquare: 59}) // Kd8
+	g.Move(game.Move{StartSquare: 4, TargetSquare: 3})   // Kd1
+	g.Move(game.Move{StartSquare: 59, TargetSquare: 60}) // Ke8
+	g.Move(game.Move{StartSquare: 3, TargetSquare: 4})   // Ke1
+	if g.RepetitionCount() != 1 {
+		t.Errorf("Expected the position with en passant not to repeat, got count %d", g.RepetitionCount())
+	}
+}
+
+func TestStatusFivefoldRepetition(t *testing.T) {
+	g := game.NewGame()
+
+	for i := 0; i < 4; i++ {
+		playKnightShuffle(g)
+	}
+
+	if g.RepetitionCount() != 5 {
+		t.Errorf("Expected repetition count 5, got %d", g.RepetitionCount())
+	}
+	if g.Status() != game.FivefoldRepetition {
+		t.Error(compareStatusErrorMessage(game.FivefoldRepetition, g.Status()))
+	}
+}
+
+func TestClaimDrawWithoutRepetition(t *testing.T) {
+	g := game.NewGame()
+
+	playKnightShuffle(g)
+
+	if g.ClaimDraw() == nil {
+		t.Error("Expected error claiming a draw after one repetition")
+	}
+}
+
+func TestRepetitionAfterIrreversibleMove(t *testing.T) {
+	g := game.NewGame()
+
+	playKnightShuffle(g)
+	g.Move(game.Move{StartSquare: 12, TargetSquare: 20}) // e3
+	g.Move(game.Move{StartSquare: 52, TargetSquare: 44}) // e6
+	playKnightShuffle(g)
+
+	if g.RepetitionCount() != 2 {
+		t.Errorf("Expected repetition count 2, got %d", g.RepetitionCount())
+	}
+}
+
+func TestStatusInsufficientMaterial(t *testing.T) {
+	fens := map[string]game.Status{
+		"4k3/8/8/8/8/8/8/4K3 w - - 0 1":     game.InsufficientMaterial,
+		"4k3/8/8/8/8/8/8/2B1K3 w - - 0 1":   game.InsufficientMaterial,
+		"4k3/8/8/8/8/8/8/1N2K3 w - - 0 1":   game.InsufficientMaterial,
+		"2b1k3/8/8/8/8/8/8/2B1K3 w - - 0 1": game.Ongoing,
+		"3bk3/8/8/8/8/8/8/2B1K3 w - - 0 1":  game.InsufficientMaterial,
+		"1n2k3/8/8/8/8/8/8/1N2K3 w - - 0 1": game.Ongoing,
+		"4k3/8/8/8/8/8/8/P3K3 w - - 0 1":    game.Ongoing,
+	}
+
+	for fen, expected := range fens {
+		g := game.NewGameFromFen(fen)
+		if g.Status() != expected {
+			t.Errorf("%s\n%s", fen, compareStatusErrorMessage(expected, g.Status()))
+		}
+	}
+}
+
+func TestStatusTimeout(t *testing.T) {
+	tests := []struct {
+		fen    string
+		status game.Status
+		result string
+	}{
+		{"4k3/8/8/8/8/8/8/R3K3 b - - 0 1", game.Timeout, "1-0"},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", game.TimeoutVsInsufficientMaterial, "1/2-1/2"},
+		// The pawn may block its own king, so the knight can mate
+		{"4k3/4p3/8/8/8/8/8/1N2K3 b - - 0 1", game.Timeout, "1-0"},
+		{"4k3/8/8/8/8/8/8/1n1QK3 w - - 0 1", game.Timeout, "0-1"},
+		{"4k3/8/8/8/8/8/8/1NB1K3 b - - 0 1", game.Timeout, "1-0"},
+		{"4k3/4p3/8/8/8/8/8/R3K3 w - - 0 1", game.Timeout, "0-1"},
+	}
+
+	for _, test := range tests {
+		g := game.NewGameFromFen(test.fen)
+		if err := g.Timeout(); err != nil {
+			t.Fatalf("%s: error: %v", test.fen, err)
+		}
+		if g.Status() != test.status || g.Result() != test.result {
+			t.Errorf("%s: expected %s %s, got %s %s", test.fen, test.status, test.result, g.Status(), g.Result())
+		}
+		if g.Timeout() == nil {
+			t.Errorf("%s: expected error for a second timeout", test.fen)
+		}
+	}
+}
+
+func TestTimeoutLapsesOnUndo(t *testing.T) {
+	g := game.NewGame()
+	g.Move(game.Move{StartSquare: 12, TargetSquare: 28}) // e4
+	g.Timeout()
+
+	g.UndoMove()
+	if g.Status() != game.Ongoing {
+		t.Errorf("Expected timeout to lapse after undo, got %s", g.Status())
+	}
+}
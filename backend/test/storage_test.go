@@ -0,0 +1,208 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-chess/backend/clock"
+	game "web-chess/backend/src"
+	"web-chess/backend/storage"
+)
+
+func playUCI(t *testing.T, g *game.Game, moves ...string) {
+	t.Helper()
+	for _, uci := range moves {
+		move, err := game.ParseUCIMove(g, uci)
+		if err == nil {
+			err = g.Move(move)
+		}
+		if err != nil {
+			t.Fatalf("Error playing %s: %v", uci, err)
+		}
+	}
+}
+
+func testRepository(t *testing.T, repository storage.GameRepository) {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	record := storage.Record{
+		ID:       "0123456789abcdef",
+		Created:  created,
+		StartFen: game.StartingFen,
+		Moves:    []string{"e2e4", "e7e5"},
+		Tags:     map[string]string{"White": "Alice"},
+		Opponent: &storage.Opponent{EngineColor: "black", Level: 3, MoveTimeMs: 500},
+		Clock:    &clock.Snapshot{TimeControl: "5+3", Remaining: [2]time.Duration{time.Minute, 2 * time.Minute}, Running: true, Turn: 1, Since: created},
+	}
+
+	if _, err := repository.Load(record.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound before saving, got %v", err)
+	}
+	if err := repository.Save(record); err != nil {
+		t.Fatalf("Error saving: %v", err)
+	}
+
+	record.Moves = append(record.Moves, "g1f3")
+	if err := repository.Save(record); err != nil {
+		t.Fatalf("Error saving again: %v", err)
+	}
+	loaded, err := repository.Load(record.ID)
+	if err != nil {
+		t.Fatalf("Error loading: %v", err)
+	}
+	if len(loaded.Moves) != 3 || !loaded.Created.Equal(created) || loaded.Tags["White"] != "Alice" ||
+		*loaded.Opponent != *record.Opponent || loaded.Clock.Remaining != record.Clock.Remaining || !loaded.Clock.Since.Equal(created) {
+		t.Errorf("Expected %+v, got %+v", record, loaded)
+	}
+
+	second := storage.Record{ID: "fedcba9876543210", StartFen: game.StartingFen}
+	if err := repository.Save(second); err != nil {
+		t.Fatalf("Error saving: %v", err)
+	}
+	records, err := repository.List()
+	if err != nil || len(records) != 2 {
+		t.Errorf("Expected 2 records, got %d %v", len(records), err)
+	}
+
+	if err := repository.Delete(record.ID); err != nil {
+		t.Errorf("Error deleting: %v", err)
+	}
+	if err := repository.Delete(record.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+	if records, _ := repository.List(); len(records) != 1 || records[0].ID != second.ID {
+		t.Errorf("Expected only the second record left, got %+v", records)
+	}
+}
+
+func TestJSONRepository(t *testing.T) {
+	dir := t.TempDir()
+	repository, err := storage.NewJSONRepository(filepath.Join(dir, "games"))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	testRepository(t, repository)
+
+	if err := repository.Save(storage.Record{ID: "../escape"}); err == nil {
+		t.Errorf("Expected error for an id outside the directory")
+	}
+}
+
+func TestJSONRepositorySkipsUnreadableRecords(t *testing.T) {
+	dir := t.TempDir()
+	repository, err := storage.NewJSONRepository(dir)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := repository.Save(storage.Record{ID: "0123456789abcdef", StartFen: game.StartingFen}); err != nil {
+		t.Fatalf("Error saving: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "fedcba9876543210.json"), []byte(`{"id": "fedcba`), 0o644); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	records, err := repository.List()
+	if !errors.Is(err, storage.ErrUnreadable) {
+		t.Errorf("Expected ErrUnreadable, got %v", err)
+	}
+	if len(records) != 1 || records[0].ID != "0123456789abcdef" {
+		t.Errorf("Expected the readable record, got %+v", records)
+	}
+}
+
+func TestBoltRepository(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.db")
+	repository, err := storage.NewBoltRepository(path)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	testRepository(t, repository)
+	repository.Close()
+
+	reopened, err := storage.NewBoltRepository(path)
+	if err != nil {
+		t.Fatalf("Error reopening: %v", err)
+	}
+	defer reopened.Close()
+	if records, err := reopened.List(); err != nil || len(records) != 1 {
+		t.Errorf("Expected the record to survive reopening, got %d %v", len(records), err)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	g := game.NewGameFromFen("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	playUCI(t, g, "e1g1", "e8c8", "a1a8", "c8b7")
+	record := storage.NewRecord(g)
+
+	replayed, err := record.Replay()
+	if err != nil {
+		t.Fatalf("Error replaying: %v", err)
+	}
+	if replayed.CurrentFen() != g.CurrentFen() || replayed.Hash() != g.Hash() || len(replayed.Moves()) != 4 {
+		t.Errorf("Expected %s, got %s", g.CurrentFen(), replayed.CurrentFen())
+	}
+	if err := replayed.VerifyHash(); err != nil {
+		t.Errorf("Expected the replayed hash to match the position: %v", err)
+	}
+
+	// Undoing every move leads back to the start position
+	for replayed.UndoMove() == nil {
+	}
+	if replayed.CurrentFen() != record.StartFen {
+		t.Errorf("Expected %s after undoing, got %s", record.StartFen, replayed.CurrentFen())
+	}
+}
+
+func TestRecordReplayVariant(t *testing.T) {
+	g := game.NewVariantGameFromFen(game.ThreeCheck, "4k3/8/8/8/8/8/8/R3K3 w - - 3+3 0 1")
+	playUCI(t, g, "a1a8", "e8e7")
+	record := storage.NewRecord(g)
+	if record.Variant != "Three-check" {
+		t.Errorf("Expected the variant to be saved, got %q", record.Variant)
+	}
+
+	replayed, err := record.Replay()
+	if err != nil {
+		t.Fatalf("Error replaying: %v", err)
+	}
+	if replayed.Variant() != game.ThreeCheck || replayed.CurrentFen() != g.CurrentFen() {
+		t.Errorf("Expected %s, got %s", g.CurrentFen(), replayed.CurrentFen())
+	}
+}
+
+func TestRecordReplayEndings(t *testing.T) {
+	timedOut := game.NewGame()
+	playUCI(t, timedOut, "e2e4")
+	timedOut.Timeout()
+
+	claimed := game.NewGame()
+	playKnightShuffle(claimed)
+	playKnightShuffle(claimed)
+	if err := claimed.ClaimDraw(); err != nil {
+		t.Fatalf("Error claiming draw: %v", err)
+	}
+
+	for _, g := range []*game.Game{timedOut, claimed} {
+		record := storage.NewRecord(g)
+		replayed, err := record.Replay()
+		if err != nil {
+			t.Fatalf("Error replaying: %v", err)
+		}
+		if replayed.Status() != g.Status() || replayed.Result() != g.Result() {
+			t.Errorf("Expected %s %s, got %s %s", g.Status(), g.Result(), replayed.Status(), replayed.Result())
+		}
+	}
+}
+
+func TestRecordReplayRejectsIllegalMoves(t *testing.T) {
+	record := storage.Record{StartFen: game.StartingFen, Moves: []string{"e2e4", "e2e4"}}
+	if _, err := record.Replay(); err == nil {
+		t.Errorf("Expected error replaying an illegal move")
+	}
+	record = storage.Record{StartFen: game.StartingFen, Moves: []string{"e2e4"}, Ended: "resignation"}
+	if _, err := record.Replay(); err == nil {
+		t.Errorf("Expected error for an unknown ending")
+	}
+}
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"sync"
+	"testing"
+
+	"web-chess/backend/engine"
+	game "web-chess/backend/src"
+)
+
+func TestTranspositionTableStoreAndProbe(t *testing.T) {
+	tt := engine.NewTranspositionTable(1)
+	move := game.Move{StartSquare: 12, TargetSquare: 28, Flag: game.PawnTwoForward}.Pack()
+
+	tt.Store(0x1234, 0, engine.TTEntry{Move: move, Score: -57, Depth: 6, Bound: engine.BoundLower})
+	entry, ok := tt.Probe(0x1234, 0)
+	expected := engine.TTEntry{Move: move, Score: -57, Depth: 6, Bound: engine.BoundLower}
+	if !ok || entry != expected {
+		t.Errorf("Expected %+v, got %+v (found %v)", expected, entry, ok)
+	}
+
+	if _, ok := tt.Probe(0x5678, 0); ok {
+		t.Error("Expected a miss for a position that was not stored")
+	}
+
+	tt.Clear()
+	if _, ok := tt.Probe(0x1234, 0); ok {
+		t.Error("Expected a miss after clearing the table")
+	}
+}
+
+func TestTranspositionTableMateScores(t *testing.T) {
+	tt := engine.NewTranspositionTable(1)
+
+	// Mate in 3 from a position 4 plies from the root is a mate in 2 from one
+	// 2 plies from the root
+	tt.Store(1, 4, engine.TTEntry{Score: engine.MateScore - 9, Depth: 5, Bound: engine.BoundExact})
+	entry, _ := tt.Probe(1, 2)
+	if entry.Score != engine.MateScore-7 {
+		t.Errorf("Expected %v, got %v", engine.Score(engine.MateScore-7), entry.Score)
+	}
+
+	tt.Store(2, 3, engine.TTEntry{Score: -engine.MateScore + 5, Depth: 5, Bound: engine.BoundExact})
+	entry, _ = tt.Probe(2, 1)
+	if entry.Score != -engine.MateScore+3 {
+		t.Errorf("Expected %v, got %v", -engine.MateScore+3, entry.Score)
+	}
+
+	tt.Store(3, 10, engine.TTEntry{Score: 250, Depth: 5, Bound: engine.BoundExact})
+	if entry, _ = tt.Probe(3, 0); entry.Score != 250 {
+		t.Errorf("Expected centipawn scores to be stored unchanged, got %v", entry.Score)
+	}
+}
+
+func TestTranspositionTableReplaceByDepth(t *testing.T) {
+	tt := engine.NewTranspositionTable(1)
+	// Both keys map to the same slot of a table with fewer than 2^32 entries
+	deep, shallow := uint64(7), uint64(7|1<<32)
+
+	tt.Store(deep, 0, engine.TTEntry{Score: 1, Depth: 8, Bound: engine.BoundExact})
+	tt.Store(shallow, 0, engine.TTEntry{Score: 2, Depth: 3, Bound: engine.BoundExact})
+	if _, ok := tt.Probe(deep, 0); !ok {
+		t.Error("Expected the deeper entry to be kept")
+	}
+
+	tt.NewSearch()
+	tt.Store(shallow, 0, engine.TTEntry{Score: 2, Depth: 3, Bound: engine.BoundExact})
+	if _, ok := tt.Probe(shallow, 0); !ok {
+		t.Error("Expected entries of an earlier search to be replaced")
+	}
+}
+
+func TestTranspositionTableConcurrentAccess(t *testing.T) {
+	tt := engine.NewTranspositionTable(1)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 10000; i++ {
+				// Keys collide within a small range of slots
+				hash := uint64(i%64) | uint64(worker)<<40
+				// The depth is derived from the key, so any entry that is
+				// found must belong to it
+				depth := int(hash>>40) + 1
+				tt.Store(hash, 0, engine.TTEntry{Score: engine.Score(depth), Depth: depth, Bound: engine.BoundExact})
+				if entry, ok := tt.Probe(hash, 0); ok && (entry.Depth != depth || entry.Score != engine.Score(depth)) {
+					t.Errorf("Found entry %+v for key %x", entry, hash)
+					return
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+}
+
+func TestSearchWithSharedTable(t *testing.T) {
+	tt := engine.NewTranspositionTable(4)
+
+	var wg sync.WaitGroup
+	for _, fen := range perftFens[:4] {
+		wg.Add(1)
+		go func(fen string) {
+			defer wg.Done()
+			g := game.NewGameFromFen(fen)
+			result, err := engine.NewWithTable(tt).Search(g, engine.Limits{Depth: 4})
+			if err != nil {
+				t.Errorf("%s: error: %v", fen, err)
+				return
+			}
+			if g.CurrentFen() != fen || len(result.PV) == 0 {
+				t.Errorf("%s: expected an unchanged game and a principal variation", fen)
+			}
+		}(fen)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,186 @@
+package test
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	game "web-chess/backend/src"
+	"web-chess/backend/uci"
+)
+
+// Runs the script through the UCI front-end and returns the output lines
+func runUCI(t *testing.T, script string) []string {
+	t.Helper()
+	var out strings.Builder
+	if err := uci.Run(strings.NewReader(script), &out); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(out.String()), "\n")
+}
+
+func linesWithPrefix(lines []string, prefix string) []string {
+	var matching []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			matching = append(matching, line)
+		}
+	}
+	return matching
+}
+
+// Checks that the output ends in exactly one best move that is legal in g
+func checkBestMove(t *testing.T, lines []string, g *game.Game) {
+	t.Helper()
+	bestMoves := linesWithPrefix(lines, "bestmove ")
+	if len(bestMoves) != 1 {
+		t.Fatalf("Expected one bestmove line, got %q", lines)
+	}
+	fields := strings.Fields(bestMoves[0])
+	if _, err := game.ParseUCIMove(g, fields[1]); err != nil {
+		t.Errorf("Best move %s is not legal: %v", fields[1], err)
+	}
+}
+
+func TestUCIHandshake(t *testing.T) {
+	lines := runUCI(t, "uci\nisready\n")
+
+	if len(linesWithPrefix(lines, "id name ")) != 1 {
+		t.Errorf("Expected an id name line, got %q", lines)
+	}
+	for _, option := range []string{"option name Hash ", "option name Threads ", "option name UCI_Chess960 "} {
+		if len(linesWithPrefix(lines, option)) != 1 {
+			t.Errorf("Expected line starting with %q, got %q", option, lines)
+		}
+	}
+	if len(lines) < 2 || lines[len(lines)-2] != "uciok" || lines[len(lines)-1] != "readyok" {
+		t.Errorf("Expected uciok followed by readyok, got %q", lines)
+	}
+}
+
+func TestUCIGoDepth(t *testing.T) {
+	lines := runUCI(t, "position startpos moves e2e4 e7e5 g1f3\ngo depth 3\n")
+
+	g := game.NewGame()
+	for _, uciMove := range []string{"e2e4", "e7e5", "g1f3"} {
+		move, _ := game.ParseUCIMove(g, uciMove)
+		g.Move(move)
+	}
+	checkBestMove(t, lines, g)
+
+	infos := linesWithPrefix(lines, "info depth ")
+	if len(infos) != 3 {
+		t.Fatalf("Expected an info line per depth, got %q", lines)
+	}
+	for _, field := range []string{" score ", " nodes ", " nps ", " time ", " pv "} {
+		if !strings.Contains(infos[2], field) {
+			t.Errorf("Expected%sin %q", field, infos[2])
+		}
+	}
+}
+
+func TestUCIPositionFen(t *testing.T) {
+	fen := "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1"
+	lines := runUCI(t, "position fen "+fen+"\ngo depth 2\n")
+
+	if bestMoves := linesWithPrefix(lines, "bestmove "); len(bestMoves) != 1 || bestMoves[0] != "bestmove a1a8" {
+		t.Errorf("Expected bestmove a1a8, got %q", lines)
+	}
+	if infos := linesWithPrefix(lines, "info depth "); !strings.Contains(infos[len(infos)-1], "score mate 1") {
+		t.Errorf("Expected mate score, got %q", infos)
+	}
+}
+
+func TestUCIPositionFenWithMoves(t *testing.T) {
+	fen := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	lines := runUCI(t, "position fen "+fen+" moves f2f3 e7e5 g2g4\ngo depth 2\n")
+
+	if bestMoves := linesWithPrefix(lines, "bestmove "); len(bestMoves) != 1 || bestMoves[0] != "bestmove d8h4" {
+		t.Errorf("Expected bestmove d8h4, got %q", lines)
+	}
+}
+
+func TestUCIInvalidInput(t *testing.T) {
+	lines := runUCI(t, "position startpos moves e2e5\nposition fen not a fen\nsetoption name Hash value many\nsetoption name Colour value 1\nfoo\nisready\n")
+
+	if infos := linesWithPrefix(lines, "info string "); len(infos) != 5 {
+		t.Errorf("Expected an info string per invalid command, got %q", lines)
+	}
+	if lines[len(lines)-1] != "readyok" {
+		t.Errorf("Expected readyok after invalid commands, got %q", lines)
+	}
+}
+
+func TestUCISetOption(t *testing.T) {
+	lines := runUCI(t, "setoption name Hash value 1\nsetoption name Threads value 2\nucinewgame\ngo nodes 20000\n")
+
+	checkBestMove(t, lines, game.NewGame())
+	if infos := linesWithPrefix(lines, "info string "); len(infos) != 0 {
+		t.Errorf("Expected options to be accepted, got %q", infos)
+	}
+}
+
+func TestUCIChess960(t *testing.T) {
+	// Castling is the only mate, the king stays on g1 and the rook goes to f1
+	lines := runUCI(t, "setoption name UCI_Chess960 value true\nposition fen 4rkr1/4p1p1/8/8/8/1B6/8/6KR w H - 0 1\ngo depth 2\n")
+
+	if bestMoves := linesWithPrefix(lines, "bestmove "); len(bestMoves) != 1 || bestMoves[0] != "bestmove g1h1" {
+		t.Errorf("Expected bestmove g1h1, got %q", lines)
+	}
+	if infos := linesWithPrefix(lines, "info string "); len(infos) != 0 {
+		t.Errorf("Expected the option to be accepted, got %q", infos)
+	}
+}
+
+func TestUCIGoMovetime(t *testing.T) {
+	start := time.Now()
+	lines := runUCI(t, "go movetime 200\n")
+
+	checkBestMove(t, lines, game.NewGame())
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected search to take about 200ms, took %v", elapsed)
+	}
+}
+
+func TestUCIGoInfiniteStop(t *testing.T) {
+	in, writer := io.Pipe()
+	reader, out := io.Pipe()
+	go func() {
+		uci.Run(in, out)
+		out.Close()
+	}()
+
+	scanner := bufio.NewScanner(reader)
+	writer.Write([]byte("go infinite\n"))
+	// Wait until the search has reported an iteration before stopping it
+	for scanner.Scan() && !strings.HasPrefix(scanner.Text(), "info depth ") {
+	}
+	writer.Write([]byte("stop\n"))
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if strings.HasPrefix(scanner.Text(), "bestmove ") {
+			break
+		}
+	}
+	writer.Write([]byte("quit\n"))
+	io.Copy(io.Discard, reader)
+
+	checkBestMove(t, lines, game.NewGame())
+}
+
+func TestUCIGoClock(t *testing.T) {
+	start := time.Now()
+	lines := runUCI(t, "position startpos moves e2e4\ngo wtime 1000 btime 1000 winc 0 binc 0\n")
+
+	g := game.NewGame()
+	move, _ := game.ParseUCIMove(g, "e2e4")
+	g.Move(move)
+	checkBestMove(t, lines, g)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected search to use a fraction of the clock, took %v", elapsed)
+	}
+}
@@ -0,0 +1,76 @@
+package test
+
+import (
+	"testing"
+	game "web-chess/backend/src"
+)
+
+func TestMoveUCI(t *testing.T) {
+	tests := []struct {
+		move     game.Move
+		expected string
+	}{
+		{game.Move{StartSquare: 12, TargetSquare: 28, Flag: game.PawnTwoForward}, "e2e4"},
+		{game.Move{StartSquare: 4, TargetSquare: 6, Flag: game.Castling}, "e1g1"},
+		{game.Move{StartSquare: 60, TargetSquare: 58, Flag: game.Castling}, "e8c8"},
+		{game.Move{StartSquare: 35, TargetSquare: 44, Flag: game.EnPassantCapture}, "d5e6"},
+		{game.Move{StartSquare: 52, TargetSquare: 60, Flag: game.PromoteToQueen}, "e7e8q"},
+		{game.Move{StartSquare: 9, TargetSquare: 0, Flag: game.PromoteToKnight}, "b2a1n"},
+	}
+
+	for _, test := range tests {
+		if test.move.UCI() != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, test.move.UCI())
+		}
+	}
+}
+
+func TestParseUCIMovePromotion(t *testing.T) {
+	g := game.NewGameFromFen("r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 b kq - 0 1")
+
+	expected := map[string]int{
+		"b2a1q": game.PromoteToQueen,
+		"b2a1r": game.PromoteToRook,
+		"b2b1b": game.PromoteToBishop,
+		"b2b1n": game.PromoteToKnight,
+	}
+	for uci, flag := range expected {
+		move, err := game.ParseUCIMove(g, uci)
+		if err != nil {
+			t.Errorf("Error parsing %s: %v", uci, err)
+			continue
+		}
+		if move.Flag != flag {
+			t.Errorf("Expected flag %d for %s, got %d", flag, uci, move.Flag)
+		}
+	}
+
+	for _, uci := range []string{"b2a1", "b2b1k", "e8g8q", "e2e4", "b2", "z9a1"} {
+		if _, err := game.ParseUCIMove(g, uci); err == nil {
+			t.Errorf("Expected error parsing %s", uci)
+		}
+	}
+}
+
+func TestUCIRoundTripPerftPositions(t *testing.T) {
+	for _, fen := range perftFens {
+		g := game.NewGameFromFen(fen)
+		seen := map[string]bool{}
+		for _, move := range g.GenerateLegalMoves() {
+			uci := move.UCI()
+			if seen[uci] {
+				t.Errorf("%s: duplicate move string %s", fen, uci)
+			}
+			seen[uci] = true
+
+			parsed, err := game.ParseUCIMove(g, uci)
+			if err != nil {
+				t.Errorf("%s: error parsing %s: %v", fen, uci, err)
+				continue
+			}
+			if parsed != move {
+				t.Errorf("%s: expected %v from %s, got %v", fen, move, uci, parsed)
+			}
+		}
+	}
+}
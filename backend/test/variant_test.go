@@ -0,0 +1,223 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"web-chess/backend/engine"
+	game "web-chess/backend/src"
+)
+
+func TestParseVariant(t *testing.T) {
+	tests := map[string]game.Variant{
+		"":                 game.Standard,
+		"Standard":         game.Standard,
+		"King of the Hill": game.KingOfTheHill,
+		"kingofthehill":    game.KingOfTheHill,
+		"koth":             game.KingOfTheHill,
+		"Three-check":      game.ThreeCheck,
+		"3check":           game.ThreeCheck,
+		"atomic":           game.Atomic,
+		"Horde":            game.Horde,
+		"Crazyhouse":       game.Crazyhouse,
+		"zh":               game.Crazyhouse,
+	}
+	for name, expected := range tests {
+		if variant, err := game.ParseVariant(name); err != nil || variant != expected {
+			t.Errorf("%q: expected %s, got %v %v", name, expected.Name(), variant, err)
+		}
+	}
+	if _, err := game.ParseVariant("suicide"); err == nil {
+		t.Error("Expected an error for an unknown variant")
+	}
+}
+
+func TestKingOfTheHill(t *testing.T) {
+	fen := "4k3/8/8/8/8/4K3/8/8 w - - 0 1"
+	if !game.NewGameFromFen(fen).IsInsufficientMaterial() {
+		t.Fatal("Expected bare kings to be insufficient material in standard chess")
+	}
+
+	g := game.NewVariantGameFromFen(game.KingOfTheHill, fen)
+	if g.IsInsufficientMaterial() {
+		t.Error("Expected a lone king to be able to win by reaching the hill")
+	}
+	playUCI(t, g, "e3e4")
+	if g.Status() != game.KingOnHill {
+		t.Error(compareStatusErrorMessage(game.KingOnHill, g.Status()))
+	}
+	if g.Winner() != game.White || g.Result() != "1-0" {
+		t.Errorf("Expected white to win, got %d %s", g.Winner(), g.Result())
+	}
+	if moves := g.GenerateLegalMoves(); len(moves) != 0 {
+		t.Errorf("Expected no moves after the game ended, got %v", moves)
+	}
+
+	g.UndoMove()
+	if g.Status() != game.Ongoing {
+		t.Error(compareStatusErrorMessage(game.Ongoing, g.Status()))
+	}
+}
+
+func TestThreeCheck(t *testing.T) {
+	fen := "4k3/8/8/8/8/8/8/R3K3 w - - 3+3 0 1"
+	g := game.NewVariantGameFromFen(game.ThreeCheck, fen)
+	playUCI(t, g, "a1a8", "e8e7", "a8a7")
+	if expected := "8/R3k3/8/8/8/8/8/4K3 b - - 1+3 3 2"; g.CurrentFen() != expected {
+		t.Error(compareFenStringErrorMessage(expected, g.CurrentFen()))
+	}
+	if g.ChecksGiven(true) != 2 || g.ChecksGiven(false) != 0 {
+		t.Errorf("Expected 2 and 0 checks, got %d and %d", g.ChecksGiven(true), g.ChecksGiven(false))
+	}
+
+	playUCI(t, g, "e7e6", "a7a6")
+	if g.Status() != game.ThirdCheck {
+		t.Error(compareStatusErrorMessage(game.ThirdCheck, g.Status()))
+	}
+	if g.Result() != "1-0" {
+		t.Errorf("Expected result 1-0, got %s", g.Result())
+	}
+
+	for range 5 {
+		g.UndoMove()
+		if err := g.VerifyHash(); err != nil {
+			t.Error(err)
+		}
+	}
+	if g.CurrentFen() != fen {
+		t.Error(compareFenStringErrorMessage(fen, g.CurrentFen()))
+	}
+}
+
+func TestThreeCheckFen(t *testing.T) {
+	tests := []struct {
+		fen      string
+		expected string
+	}{
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 2+1 0 1", "4k3/8/8/8/8/8/8/R3K3 w - - 2+1 0 1"},
+		// Lichess counts the checks given at the end
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1 +1+2", "4k3/8/8/8/8/8/8/R3K3 w - - 2+1 0 1"},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "4k3/8/8/8/8/8/8/R3K3 w - - 3+3 0 1"},
+	}
+	for _, test := range tests {
+		g, err := game.ParseVariantFen(game.ThreeCheck, test.fen)
+		if err != nil {
+			t.Errorf("%s: %v", test.fen, err)
+			continue
+		}
+		if g.CurrentFen() != test.expected {
+			t.Error(compareFenStringErrorMessage(test.expected, g.CurrentFen()))
+		}
+	}
+
+	for _, fen := range []string{"4k3/8/8/8/8/8/8/R3K3 w - - 4+1 0 1", "4k3/8/8/8/8/8/8/R3K3 w - - 0 1 +1"} {
+		if _, err := game.ParseVariantFen(game.ThreeCheck, fen); !errors.Is(err, game.ErrFenChecks) {
+			t.Errorf("%s: expected ErrFenChecks, got %v", fen, err)
+		}
+	}
+	// The same position with other counters is another position
+	a := game.NewVariantGameFromFen(game.ThreeCheck, "4k3/8/8/8/8/8/8/R3K3 w - - 2+1 0 1")
+	b := game.NewVariantGameFromFen(game.ThreeCheck, "4k3/8/8/8/8/8/8/R3K3 w - - 2+2 0 1")
+	if a.Hash() == b.Hash() {
+		t.Error("Expected the check counters to change the hash")
+	}
+}
+
+func TestAtomicExplosion(t *testing.T) {
+	fen := "4k3/8/2n5/3p4/4P1P1/8/8/4K3 w - - 0 1"
+	g := game.NewVariantGameFromFen(game.Atomic, fen)
+	playUCI(t, g, "e4d5")
+	// The knight next to the capture goes with both pawns, the pawn on g4
+	// is too far away
+	if expected := "4k3/8/8/8/6P1/8/8/4K3 b - - 0 1"; g.CurrentFen() != expected {
+		t.Error(compareFenStringErrorMessage(expected, g.CurrentFen()))
+	}
+	if err := g.VerifyHash(); err != nil {
+		t.Error(err)
+	}
+
+	g.UndoMove()
+	if g.CurrentFen() != fen {
+		t.Error(compareFenStringErrorMessage(fen, g.CurrentFen()))
+	}
+	if err := g.VerifyHash(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAtomicLegality(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		uci   string
+		legal bool
+	}{
+		{"king cannot capture", "4k3/8/8/8/8/8/4p3/4K3 w - - 0 1", "e1e2", false},
+		{"capture next to own king", "4k3/8/8/8/8/8/3p4/2QK4 w - - 0 1", "c1d2", false},
+		{"blowing up the king ignores check", "3qk3/8/8/8/8/8/8/3QK2r w - - 0 1", "d1d8", true},
+		{"kings next to each other", "4r3/8/8/8/3k4/8/3K4/8 w - - 0 1", "d2e3", true},
+		{"attacked away from the enemy king", "4r3/8/8/8/3k4/8/3K4/8 w - - 0 1", "d2e2", false},
+	}
+
+	for _, test := range tests {
+		g := game.NewVariantGameFromFen(game.Atomic, test.fen)
+		if _, err := game.ParseUCIMove(g, test.uci); (err == nil) != test.legal {
+			t.Errorf("%s: expected legal %v, got %v", test.name, test.legal, err)
+		}
+	}
+
+	g := game.NewVariantGameFromFen(game.Atomic, "3qk3/8/8/8/8/8/8/3QK2r w - - 0 1")
+	playUCI(t, g, "d1d8")
+	if g.Status() != game.KingExploded || g.Winner() != game.White {
+		t.Errorf("Expected white to win by explosion, got %s", g.Status())
+	}
+}
+
+func TestHorde(t *testing.T) {
+	start := game.NewVariantGame(game.Horde)
+	if _, err := game.ParseFen(start.InitialFen()); err == nil {
+		t.Error("Expected the horde to be invalid in standard chess")
+	}
+	if _, err := game.ParseVariantFen(game.Horde, start.InitialFen()); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+
+	// Pawns on the first rank move two squares without an en passant square
+	g := game.NewVariantGameFromFen(game.Horde, "4k3/8/8/8/8/8/8/P7 w - - 0 1")
+	playUCI(t, g, "a1a3")
+	if expected := "4k3/8/8/8/8/P7/8/8 b - - 0 1"; g.CurrentFen() != expected {
+		t.Error(compareFenStringErrorMessage(expected, g.CurrentFen()))
+	}
+
+	g = game.NewVariantGameFromFen(game.Horde, "4k3/8/8/8/8/8/3P4/4q3 b - - 0 1")
+	if g.IsInsufficientMaterial() {
+		t.Error("Expected black to be able to capture the horde")
+	}
+	playUCI(t, g, "e1d2")
+	if g.Status() != game.NoPiecesLeft || g.Result() != "0-1" {
+		t.Errorf("Expected black to win, got %s %s", g.Status(), g.Result())
+	}
+}
+
+func TestSearchFindsVariantWins(t *testing.T) {
+	tests := []struct {
+		variant game.Variant
+		fen     string
+		move    string
+	}{
+		{game.KingOfTheHill, "4k3/8/8/8/8/5K2/8/8 w - - 0 1", "f3e4"},
+		{game.ThreeCheck, "4k3/8/8/8/8/8/8/R3K3 w - - 1+3 0 1", "a1a8"},
+		{game.Atomic, "3qk3/8/8/8/8/8/8/3QK2r w - - 0 1", "d1d8"},
+	}
+
+	for _, test := range tests {
+		g := game.NewVariantGameFromFen(test.variant, test.fen)
+		result, err := engine.Search(g, engine.Limits{Depth: 3})
+		if err != nil {
+			t.Fatalf("%s: error: %v", test.fen, err)
+		}
+		if result.Move.UCI() != test.move || result.Score.MateIn() != 1 {
+			t.Errorf("%s %s: expected %s winning at once, got %s %v", test.variant.Name(), test.fen, test.move, result.Move.UCI(), result.Score)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package test
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+
+	"web-chess/backend/engine"
+	game "web-chess/backend/src"
+)
+
+// Nodes each position of the tactical suite is searched for
+const wacNodes = 50_000
+
+type epdPosition struct {
+	id        string
+	fen       string
+	bestMoves []string
+}
+
+// Reads positions with "bm" and "id" operations. EPD has no move counters, so
+// they are added to make a FEN
+func readEPD(t *testing.T, path string) []epdPosition {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer file.Close()
+
+	positions := []epdPosition{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		position := epdPosition{fen: strings.Join(fields[:4], " ") + " 0 1"}
+		for _, operation := range strings.Split(strings.Join(fields[4:], " "), ";") {
+			name, operand, _ := strings.Cut(strings.TrimSpace(operation), " ")
+			switch name {
+			case "bm":
+				position.bestMoves = strings.Fields(operand)
+			case "id":
+				position.id = strings.Trim(operand, `"`)
+			}
+		}
+		positions = append(positions, position)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	return positions
+}
+
+// Returns how many positions the engine finds a best move for
+func solveEPD(t *testing.T, positions []epdPosition, options engine.Options) int {
+	solved := 0
+	for _, position := range positions {
+		g, err := game.ParseFen(position.fen)
+		if err != nil {
+			t.Fatalf("%s: error: %v", position.id, err)
+		}
+		e := engine.New()
+		e.Options = options
+		result, err := e.Search(g, engine.Limits{Nodes: wacNodes})
+		if err != nil {
+			t.Fatalf("%s: error: %v", position.id, err)
+		}
+
+		for _, san := range position.bestMoves {
+			if best, err := game.ParseSAN(g, san); err == nil && best == result.Move {
+				solved++
+				break
+			}
+		}
+	}
+	return solved
+}
+
+func TestWACSuiteIsValid(t *testing.T) {
+	for _, position := range readEPD(t, "testdata/wac.epd") {
+		g, err := game.ParseFen(position.fen)
+		if err != nil {
+			t.Errorf("%s: error: %v", position.id, err)
+			continue
+		}
+		if len(position.bestMoves) == 0 {
+			t.Errorf("%s: no best move", position.id)
+		}
+		for _, san := range position.bestMoves {
+			if _, err := game.ParseSAN(g, san); err != nil {
+				t.Errorf("%s: best move %s: %v", position.id, san, err)
+			}
+		}
+	}
+}
+
+func TestWACSolveRate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("searches every position twice")
+	}
+	positions := readEPD(t, "testdata/wac.epd")
+
+	plain := solveEPD(t, positions, engine.Options{})
+	full := solveEPD(t, positions, engine.DefaultOptions())
+	t.Logf("Solved %d/%d with plain alpha-beta, %d/%d with all search features", plain, len(positions), full, len(positions))
+
+	if full <= plain {
+		t.Errorf("Expected the search features to solve more than %d positions, solved %d", plain, full)
+	}
+	if full*4 < len(positions)*3 {
+		t.Errorf("Expected at least three quarters of the positions solved, solved %d/%d", full, len(positions))
+	}
+}
@@ -0,0 +1,89 @@
+package test
+
+import (
+	"testing"
+	game "web-chess/backend/src"
+)
+
+var perftFens = []string{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+	"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+}
+
+func bitboardsMatchBoard(g *game.Game) bool {
+	bitboards := g.BitBoards()
+	expected := [23]uint64{}
+	for square, piece := range g.Board {
+		if piece.Type != game.None {
+			expected[piece.Type] |= 1 << square
+			expected[piece.Type&(game.White|game.Black)] |= 1 << square
+		}
+	}
+	return bitboards == expected
+}
+
+func verifyHashTree(t *testing.T, g *game.Game, depth int) {
+	if err := g.VerifyHash(); err != nil {
+		t.Fatal(err)
+	}
+	if !bitboardsMatchBoard(g) {
+		t.Fatalf("bitboards do not match board for %s", g.CurrentFen())
+	}
+	if depth == 0 {
+		return
+	}
+	for _, move := range g.GenerateLegalMoves() {
+		hash := g.Hash()
+		g.MakeMove(move)
+		verifyHashTree(t, g, depth-1)
+		g.UnmakeMove(move)
+		if g.Hash() != hash {
+			t.Fatalf("hash not restored after unmaking %v in %s", move, g.CurrentFen())
+		}
+	}
+}
+
+func TestHashIncrementalMatchesRecompute(t *testing.T) {
+	for _, fen := range perftFens {
+		g := game.NewGameFromFen(fen)
+		verifyHashTree(t, g, 3)
+	}
+}
+
+func TestHashTransposition(t *testing.T) {
+	first := game.NewGame()
+	first.Move(game.Move{StartSquare: 6, TargetSquare: 21})  // Nf3
+	first.Move(game.Move{StartSquare: 62, TargetSquare: 45}) // Nf6
+	first.Move(game.Move{StartSquare: 1, TargetSquare: 18})  // Nc3
+
+	second := game.NewGame()
+	second.Move(game.Move{StartSquare: 1, TargetSquare: 18})  // Nc3
+	second.Move(game.Move{StartSquare: 62, TargetSquare: 45}) // Nf6
+	second.Move(game.Move{StartSquare: 6, TargetSquare: 21})  // Nf3
+
+	if first.Hash() != second.Hash() {
+		t.Errorf("Expected equal hashes, got %016x and %016x", first.Hash(), second.Hash())
+	}
+
+	fromFen := game.NewGameFromFen(first.CurrentFen())
+	if first.Hash() != fromFen.Hash() {
+		t.Errorf("Expected hash %016x from fen, got %016x", first.Hash(), fromFen.Hash())
+	}
+}
+
+func TestHashDiffersBySideToMoveAndCastling(t *testing.T) {
+	white := game.NewGameFromFen("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	black := game.NewGameFromFen("r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1")
+	noCastling := game.NewGameFromFen("r3k2r/8/8/8/8/8/8/R3K2R w - - 0 1")
+
+	if white.Hash() == black.Hash() {
+		t.Error("Expected side to move to change the hash")
+	}
+	if white.Hash() == noCastling.Hash() {
+		t.Error("Expected castling rights to change the hash")
+	}
+}
@@ -0,0 +1,324 @@
+// Package uci lets the engine be driven by chess GUIs and tournament managers
+// through the Universal Chess Interface
+package uci
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"web-chess/backend/engine"
+	game "web-chess/backend/src"
+)
+
+const (
+	engineName   = "web-chess"
+	engineAuthor = "Oliver Toft"
+
+	maxHashMB  = 4096
+	maxThreads = 64
+
+	// Kept back from the clock for the time it takes the GUI to receive the
+	// move
+	moveOverhead = 50 * time.Millisecond
+	// Moves the remaining time is spread over when the GUI does not say
+	defaultMovesToGo = 30
+)
+
+type protocol struct {
+	mu  sync.Mutex
+	out io.Writer
+
+	engine *engine.Engine
+	game   *game.Game
+	// Positions are played as Chess960, castling is written as the king
+	// taking its rook
+	chess960 bool
+
+	// Set while a search runs. cancel stops it, done is closed once the best
+	// move has been written
+	cancel   context.CancelFunc
+	done     chan struct{}
+	infinite bool
+}
+
+// Run reads commands from in and writes the responses to out until it reads
+// quit. When the input ends, a running search is allowed to finish first,
+// except an infinite one, which is stopped
+func Run(in io.Reader, out io.Writer) error {
+	p := &protocol{
+		out:    out,
+		engine: engine.New(),
+		game:   game.NewGame(),
+	}
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "uci":
+			p.println("id name " + engineName)
+			p.println("id author " + engineAuthor)
+			p.printf("option name Hash type spin default %d min 1 max %d\n", engine.DefaultHashMB, maxHashMB)
+			p.printf("option name Threads type spin default 1 min 1 max %d\n", maxThreads)
+			p.println("option name UCI_Chess960 type check default false")
+			p.println("uciok")
+		case "isready":
+			p.println("readyok")
+		case "ucinewgame":
+			p.stopSearch()
+			p.engine.Clear()
+			p.game = game.NewGame()
+		case "setoption":
+			p.stopSearch()
+			p.setOption(fields[1:])
+		case "position":
+			p.stopSearch()
+			p.position(fields[1:])
+		case "go":
+			p.stopSearch()
+			p.goSearch(fields[1:])
+		case "stop":
+			p.stopSearch()
+		case "quit":
+			p.stopSearch()
+			return nil
+		default:
+			p.println("info string unknown command " + fields[0])
+		}
+	}
+
+	p.waitForSearch()
+	return scanner.Err()
+}
+
+func (p *protocol) println(line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintln(p.out, line)
+}
+
+func (p *protocol) printf(format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.out, format, args...)
+}
+
+// setoption name <name> value <value>
+func (p *protocol) setOption(args []string) {
+	name, value := "", ""
+	for i := 0; i+1 < len(args); i++ {
+		switch args[i] {
+		case "name":
+			name = args[i+1]
+		case "value":
+			value = args[i+1]
+		}
+	}
+
+	if strings.EqualFold(name, "UCI_Chess960") {
+		switch strings.ToLower(value) {
+		case "true", "false":
+			p.chess960 = strings.EqualFold(value, "true")
+		default:
+			p.println("info string invalid value " + value + " for option " + name)
+		}
+		return
+	}
+
+	n, err := strconv.Atoi(value)
+	switch {
+	case !strings.EqualFold(name, "Hash") && !strings.EqualFold(name, "Threads"):
+		p.println("info string unknown option " + name)
+	case err != nil || n < 1:
+		p.println("info string invalid value " + value + " for option " + name)
+	case strings.EqualFold(name, "Hash"):
+		p.engine.SetHashSize(min(n, maxHashMB))
+	default:
+		p.engine.SetThreads(min(n, maxThreads))
+	}
+}
+
+// position [startpos | fen <fen>] [moves <move>...]
+func (p *protocol) position(args []string) {
+	if len(args) == 0 {
+		return
+	}
+
+	var g *game.Game
+	rest := args[1:]
+	switch args[0] {
+	case "startpos":
+		g = game.NewGame()
+	case "fen":
+		end := len(rest)
+		for i, arg := range rest {
+			if arg == "moves" {
+				end = i
+				break
+			}
+		}
+		var err error
+		if g, err = game.ParseFen(strings.Join(rest[:end], " ")); err != nil {
+			p.println("info string " + err.Error())
+			return
+		}
+		rest = rest[end:]
+	default:
+		p.println("info string unknown position " + args[0])
+		return
+	}
+
+	// The standard position in X-FEN looks like a standard game
+	if p.chess960 {
+		g.SetChess960(true)
+	}
+
+	if len(rest) > 0 && rest[0] == "moves" {
+		for _, uci := range rest[1:] {
+			move, err := game.ParseUCIMove(g, uci)
+			if err == nil {
+				err = g.Move(move)
+			}
+			if err != nil {
+				p.println("info string " + err.Error())
+				return
+			}
+		}
+	}
+	p.game = g
+}
+
+// go [depth n] [nodes n] [movetime ms] [wtime ms] [btime ms] [winc ms]
+// [binc ms] [movestogo n] [infinite]
+func (p *protocol) goSearch(args []string) {
+	limits, infinite := p.limits(args)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	p.cancel, p.done, p.infinite = cancel, done, infinite
+
+	p.engine.OnIteration = func(result engine.Result) {
+		p.println(infoLine(result))
+	}
+	g := p.game
+	go func() {
+		defer close(done)
+		result, err := p.engine.SearchContext(ctx, g, limits)
+		// An infinite search only reports its move when told to stop
+		if infinite {
+			<-ctx.Done()
+		}
+		if err != nil {
+			p.println("bestmove 0000")
+			return
+		}
+		line := "bestmove " + result.Move.UCI()
+		if len(result.PV) > 1 {
+			line += " ponder " + result.PV[1].UCI()
+		}
+		p.println(line)
+	}()
+}
+
+func (p *protocol) limits(args []string) (engine.Limits, bool) {
+	var limits engine.Limits
+	var clock, increment [2]time.Duration
+	movesToGo := defaultMovesToGo
+	infinite := false
+
+	for i := 0; i < len(args); i++ {
+		if args[i] == "infinite" {
+			infinite = true
+			continue
+		}
+		if i+1 >= len(args) {
+			break
+		}
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil {
+			continue
+		}
+		ms := time.Duration(n) * time.Millisecond
+		switch args[i] {
+		case "depth":
+			limits.Depth = n
+		case "nodes":
+			limits.Nodes = uint64(n)
+		case "movetime":
+			limits.Time = max(ms-moveOverhead, ms/2)
+		case "wtime":
+			clock[0] = ms
+		case "btime":
+			clock[1] = ms
+		case "winc":
+			increment[0] = ms
+		case "binc":
+			increment[1] = ms
+		case "movestogo":
+			movesToGo = max(n, 1)
+		default:
+			continue
+		}
+		i++
+	}
+
+	side := 0
+	if !p.game.ColorToMove {
+		side = 1
+	}
+	if limits.Time == 0 && clock[side] > 0 {
+		budget := clock[side]/time.Duration(movesToGo) + increment[side]*3/4
+		limits.Time = max(min(budget, clock[side]-moveOverhead), time.Millisecond)
+	}
+
+	if infinite || limits == (engine.Limits{}) {
+		return engine.Limits{Depth: engine.MaxPly}, true
+	}
+	return limits, false
+}
+
+func infoLine(result engine.Result) string {
+	nps := uint64(0)
+	if result.Time > 0 {
+		nps = result.Nodes * uint64(time.Second) / uint64(result.Time)
+	}
+	pv := make([]string, len(result.PV))
+	for i, move := range result.PV {
+		pv[i] = move.UCI()
+	}
+	return fmt.Sprintf("info depth %d score %v nodes %d nps %d time %d pv %s",
+		result.Depth, result.Score, result.Nodes, nps, result.Time.Milliseconds(), strings.Join(pv, " "))
+}
+
+// Stops the running search and waits until its best move is written
+func (p *protocol) stopSearch() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	p.waitForSearch()
+}
+
+// Waits until the running search has written its best move. An infinite
+// search would never finish on its own, so it is stopped
+func (p *protocol) waitForSearch() {
+	if p.done == nil {
+		return
+	}
+	if p.infinite {
+		p.cancel()
+	}
+	<-p.done
+	p.cancel()
+	p.cancel, p.done = nil, nil
+}
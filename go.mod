@@ -3,3 +3,10 @@ module web-chess
 go 1.23.1
 
 require github.com/gorilla/mux v1.8.1
+
+require (
+	github.com/gorilla/websocket v1.5.3
+	go.etcd.io/bbolt v1.4.3
+)
+
+require golang.org/x/sys v0.29.0 // indirect
@@ -1,61 +1,133 @@
 package main
 
 import (
+	"cmp"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
 	"web-chess/backend/api"
+	"web-chess/backend/storage"
 	"web-chess/backend/test/perft"
+	"web-chess/backend/uci"
 )
 
+const usage = "Usage: perft-test [-hash mb] | perft [-hash mb] <position> <depth> | perft-divide [-hash mb] <position> <depth> | server [-storage memory|json|bolt] [-path path] | uci"
+
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: perft-test | perft <position> <depth> | perft-sub <position> <depth> | server")
+		fmt.Println(usage)
 		return
 	}
 
 	switch os.Args[1] {
 	case "perft-test":
-		perft.RunPerftTest()
-	case "perft":
-		if len(os.Args) < 4 {
-			fmt.Println("Usage: perft <position> <depth>")
+		cache, _, ok := parsePerftFlags("perft-test", os.Args[2:])
+		if !ok {
 			return
 		}
-		position, err := strconv.Atoi(os.Args[2])
-		if err != nil {
-			fmt.Printf("Invalid position: %s\n", os.Args[2])
+		perft.RunPerftTest(cache)
+	case "perft":
+		cache, args, ok := parsePerftFlags("perft", os.Args[2:])
+		if !ok {
 			return
 		}
-		depth, err := strconv.Atoi(os.Args[3])
-		if err != nil {
-			fmt.Printf("Invalid depth: %s\n", os.Args[2])
+		position, depth, ok := parsePositionAndDepth("perft", args)
+		if !ok {
 			return
 		}
-		perft.RunPerft(position, depth)
+		perft.RunPerft(position, depth, cache)
 	case "perft-divide":
-		if len(os.Args) < 4 {
-			fmt.Println("Usage: perft-sub <position> <depth>")
+		cache, args, ok := parsePerftFlags("perft-divide", os.Args[2:])
+		if !ok {
 			return
 		}
-		position, err := strconv.Atoi(os.Args[2])
-		if err != nil {
-			fmt.Printf("Invalid position: %s\n", os.Args[2])
+		position, depth, ok := parsePositionAndDepth("perft-divide", args)
+		if !ok {
 			return
 		}
-		depth, err := strconv.Atoi(os.Args[3])
-		if err != nil {
-			fmt.Printf("Invalid depth: %s\n", os.Args[2])
+		perft.RunPerftDivide(position, depth, cache)
+	case "server":
+		config, ok := parseServerFlags(os.Args[2:])
+		if !ok {
 			return
 		}
-		perft.RunPerftDivide(position, depth)
-	case "server":
-		srv := api.NewServer()
+		srv, err := api.NewServerWithConfig(config)
+		if err != nil {
+			log.Fatal(err)
+		}
 		fmt.Println("SERVER CREATED")
 		log.Fatal(http.ListenAndServe("127.0.0.1:42069", srv))
+	case "uci":
+		if err := uci.Run(os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
 	default:
-		fmt.Println("Usage: perft-test | perft <position> <depth> | perft-sub <position> <depth> | server")
+		fmt.Println(usage)
+	}
+}
+
+// Parses the flags shared by the perft commands and returns the remaining
+// arguments. The cache is nil unless -hash is given
+func parsePerftFlags(name string, args []string) (*perft.Cache, []string, bool) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	hashMB := flags.Int("hash", 0, "cache node counts in a table of this many megabytes")
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, false
+	}
+
+	var cache *perft.Cache
+	if *hashMB > 0 {
+		cache = perft.NewCache(*hashMB)
+	}
+	return cache, flags.Args(), true
+}
+
+// Opens the repository games are stored in. JSON files are kept in the
+// directory "games" and a bolt database in "games.db" unless -path is given
+func parseServerFlags(args []string) (api.Config, bool) {
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	kind := flags.String("storage", "json", "where to store games: memory, json or bolt")
+	path := flags.String("path", "", "directory of the json store or file of the bolt store")
+	if err := flags.Parse(args); err != nil {
+		return api.Config{}, false
+	}
+
+	var config api.Config
+	var err error
+	switch *kind {
+	case "memory":
+	case "json":
+		config.Repository, err = storage.NewJSONRepository(cmp.Or(*path, "games"))
+	case "bolt":
+		config.Repository, err = storage.NewBoltRepository(cmp.Or(*path, "games.db"))
+	default:
+		fmt.Printf("Unknown storage: %s\n", *kind)
+		return config, false
+	}
+	if err != nil {
+		fmt.Printf("Error opening storage: %v\n", err)
+		return config, false
+	}
+	return config, true
+}
+
+func parsePositionAndDepth(name string, args []string) (int, int, bool) {
+	if len(args) < 2 {
+		fmt.Printf("Usage: %s [-hash mb] <position> <depth>\n", name)
+		return 0, 0, false
+	}
+	position, err := strconv.Atoi(args[0])
+	if err != nil {
+		fmt.Printf("Invalid position: %s\n", args[0])
+		return 0, 0, false
+	}
+	depth, err := strconv.Atoi(args[1])
+	if err != nil {
+		fmt.Printf("Invalid depth: %s\n", args[1])
+		return 0, 0, false
 	}
+	return position, depth, true
 }
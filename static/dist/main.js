@@ -23,6 +23,14 @@ var PieceType;
     PieceType[PieceType["White"] = 8] = "White";
     PieceType[PieceType["Black"] = 16] = "Black";
 })(PieceType || (PieceType = {}));
+// Move flags of dropping a pawn, knight, bishop, rook or queen
+const DropFlags = {
+    [PieceType.Pawn]: 8,
+    [PieceType.Knight]: 9,
+    [PieceType.Bishop]: 10,
+    [PieceType.Rook]: 11,
+    [PieceType.Queen]: 12,
+};
 const PieceImages = {
     [PieceType.Pawn]: {
         [PieceType.White]: "/static/images/white_pawn.svg",
@@ -53,6 +61,9 @@ const PieceImages = {
 document.addEventListener("DOMContentLoaded", () => {
     setupNewGameButton();
     setupUndoButton();
+    setupClaimDrawButton();
+    setupImportPgnButton();
+    joinGameFromUrl();
 });
 function setupNewGameButton() {
     const newGameButton = document.getElementById("new-game-button");
@@ -69,18 +80,99 @@ function newGame() {
         startNewGame();
     }
 }
+// Reads the variant and opponent chosen next to the new game button
+function gameOptions() {
+    const variant = document.getElementById("variant").value;
+    const opponent = document.getElementById("opponent").value;
+    const timeControl = document.getElementById("time-control").value || undefined;
+    if (opponent === "human") {
+        return { variant, opponent, timeControl };
+    }
+    const level = Number(document.getElementById("level").value);
+    return { variant, opponent: "engine", engineColor: opponent, level, timeControl };
+}
 let moves = [];
 let legalMoves = [];
+let gameId = "";
+let liveSocket;
 function setupUndoButton() {
     const undoButton = document.getElementById("undo-button");
     undoButton.addEventListener("click", undoMove);
 }
+function setupClaimDrawButton() {
+    const claimDrawButton = document.getElementById("claim-draw-button");
+    claimDrawButton.addEventListener("click", claimDraw);
+}
+function setupImportPgnButton() {
+    const importPgnButton = document.getElementById("import-pgn-button");
+    importPgnButton.addEventListener("click", importPgn);
+}
 const gameContainer = document.getElementById("game-container");
 function renderBoard(game) {
+    if (game.id && game.id !== gameId) {
+        followGame(game.id);
+    }
     gameContainer.innerHTML = "";
     gameContainer.dataset.colorToMove = game.ColorToMove.toString();
     const boardDiv = createBoardDiv(game);
     gameContainer.appendChild(boardDiv);
+    if (game.pockets) {
+        gameContainer.appendChild(createPocketsDiv(game.pockets));
+    }
+    renderStatus(game);
+}
+// Subscribes to the game's live updates, which re-render the board whenever
+// anyone moves
+function followGame(id) {
+    gameId = id;
+    location.hash = id;
+    const pgnLink = document.getElementById("pgn-link");
+    pgnLink.href = `/games/${id}/pgn`;
+    if (liveSocket) {
+        liveSocket.close();
+    }
+    const protocol = location.protocol === "https:" ? "wss:" : "ws:";
+    liveSocket = new WebSocket(`${protocol}//${location.host}/games/${id}/live`);
+    liveSocket.addEventListener("message", (event) => {
+        renderBoard(JSON.parse(event.data));
+    });
+}
+function joinGameFromUrl() {
+    const id = location.hash.slice(1);
+    if (id !== "") {
+        followGame(id);
+    }
+}
+function renderStatus(game) {
+    const claimDrawButton = document.getElementById("claim-draw-button");
+    claimDrawButton.hidden = !game.canClaimDraw;
+    renderClock(game.clock);
+    const statusDiv = document.getElementById("game-status");
+    const checks = game.checks ? `checks ${game.checks.white}-${game.checks.black}` : "";
+    if (game.status === undefined || game.status === "ongoing") {
+        statusDiv.textContent = checks;
+        return;
+    }
+    let text = game.status.replace(/-/g, " ");
+    if (game.winner) {
+        text += `, ${game.winner} wins`;
+    }
+    statusDiv.textContent = `${text} (${game.result})`;
+}
+function renderClock(clock) {
+    const clockDiv = document.getElementById("clock");
+    if (!clock) {
+        clockDiv.textContent = "";
+        return;
+    }
+    const white = formatClockTime(clock.whiteMs);
+    const black = formatClockTime(clock.blackMs);
+    clockDiv.textContent = `White ${white} - Black ${black} (${clock.timeControl})`;
+}
+function formatClockTime(ms) {
+    const seconds = Math.ceil(ms / 1000);
+    const rest = seconds % 60;
+    return `${Math.floor(seconds / 60)}:${rest < 10 ? "0" : ""}${rest}`;
 }
 function createBoardDiv(game) {
     const boardDiv = document.createElement("div");
@@ -141,7 +233,51 @@ function createPieceDiv(piece) {
     return pieceDiv;
 }
 let selectedSquares = [];
+// Black's pocket above white's, next to the board. Clicking a piece of the
+// side to move selects it to be dropped on the next square clicked
+function createPocketsDiv(pockets) {
+    const pocketsDiv = document.createElement("div");
+    pocketsDiv.classList.add("pockets");
+    pocketsDiv.appendChild(createPocketDiv(pockets.black, PieceType.Black));
+    pocketsDiv.appendChild(createPocketDiv(pockets.white, PieceType.White));
+    return pocketsDiv;
+}
+function createPocketDiv(pocket, color) {
+    const pocketDiv = document.createElement("div");
+    pocketDiv.classList.add("pocket");
+    pocket.forEach((count, pieceType) => {
+        if (count === 0) {
+            return;
+        }
+        const pieceDiv = createPieceDiv({ type: pieceType | color });
+        pieceDiv.classList.add("pocket-piece");
+        pieceDiv.dataset.count = count.toString();
+        pieceDiv.addEventListener("click", () => {
+            handlePocketClick(pieceDiv, pieceType, color);
+        });
+        pocketDiv.appendChild(pieceDiv);
+    });
+    return pocketDiv;
+}
+let selectedDrop;
+function handlePocketClick(pieceDiv, pieceType, color) {
+    const colorToMove = gameContainer.dataset.colorToMove === "true";
+    if ((color === PieceType.White) !== colorToMove) {
+        return;
+    }
+    selectedSquares = [];
+    removeHighlight();
+    selectedDrop = DropFlags[pieceType];
+    pieceDiv.classList.add("highlight-square");
+}
 function handleSquareClick(index) {
+    if (selectedDrop !== undefined) {
+        // A drop has no start square of its own
+        sendMoveRequest({ startSquare: index, targetSquare: index, flag: selectedDrop });
+        selectedDrop = undefined;
+        removeHighlight();
+        return;
+    }
     if (selectedSquares.length === 0) {
         const colorToMove = gameContainer.dataset.colorToMove === "true";
         const piece = document.querySelector(`[data-index='${index}']`);
@@ -239,6 +375,10 @@ function highlightSquare(index) {
     }
 }
 function removeHighlight() {
+    const pocketPieces = document.getElementsByClassName("pocket-piece");
+    for (let i = 0; i < pocketPieces.length; i++) {
+        pocketPieces[i].classList.remove("highlight-square");
+    }
     const squares = document.getElementsByClassName("chess-square");
     for (let i = 0; i < squares.length; i++) {
         const square = squares[i];
@@ -268,7 +408,13 @@ function startNewGame() {
 }
 function fetchNewGame() {
     return __awaiter(this, void 0, void 0, function* () {
-        const response = yield fetch("new-game", { method: "POST" });
+        const response = yield fetch("new-game", {
+            method: "POST",
+            headers: {
+                "Content-Type": "application/json",
+            },
+            body: JSON.stringify(gameOptions()),
+        });
         if (!response.ok) {
             throw new Error("Could not fetch new chess game");
         }
@@ -294,10 +440,10 @@ function fetchNewGameFromFen(fen) {
             headers: {
                 "Content-Type": "application/json",
             },
-            body: JSON.stringify({ fen }),
+            body: JSON.stringify(Object.assign({ fen }, gameOptions())),
         });
         if (!response.ok) {
-            throw new Error("Could not fetch new chess game");
+            throw new Error(yield response.text());
         }
         return response.json();
     });
@@ -309,8 +455,6 @@ function sendMoveRequest(move) {
             yield makeMove(move);
             moves.push(move);
             console.log("Moves after send request:", moves);
-            const gameState = yield currentGameState();
-            renderBoard(gameState);
         }
         catch (error) {
             console.error(error);
@@ -319,7 +463,7 @@ function sendMoveRequest(move) {
 }
 function makeMove(move) {
     return __awaiter(this, void 0, void 0, function* () {
-        const response = yield fetch("/move", {
+        const response = yield fetch(`/games/${gameId}/move`, {
             method: "POST",
             headers: {
                 "Content-Type": "application/json",
@@ -332,15 +476,6 @@ function makeMove(move) {
         return response.json();
     });
 }
-function currentGameState() {
-    return __awaiter(this, void 0, void 0, function* () {
-        const response = yield fetch("/current-state", { method: "GET" });
-        if (!response.ok) {
-            throw new Error("Could not fetch game state");
-        }
-        return response.json();
-    });
-}
 function getLegalMoves(index) {
     return __awaiter(this, void 0, void 0, function* () {
         try {
@@ -354,7 +489,7 @@ function getLegalMoves(index) {
 }
 function fetchLegalMoves(index) {
     return __awaiter(this, void 0, void 0, function* () {
-        const response = yield fetch(`/legal-moves/${index}`, { method: "GET" });
+        const response = yield fetch(`/games/${gameId}/legal-moves/${index}`, { method: "GET" });
         if (!response.ok) {
             throw new Error("Could not fetch legal moves");
         }
@@ -369,8 +504,6 @@ function undoMove() {
             console.log("Undoing move", move);
             yield fetchUndoMove(move);
             moves.pop();
-            const gameState = yield currentGameState();
-            renderBoard(gameState);
         }
         catch (error) {
             console.error("There was a problem with undoing the move:", error);
@@ -379,7 +512,7 @@ function undoMove() {
 }
 function fetchUndoMove(move) {
     return __awaiter(this, void 0, void 0, function* () {
-        const response = yield fetch("/undo-move", {
+        const response = yield fetch(`/games/${gameId}/undo-move`, {
             method: "POST",
             headers: {
                 "Content-Type": "application/json",
@@ -391,3 +524,41 @@ function fetchUndoMove(move) {
         }
     });
 }
+function claimDraw() {
+    return __awaiter(this, void 0, void 0, function* () {
+        try {
+            const response = yield fetch(`/games/${gameId}/claim-draw`, { method: "POST" });
+            if (!response.ok) {
+                throw new Error("Could not claim draw");
+            }
+            const gameState = yield response.json();
+            renderBoard(gameState);
+        }
+        catch (error) {
+            console.error("There was a problem with claiming a draw:", error);
+        }
+    });
+}
+function importPgn() {
+    return __awaiter(this, void 0, void 0, function* () {
+        const pgnInput = document.getElementById("pgn");
+        try {
+            const response = yield fetch("/import-pgn", {
+                method: "POST",
+                headers: {
+                    "Content-Type": "application/x-chess-pgn",
+                },
+                body: pgnInput.value,
+            });
+            if (!response.ok) {
+                throw new Error(yield response.text());
+            }
+            moves = [];
+            const gameState = yield response.json();
+            renderBoard(gameState);
+        }
+        catch (error) {
+            console.error("There was a problem with importing the PGN:", error);
+        }
+    });
+}
@@ -23,6 +23,50 @@ interface Piece {
 interface Game {
   board: Piece[];
   ColorToMove: Color;
+  status?: string;
+  winner?: string;
+  result?: string;
+  canClaimDraw?: boolean;
+  id?: string;
+  clock?: Clock;
+  checks?: Checks;
+  pockets?: Pockets;
+}
+
+// Checks given by each side in Three-check
+interface Checks {
+  white: number;
+  black: number;
+}
+
+// Pieces in hand of each side in Crazyhouse, counted by piece type
+interface Pockets {
+  white: number[];
+  black: number[];
+}
+
+// Move flags of dropping a pawn, knight, bishop, rook or queen
+const DropFlags: { [key in PieceType]?: number } = {
+  [PieceType.Pawn]: 8,
+  [PieceType.Knight]: 9,
+  [PieceType.Bishop]: 10,
+  [PieceType.Rook]: 11,
+  [PieceType.Queen]: 12,
+};
+
+interface Clock {
+  timeControl: string;
+  whiteMs: number;
+  blackMs: number;
+  running?: string;
+}
+
+interface GameOptions {
+  variant: string;
+  opponent: string;
+  engineColor?: string;
+  level?: number;
+  timeControl?: string;
 }
 
 interface Move {
@@ -63,6 +107,9 @@ const PieceImages: { [key in PieceType]?: { [key in Color]: string } } = {
 document.addEventListener("DOMContentLoaded", () => {
   setupNewGameButton();
   setupUndoButton();
+  setupClaimDrawButton();
+  setupImportPgnButton();
+  joinGameFromUrl();
 });
 
 function setupNewGameButton() {
@@ -82,21 +129,113 @@ function newGame() {
   }
 }
 
+// Reads the variant and opponent chosen next to the new game button
+function gameOptions(): GameOptions {
+  const variant = (document.getElementById("variant") as HTMLSelectElement).value;
+  const opponent = (document.getElementById("opponent") as HTMLSelectElement).value;
+  const timeControl = (document.getElementById("time-control") as HTMLInputElement).value || undefined;
+  if (opponent === "human") {
+    return { variant, opponent, timeControl };
+  }
+  const level = Number((document.getElementById("level") as HTMLSelectElement).value);
+  return { variant, opponent: "engine", engineColor: opponent, level, timeControl };
+}
+
 let moves: Move[] = [];
 let legalMoves: Move[] = [];
+let gameId = "";
+let liveSocket: WebSocket | undefined;
 
 function setupUndoButton() {
   const undoButton = document.getElementById("undo-button")!;
   undoButton.addEventListener("click", undoMove);
 }
 
+function setupClaimDrawButton() {
+  const claimDrawButton = document.getElementById("claim-draw-button")!;
+  claimDrawButton.addEventListener("click", claimDraw);
+}
+
+function setupImportPgnButton() {
+  const importPgnButton = document.getElementById("import-pgn-button")!;
+  importPgnButton.addEventListener("click", importPgn);
+}
+
 const gameContainer = document.getElementById("game-container")!;
 
 function renderBoard(game: Game) {
+  if (game.id && game.id !== gameId) {
+    followGame(game.id);
+  }
   gameContainer.innerHTML = "";
   gameContainer.dataset.colorToMove = game.ColorToMove.toString();
   const boardDiv = createBoardDiv(game);
   gameContainer.appendChild(boardDiv);
+  if (game.pockets) {
+    gameContainer.appendChild(createPocketsDiv(game.pockets));
+  }
+  renderStatus(game);
+}
+
+// Subscribes to the game's live updates, which re-render the board whenever
+// anyone moves
+function followGame(id: string) {
+  gameId = id;
+  location.hash = id;
+  const pgnLink = document.getElementById("pgn-link") as HTMLAnchorElement;
+  pgnLink.href = `/games/${id}/pgn`;
+
+  if (liveSocket) {
+    liveSocket.close();
+  }
+  const protocol = location.protocol === "https:" ? "wss:" : "ws:";
+  liveSocket = new WebSocket(`${protocol}//${location.host}/games/${id}/live`);
+  liveSocket.addEventListener("message", (event) => {
+    renderBoard(JSON.parse(event.data) as Game);
+  });
+}
+
+function joinGameFromUrl() {
+  const id = location.hash.slice(1);
+  if (id !== "") {
+    followGame(id);
+  }
+}
+
+function renderStatus(game: Game) {
+  const claimDrawButton = document.getElementById("claim-draw-button")!;
+  claimDrawButton.hidden = !game.canClaimDraw;
+
+  renderClock(game.clock);
+
+  const statusDiv = document.getElementById("game-status")!;
+  const checks = game.checks ? `checks ${game.checks.white}-${game.checks.black}` : "";
+  if (game.status === undefined || game.status === "ongoing") {
+    statusDiv.textContent = checks;
+    return;
+  }
+  let text = game.status.replace(/-/g, " ");
+  if (game.winner) {
+    text += `, ${game.winner} wins`;
+  }
+  statusDiv.textContent = `${text} (${game.result})`;
+}
+
+function renderClock(clock?: Clock) {
+  const clockDiv = document.getElementById("clock")!;
+  if (!clock) {
+    clockDiv.textContent = "";
+    return;
+  }
+  const white = formatClockTime(clock.whiteMs);
+  const black = formatClockTime(clock.blackMs);
+  clockDiv.textContent = `White ${white} - Black ${black} (${clock.timeControl})`;
+}
+
+function formatClockTime(ms: number): string {
+  const seconds = Math.ceil(ms / 1000);
+  const rest = seconds % 60;
+  return `${Math.floor(seconds / 60)}:${rest < 10 ? "0" : ""}${rest}`;
 }
 
 function createBoardDiv(game: Game): HTMLDivElement {
@@ -174,9 +313,58 @@ function createPieceDiv(piece: Piece): HTMLDivElement {
   return pieceDiv;
 }
 
+// Black's pocket above white's, next to the board. Clicking a piece of the
+// side to move selects it to be dropped on the next square clicked
+function createPocketsDiv(pockets: Pockets): HTMLDivElement {
+  const pocketsDiv = document.createElement("div");
+  pocketsDiv.classList.add("pockets");
+  pocketsDiv.appendChild(createPocketDiv(pockets.black, PieceType.Black));
+  pocketsDiv.appendChild(createPocketDiv(pockets.white, PieceType.White));
+  return pocketsDiv;
+}
+
+function createPocketDiv(pocket: number[], color: Color): HTMLDivElement {
+  const pocketDiv = document.createElement("div");
+  pocketDiv.classList.add("pocket");
+
+  pocket.forEach((count, pieceType) => {
+    if (count === 0) {
+      return;
+    }
+    const pieceDiv = createPieceDiv({ type: pieceType | color });
+    pieceDiv.classList.add("pocket-piece");
+    pieceDiv.dataset.count = count.toString();
+    pieceDiv.addEventListener("click", () => {
+      handlePocketClick(pieceDiv, pieceType, color);
+    });
+    pocketDiv.appendChild(pieceDiv);
+  });
+  return pocketDiv;
+}
+
+let selectedDrop: number | undefined;
+
+function handlePocketClick(pieceDiv: HTMLDivElement, pieceType: PieceType, color: Color) {
+  const colorToMove: boolean = gameContainer.dataset.colorToMove! === "true";
+  if ((color === PieceType.White) !== colorToMove) {
+    return;
+  }
+  selectedSquares = [];
+  removeHighlight();
+  selectedDrop = DropFlags[pieceType];
+  pieceDiv.classList.add("highlight-square");
+}
+
 let selectedSquares: number[] = [];
 
 function handleSquareClick(index: number) {
+  if (selectedDrop !== undefined) {
+    // A drop has no start square of its own
+    sendMoveRequest({ startSquare: index, targetSquare: index, flag: selectedDrop });
+    selectedDrop = undefined;
+    removeHighlight();
+    return;
+  }
   if (selectedSquares.length === 0) {
     const colorToMove: boolean = gameContainer.dataset.colorToMove! === "true";
     const piece = document.querySelector(
@@ -294,6 +482,10 @@ function highlightSquare(index: number) {
 }
 
 function removeHighlight() {
+  const pocketPieces = document.getElementsByClassName("pocket-piece");
+  for (let i = 0; i < pocketPieces.length; i++) {
+    pocketPieces[i].classList.remove("highlight-square");
+  }
   const squares = document.getElementsByClassName("chess-square");
   for (let i = 0; i < squares.length; i++) {
     const square = squares[i] as HTMLElement;
@@ -322,7 +514,13 @@ async function startNewGame() {
 }
 
 async function fetchNewGame(): Promise<Game> {
-  const response = await fetch("new-game", { method: "POST" });
+  const response = await fetch("new-game", {
+    method: "POST",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    body: JSON.stringify(gameOptions()),
+  });
 
   if (!response.ok) {
     throw new Error("Could not fetch new chess game");
@@ -347,11 +545,11 @@ async function fetchNewGameFromFen(fen: string): Promise<Game> {
     headers: {
       "Content-Type": "application/json",
     },
-    body: JSON.stringify({ fen }),
+    body: JSON.stringify({ fen, ...gameOptions() }),
   });
 
   if (!response.ok) {
-    throw new Error("Could not fetch new chess game");
+    throw new Error(await response.text());
   }
 
   return response.json() as Promise<Game>;
@@ -364,15 +562,13 @@ async function sendMoveRequest(move: Move) {
     await makeMove(move);
     moves.push(move);
     console.log("Moves after send request:", moves);
-    const gameState = await currentGameState();
-    renderBoard(gameState);
   } catch (error) {
     console.error(error);
   }
 }
 
 async function makeMove(move: Move): Promise<string> {
-  const response = await fetch("/move", {
+  const response = await fetch(`/games/${gameId}/move`, {
     method: "POST",
     headers: {
       "Content-Type": "application/json",
@@ -387,16 +583,6 @@ async function makeMove(move: Move): Promise<string> {
   return response.json();
 }
 
-async function currentGameState(): Promise<Game> {
-  const response = await fetch("/current-state", { method: "GET" });
-
-  if (!response.ok) {
-    throw new Error("Could not fetch game state");
-  }
-
-  return response.json();
-}
-
 async function getLegalMoves(index: number) {
   try {
     legalMoves = await fetchLegalMoves(index);
@@ -407,7 +593,7 @@ async function getLegalMoves(index: number) {
 }
 
 async function fetchLegalMoves(index: number): Promise<Move[]> {
-  const response = await fetch(`/legal-moves/${index}`, { method: "GET" });
+  const response = await fetch(`/games/${gameId}/legal-moves/${index}`, { method: "GET" });
 
   if (!response.ok) {
     throw new Error("Could not fetch legal moves");
@@ -424,15 +610,13 @@ async function undoMove() {
     console.log("Undoing move", move);
     await fetchUndoMove(move);
     moves.pop();
-    const gameState = await currentGameState();
-    renderBoard(gameState);
   } catch (error) {
     console.error("There was a problem with undoing the move:", error);
   }
 }
 
 async function fetchUndoMove(move: Move) {
-  const response = await fetch("/undo-move", {
+  const response = await fetch(`/games/${gameId}/undo-move`, {
     method: "POST",
     headers: {
       "Content-Type": "application/json",
@@ -444,3 +628,42 @@ async function fetchUndoMove(move: Move) {
     throw new Error("Could not undo move");
   }
 }
+
+async function claimDraw() {
+  try {
+    const response = await fetch(`/games/${gameId}/claim-draw`, { method: "POST" });
+
+    if (!response.ok) {
+      throw new Error("Could not claim draw");
+    }
+
+    const gameState: Game = await response.json();
+    renderBoard(gameState);
+  } catch (error) {
+    console.error("There was a problem with claiming a draw:", error);
+  }
+}
+
+async function importPgn() {
+  const pgnInput = document.getElementById("pgn") as HTMLTextAreaElement;
+
+  try {
+    const response = await fetch("/import-pgn", {
+      method: "POST",
+      headers: {
+        "Content-Type": "application/x-chess-pgn",
+      },
+      body: pgnInput.value,
+    });
+
+    if (!response.ok) {
+      throw new Error(await response.text());
+    }
+
+    moves = [];
+    const gameState: Game = await response.json();
+    renderBoard(gameState);
+  } catch (error) {
+    console.error("There was a problem with importing the PGN:", error);
+  }
+}